@@ -2,12 +2,15 @@ module check-maildomain
 
 go 1.24.3
 
-require github.com/miekg/dns v1.1.66
+require (
+	github.com/miekg/dns v1.1.66
+	golang.org/x/net v0.39.0
+)
 
 require (
 	golang.org/x/mod v0.24.0 // indirect
-	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sync v0.13.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/text v0.24.0 // indirect
 	golang.org/x/tools v0.32.0 // indirect
 )
@@ -0,0 +1,186 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"check-maildomain/internal/check"
+	"check-maildomain/internal/dns"
+)
+
+// Reference is a domain with a known configuration and the rule statuses it is expected to produce
+type Reference struct {
+	Domain      string         // Domain to check
+	Description string         // Why the domain is in the dataset
+	Expect      map[int]string // Expected status per rule ID
+}
+
+// References is the built-in reference dataset of well-known domains
+var References = []Reference{
+	{
+		Domain:      "google.com",
+		Description: "Major provider with SPF, DMARC and MX",
+		Expect:      map[int]string{5: "pass", 6: "pass", 9: "pass"},
+	},
+	{
+		Domain:      "microsoft.com",
+		Description: "Major provider with SPF, DMARC and MX",
+		Expect:      map[int]string{5: "pass", 6: "pass", 9: "pass"},
+	},
+	{
+		Domain:      "paypal.com",
+		Description: "Heavily spoofed brand with an enforcing DMARC policy",
+		Expect:      map[int]string{4: "pass", 5: "pass", 6: "pass"},
+	},
+	{
+		Domain:      "example.com",
+		Description: "Reserved domain locked down with SPF -all and DMARC p=reject",
+		Expect:      map[int]string{3: "pass", 4: "pass", 6: "pass"},
+	},
+	{
+		Domain:      "nonexistent.invalid",
+		Description: "Reserved TLD that never resolves (RFC 6761), known-bad",
+		Expect:      map[int]string{5: "fail", 6: "fail", 9: "warn"},
+	},
+}
+
+// Mismatch describes a rule whose status differs from the reference expectation
+type Mismatch struct {
+	RuleID   int    // Rule that was checked
+	Expected string // Expected status
+	Actual   string // Actual status, empty when the rule produced no result
+}
+
+// Result contains the benchmark result for a single run against a single reference domain
+type Result struct {
+	Domain     string        // Domain that was checked
+	Run        int           // Run number, starting at 1
+	Duration   time.Duration // Wall clock time of the check
+	Expected   int           // Number of expectations
+	Matched    int           // Number of expectations that were met
+	Mismatches []Mismatch    // Expectations that were not met
+	Error      string        // Any error that aborted the check
+}
+
+// Summary contains aggregated statistics over all benchmark results
+type Summary struct {
+	Results       []Result
+	Runs          int
+	TotalDuration time.Duration
+	MinDuration   time.Duration
+	MaxDuration   time.Duration
+	AvgDuration   time.Duration
+	P95Duration   time.Duration
+	Expected      int     // Total number of expectations
+	Matched       int     // Total number of expectations met
+	Correctness   float64 // Percentage of expectations met
+}
+
+// Run checks every reference domain the given number of times and collects timing and correctness statistics
+func Run(references []Reference, nameserver string, runs int) *Summary {
+	summary := &Summary{Runs: runs}
+
+	for run := 1; run <= runs; run++ {
+		for _, reference := range references {
+			summary.Results = append(summary.Results, runReference(reference, nameserver, run))
+		}
+	}
+
+	summary.aggregate()
+	return summary
+}
+
+// runReference checks a single reference domain and compares the results with the expectations
+func runReference(reference Reference, nameserver string, run int) Result {
+	result := Result{
+		Domain:   reference.Domain,
+		Run:      run,
+		Expected: len(reference.Expect),
+	}
+
+	start := time.Now()
+	enhanced, err := check.Run(reference.Domain, nameserver, "domain", dns.Options{})
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	statuses := make(map[int]string)
+	for _, ruleResult := range enhanced.RuleResults {
+		statuses[ruleResult.RuleID] = ruleResult.Status
+	}
+
+	// Compare in rule ID order for stable output
+	var ruleIDs []int
+	for ruleID := range reference.Expect {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Ints(ruleIDs)
+
+	for _, ruleID := range ruleIDs {
+		expected := reference.Expect[ruleID]
+		if statuses[ruleID] == expected {
+			result.Matched++
+		} else {
+			result.Mismatches = append(result.Mismatches, Mismatch{RuleID: ruleID, Expected: expected, Actual: statuses[ruleID]})
+		}
+	}
+
+	return result
+}
+
+// aggregate computes the summary statistics from the individual results
+func (s *Summary) aggregate() {
+	if len(s.Results) == 0 {
+		return
+	}
+
+	var durations []time.Duration
+	for _, result := range s.Results {
+		durations = append(durations, result.Duration)
+		s.TotalDuration += result.Duration
+		s.Expected += result.Expected
+		s.Matched += result.Matched
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	s.MinDuration = durations[0]
+	s.MaxDuration = durations[len(durations)-1]
+	s.AvgDuration = s.TotalDuration / time.Duration(len(durations))
+	s.P95Duration = durations[(len(durations)*95+99)/100-1]
+
+	if s.Expected > 0 {
+		s.Correctness = float64(s.Matched) / float64(s.Expected) * 100
+	}
+}
+
+// Print writes a human readable benchmark report to stdout
+func (s *Summary) Print() {
+	fmt.Println("Benchmark Results:")
+	for _, result := range s.Results {
+		status := "✅"
+		if result.Error != "" || len(result.Mismatches) > 0 {
+			status = "❌"
+		}
+		fmt.Printf("%s run %d %s: %v, %d/%d expectations met\n", status, result.Run, result.Domain, result.Duration.Round(time.Millisecond), result.Matched, result.Expected)
+		if result.Error != "" {
+			fmt.Printf("    Error: %s\n", result.Error)
+		}
+		for _, mismatch := range result.Mismatches {
+			actual := mismatch.Actual
+			if actual == "" {
+				actual = "no result"
+			}
+			fmt.Printf("    Rule %d: expected %s, got %s\n", mismatch.RuleID, mismatch.Expected, actual)
+		}
+	}
+
+	fmt.Println("\nSummary:")
+	fmt.Printf("Checks: %d (%d runs)\n", len(s.Results), s.Runs)
+	fmt.Printf("Duration: total %v, min %v, avg %v, p95 %v, max %v\n",
+		s.TotalDuration.Round(time.Millisecond), s.MinDuration.Round(time.Millisecond), s.AvgDuration.Round(time.Millisecond),
+		s.P95Duration.Round(time.Millisecond), s.MaxDuration.Round(time.Millisecond))
+	fmt.Printf("Correctness: %d/%d expectations met (%.1f%%)\n", s.Matched, s.Expected, s.Correctness)
+}
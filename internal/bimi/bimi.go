@@ -0,0 +1,174 @@
+package bimi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"check-maildomain/internal/dmarc"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultSelector is the selector receivers query unless a message names another one in its BIMI-Selector header
+const DefaultSelector = "default"
+
+// BIMIInfo contains the BIMI assertion record of a domain
+type BIMIInfo struct {
+	Domain   string    // Domain the lookup started at
+	Name     string    // Name the record was found at, the organizational domain's when inherited
+	Records  []string  // Every TXT record at the name starting with v=BIMI1
+	Record   *Record   // The parsed record, nil unless exactly one record is published
+	Logo     *Logo     // The fetched and validated SVG logo, nil without a valid record with a logo
+	Evidence *Evidence // The fetched and validated mark certificate, nil without a valid record with an a tag
+}
+
+// Record represents a parsed BIMI assertion record
+type Record struct {
+	Raw       string            // The complete raw TXT record
+	Tags      map[string]string // All tags and their values
+	Logo      string            // l tag, HTTPS URL of the SVG logo
+	Authority string            // a tag, HTTPS URL of the VMC or CMC evidence document
+	Declined  bool              // Whether the record declines to publish a logo (empty l tag)
+	Errors    []string          // Problems that make receivers ignore the record
+}
+
+// Valid reports whether receivers can use the record
+func (r *Record) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Lookup queries the BIMI record at default._bimi of the domain, falling back to the organizational domain
+// when the domain publishes none (draft-brand-indicators-for-message-identification section 4.5), and
+// fetches the logo and mark certificate of a valid record
+func Lookup(domain string, nameserver string) (*BIMIInfo, error) {
+	info := &BIMIInfo{Domain: domain}
+
+	names := []string{domain}
+	if orgDomain := dmarc.OrganizationalDomain(domain); orgDomain != strings.ToLower(strings.TrimSuffix(domain, ".")) {
+		names = append(names, orgDomain)
+	}
+
+	for _, name := range names {
+		records, err := queryRecords(DefaultSelector+"._bimi."+name, nameserver)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		info.Name = DefaultSelector + "._bimi." + name
+		info.Records = records
+		if len(records) == 1 {
+			info.Record = ParseRecord(records[0])
+			if info.Record.Valid() && info.Record.Logo != "" {
+				info.Logo = FetchLogo(info.Record.Logo)
+			}
+			if info.Record.Valid() && info.Record.Authority != "" {
+				info.Evidence = FetchEvidence(info.Record.Authority, name, info.Logo)
+			}
+		}
+		break
+	}
+	return info, nil
+}
+
+// queryRecords returns the TXT records at the name starting with v=BIMI1
+func queryRecords(name string, nameserver string) ([]string, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query failed: %v", err)
+	}
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+	}
+
+	var records []string
+	for _, a := range r.Answer {
+		if txt, ok := a.(*dns.TXT); ok {
+			value := strings.Join(txt.Txt, "")
+			if strings.HasPrefix(strings.TrimSpace(value), "v=BIMI1") {
+				records = append(records, value)
+			}
+		}
+	}
+	return records, nil
+}
+
+// ParseRecord parses a BIMI assertion record of semicolon separated tag=value pairs
+func ParseRecord(raw string) *Record {
+	record := &Record{
+		Raw:  raw,
+		Tags: make(map[string]string),
+	}
+
+	for i, field := range strings.Split(raw, ";") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			record.Errors = append(record.Errors, fmt.Sprintf("field %q is not a tag=value pair", field))
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if i == 0 && (key != "v" || value != "BIMI1") {
+			record.Errors = append(record.Errors, "the record must start with v=BIMI1")
+		}
+		if _, duplicate := record.Tags[key]; duplicate {
+			record.Errors = append(record.Errors, fmt.Sprintf("tag %s appears more than once", key))
+			continue
+		}
+		record.Tags[key] = value
+	}
+
+	logo, hasLogo := record.Tags["l"]
+	record.Logo = logo
+	record.Authority = record.Tags["a"]
+	switch {
+	case !hasLogo:
+		record.Errors = append(record.Errors, "the l tag is missing")
+	case logo == "":
+		record.Declined = record.Authority == ""
+	}
+
+	for _, tag := range []string{"l", "a"} {
+		value := record.Tags[tag]
+		if value == "" {
+			continue
+		}
+		if problem := urlProblem(value); problem != "" {
+			record.Errors = append(record.Errors, fmt.Sprintf("%s=%s %s", tag, value, problem))
+		}
+	}
+	if record.Logo == "" && record.Authority != "" {
+		record.Errors = append(record.Errors, "the a tag requires a logo in the l tag")
+	}
+	return record
+}
+
+// urlProblem describes why a logo or evidence URL is unusable, or returns an empty string
+func urlProblem(raw string) string {
+	if strings.Contains(raw, ",") {
+		return "must be a single URL"
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Sprintf("is not a valid URL: %v", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return "must be an HTTPS URL"
+	}
+	return ""
+}
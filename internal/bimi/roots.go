@@ -0,0 +1,46 @@
+package bimi
+
+import (
+	"crypto/x509"
+	"embed"
+	"encoding/pem"
+	"io/fs"
+	"path"
+)
+
+// rootFiles are the pinned root certificates of the mark certificate authorities
+//
+//go:embed roots
+var rootFiles embed.FS
+
+// MarkRoots returns the pinned root certificates of the recognized mark certificate authorities. Mark
+// certificates don't chain to the roots of the system trust store, so they are verified against these only.
+func MarkRoots() []*x509.Certificate {
+	var roots []*x509.Certificate
+	matches, _ := fs.Glob(rootFiles, path.Join("roots", "*.pem"))
+	for _, name := range matches {
+		data, err := rootFiles.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil && block.Type == "CERTIFICATE" {
+				roots = append(roots, cert)
+			}
+		}
+	}
+	return roots
+}
+
+// rootName names the certificate authority of a root, preferring its organization
+func rootName(root *x509.Certificate) string {
+	if len(root.Subject.Organization) > 0 {
+		return root.Subject.Organization[0]
+	}
+	return root.Subject.CommonName
+}
@@ -0,0 +1,194 @@
+package bimi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxLogoSize is the largest logo the BIMI guidance accepts; larger files are ignored by mailbox providers
+const maxLogoSize = 32 * 1024
+
+// maxTitleLength is the longest title the SVG Tiny Portable/Secure profile allows
+const maxTitleLength = 65
+
+// forbiddenElements are SVG elements the Tiny Portable/Secure profile doesn't allow, since they execute
+// code, load external content or animate the logo
+var forbiddenElements = map[string]string{
+	"script":           "scripts are not allowed",
+	"foreignObject":    "foreign objects are not allowed",
+	"image":            "embedded raster images are not allowed",
+	"a":                "links are not allowed",
+	"animate":          "animations are not allowed",
+	"animateMotion":    "animations are not allowed",
+	"animateColor":     "animations are not allowed",
+	"animateTransform": "animations are not allowed",
+	"set":              "animations are not allowed",
+	"iframe":           "frames are not allowed",
+}
+
+// Logo contains the result of fetching and validating the SVG logo of a BIMI record
+type Logo struct {
+	URL         string   // URL of the logo
+	ContentType string   // Content-Type header of the response
+	Size        int      // Size of the logo in bytes
+	Data        []byte   `json:"-"` // The logo as served, to compare with the mark certificate
+	Violations  []string // Deviations from the SVG Tiny Portable/Secure profile
+	Error       string   // Why the logo could not be fetched
+}
+
+// FetchLogo fetches the SVG logo over HTTPS and validates it against the SVG Tiny Portable/Secure (SVG
+// Tiny PS) profile that mailbox providers require
+func FetchLogo(url string) *Logo {
+	logo := &Logo{URL: url}
+	if !strings.HasPrefix(strings.ToLower(url), "https://") {
+		logo.Error = "the logo must be served over HTTPS"
+		return logo
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		logo.Error = fmt.Sprintf("fetching the logo failed: %v", err)
+		return logo
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logo.Error = fmt.Sprintf("%s returned HTTP %d", url, resp.StatusCode)
+		return logo
+	}
+
+	// Read one byte more than allowed to detect oversized logos without reading them completely
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLogoSize+1))
+	if err != nil {
+		logo.Error = fmt.Sprintf("reading the logo failed: %v", err)
+		return logo
+	}
+	logo.ContentType = resp.Header.Get("Content-Type")
+	logo.Size = len(data)
+	logo.Data = data
+
+	if len(data) > maxLogoSize {
+		logo.Violations = append(logo.Violations, fmt.Sprintf("the logo is larger than %d KB", maxLogoSize/1024))
+		return logo
+	}
+	if mediaType, _, _ := strings.Cut(logo.ContentType, ";"); !strings.EqualFold(strings.TrimSpace(mediaType), "image/svg+xml") {
+		logo.Violations = append(logo.Violations, fmt.Sprintf("the logo is served as %q instead of image/svg+xml", logo.ContentType))
+	}
+	logo.Violations = append(logo.Violations, ValidateSVG(data)...)
+	return logo
+}
+
+// ValidateSVG checks an SVG document against the SVG Tiny PS profile: an svg root element with version 1.2
+// and baseProfile tiny-ps, a title, and no scripts, external references, raster images or animations
+func ValidateSVG(data []byte) []string {
+	var violations []string
+	seen := make(map[string]bool)
+	violate := func(violation string) {
+		if !seen[violation] {
+			seen[violation] = true
+			violations = append(violations, violation)
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	hasRoot, hasTitle := false, false
+	inTitle := false
+	var title strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			violate(fmt.Sprintf("the logo is not well-formed XML: %v", err))
+			break
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			depth++
+			name := element.Name.Local
+			if depth == 1 {
+				hasRoot = name == "svg"
+				if !hasRoot {
+					violate(fmt.Sprintf("the root element is <%s> instead of <svg>", name))
+				} else {
+					violations = append(violations, rootViolations(element)...)
+				}
+			}
+			if depth == 2 && name == "title" {
+				hasTitle = true
+				inTitle = true
+			}
+			if reason, forbidden := forbiddenElements[name]; forbidden {
+				violate(fmt.Sprintf("<%s>: %s", name, reason))
+			}
+			for _, attr := range element.Attr {
+				if attr.Name.Local == "href" && !strings.HasPrefix(strings.TrimSpace(attr.Value), "#") {
+					violate(fmt.Sprintf("external reference %q in <%s> is not allowed", attr.Value, name))
+				}
+				if strings.HasPrefix(strings.ToLower(attr.Name.Local), "on") {
+					violate(fmt.Sprintf("event handler %s in <%s> is not allowed", attr.Name.Local, name))
+				}
+			}
+		case xml.EndElement:
+			depth--
+			inTitle = false
+		case xml.CharData:
+			if inTitle {
+				title.Write(element)
+			}
+		case xml.Directive:
+			if bytes.Contains(bytes.ToUpper(element), []byte("ENTITY")) {
+				violate("entity declarations are not allowed")
+			}
+		}
+	}
+
+	if !hasRoot {
+		violate("the logo has no <svg> root element")
+	}
+	switch {
+	case !hasTitle:
+		violate("the <title> element is missing, it must be a direct child of <svg>")
+	case strings.TrimSpace(title.String()) == "":
+		violate("the <title> element is empty")
+	case len(strings.TrimSpace(title.String())) > maxTitleLength:
+		violate(fmt.Sprintf("the title is longer than %d characters", maxTitleLength))
+	}
+	return violations
+}
+
+// rootViolations checks the attributes of the root svg element
+func rootViolations(root xml.StartElement) []string {
+	attrs := make(map[string]string)
+	for _, attr := range root.Attr {
+		attrs[attr.Name.Local] = attr.Value
+	}
+
+	var violations []string
+	if attrs["version"] != "1.2" {
+		violations = append(violations, fmt.Sprintf("the svg version is %q instead of 1.2", attrs["version"]))
+	}
+	if attrs["baseProfile"] != "tiny-ps" {
+		violations = append(violations, fmt.Sprintf("the svg baseProfile is %q instead of tiny-ps", attrs["baseProfile"]))
+	}
+	for _, attr := range []string{"x", "y"} {
+		if _, ok := attrs[attr]; ok {
+			violations = append(violations, fmt.Sprintf("the %s attribute is not allowed on the svg element", attr))
+		}
+	}
+	if viewBox, ok := attrs["viewBox"]; ok {
+		if fields := strings.Fields(strings.ReplaceAll(viewBox, ",", " ")); len(fields) == 4 && fields[2] != fields[3] {
+			violations = append(violations, fmt.Sprintf("the viewBox %q is not square", viewBox))
+		}
+	}
+	return violations
+}
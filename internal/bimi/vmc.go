@@ -0,0 +1,295 @@
+package bimi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxEvidenceSize limits how much of the evidence document is read
+const maxEvidenceSize = 256 * 1024
+
+// Kinds of mark certificates
+const (
+	TypeVMC = "VMC" // Verified Mark Certificate, for a registered trademark
+	TypeCMC = "CMC" // Common Mark Certificate, for a mark in prior use
+)
+
+var (
+	// oidBIMIKeyUsage is the extended key usage of mark certificates (id-kp-BrandIndicatorforMessageIdentification)
+	oidBIMIKeyUsage = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 31}
+	// oidLogotype is the logotype extension carrying the logo (RFC 3709)
+	oidLogotype = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 12}
+	// oidMarkType is the subject attribute naming the kind of mark
+	oidMarkType = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 53087, 1, 13}
+)
+
+// cmcMarkTypes are the mark types issued as Common Mark Certificates
+var cmcMarkTypes = map[string]bool{
+	"Prior Use Mark":           true,
+	"Modified Registered Mark": true,
+}
+
+// Evidence contains the result of fetching and validating the mark certificate (a tag) of a BIMI record
+type Evidence struct {
+	URL         string    // URL of the evidence document
+	Type        string    // VMC or CMC, derived from the mark type
+	MarkType    string    // Mark type subject attribute, e.g. "Registered Mark"
+	Subject     string    // Subject of the mark certificate
+	Issuer      string    // Issuer of the mark certificate
+	NotBefore   time.Time // Start of the validity period
+	NotAfter    time.Time // End of the validity period
+	Names       []string  // DNS names the certificate is valid for
+	KnownIssuer string    // The pinned mark certificate authority root the chain verifies to, empty when it doesn't
+	ChainError  string    // Why the certificate chain doesn't verify to a pinned root, empty when it does
+	ChainPinned bool      // Whether roots are pinned to verify the chain against, without them it is unchecked
+	LogoHash    string    // SHA-256 of the logo embedded in the certificate
+	LogoMatches bool      // Whether the embedded logo is the published logo
+	Problems    []string  // Every problem that makes receivers reject the certificate
+	Error       string    // Why the evidence document could not be fetched or parsed
+}
+
+// FetchEvidence fetches the PEM evidence document of a BIMI record and validates the mark certificate: the
+// certificate chain up to a pinned mark certificate authority root, the validity period, the BIMI extended key
+// usage, the domain, and whether the embedded logo matches the published one
+func FetchEvidence(url string, domain string, logo *Logo) *Evidence {
+	evidence := &Evidence{URL: url}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		evidence.Error = fmt.Sprintf("fetching the evidence document failed: %v", err)
+		return evidence
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		evidence.Error = fmt.Sprintf("%s returned HTTP %d", url, resp.StatusCode)
+		return evidence
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEvidenceSize))
+	if err != nil {
+		evidence.Error = fmt.Sprintf("reading the evidence document failed: %v", err)
+		return evidence
+	}
+
+	chain, err := parseChain(data)
+	if err != nil {
+		evidence.Error = err.Error()
+		return evidence
+	}
+	evidence.validate(chain, MarkRoots(), domain, logo, time.Now())
+	return evidence
+}
+
+// parseChain decodes the PEM certificates of the evidence document, the mark certificate first
+func parseChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("the evidence document contains an invalid certificate: %v", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("the evidence document contains no PEM certificates")
+	}
+	return chain, nil
+}
+
+// validate checks the mark certificate and fills in the evidence
+func (e *Evidence) validate(chain []*x509.Certificate, roots []*x509.Certificate, domain string, logo *Logo, now time.Time) {
+	leaf := chain[0]
+	e.Subject = leaf.Subject.String()
+	e.Issuer = leaf.Issuer.String()
+	e.NotBefore = leaf.NotBefore
+	e.NotAfter = leaf.NotAfter
+	e.Names = leaf.DNSNames
+
+	e.Type = TypeVMC
+	for _, name := range leaf.Subject.Names {
+		if name.Type.Equal(oidMarkType) {
+			e.MarkType = fmt.Sprintf("%v", name.Value)
+		}
+	}
+	if cmcMarkTypes[e.MarkType] {
+		e.Type = TypeCMC
+	}
+
+	switch {
+	case now.Before(leaf.NotBefore):
+		e.Problems = append(e.Problems, fmt.Sprintf("the certificate is not valid before %s", leaf.NotBefore.Format(time.RFC3339)))
+	case now.After(leaf.NotAfter):
+		e.Problems = append(e.Problems, fmt.Sprintf("the certificate expired on %s", leaf.NotAfter.Format(time.RFC3339)))
+	}
+
+	hasUsage := false
+	for _, usage := range leaf.UnknownExtKeyUsage {
+		if usage.Equal(oidBIMIKeyUsage) {
+			hasUsage = true
+		}
+	}
+	if !hasUsage {
+		e.Problems = append(e.Problems, "the certificate lacks the BIMI extended key usage, it is not a mark certificate")
+	}
+
+	if !coversDomain(leaf.DNSNames, domain) {
+		e.Problems = append(e.Problems, fmt.Sprintf("the certificate is not issued for %s (%s)", domain, strings.Join(leaf.DNSNames, ", ")))
+	}
+
+	e.ChainPinned = len(roots) > 0
+	if !e.ChainPinned {
+		// Without pinned roots nothing can be said about the chain, which doesn't make the certificate invalid
+		e.ChainError = "no mark certificate authority roots are pinned, the certificate chain was not verified"
+	} else if root, err := verifyChain(chain, roots, now); err != nil {
+		e.ChainError = err.Error()
+		e.Problems = append(e.Problems, err.Error())
+	} else {
+		e.KnownIssuer = rootName(root)
+	}
+
+	embedded, err := embeddedLogo(leaf)
+	if err != nil {
+		e.Problems = append(e.Problems, err.Error())
+		return
+	}
+	digest := sha256.Sum256(embedded)
+	e.LogoHash = hex.EncodeToString(digest[:])
+	if logo != nil && logo.Data != nil {
+		published := sha256.Sum256(logo.Data)
+		e.LogoMatches = digest == published
+	}
+}
+
+// coversDomain reports whether one of the DNS names of the certificate is the domain
+func coversDomain(names []string, domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for _, name := range names {
+		if strings.ToLower(name) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyChain verifies the mark certificate against the pinned roots, with the other certificates of the
+// evidence document as intermediates, and returns the root it chains to. crypto/x509 doesn't know the BIMI
+// key usage, so the chain is built for any usage and the intermediates are then required to allow it.
+func verifyChain(chain []*x509.Certificate, roots []*x509.Certificate, now time.Time) (*x509.Certificate, error) {
+	rootPool := x509.NewCertPool()
+	for _, root := range roots {
+		rootPool.AddCert(root)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("the certificate doesn't chain to a known mark certificate authority: %v", err)
+	}
+	verified := chains[0]
+	for _, cert := range verified[1 : len(verified)-1] {
+		if !allowsBIMIUsage(cert) {
+			return nil, fmt.Errorf("intermediate %s is not allowed to issue mark certificates", cert.Subject.CommonName)
+		}
+	}
+	return verified[len(verified)-1], nil
+}
+
+// allowsBIMIUsage reports whether a CA certificate may issue certificates for the BIMI key usage: it doesn't
+// restrict the extended key usages, or it lists the BIMI usage or any usage
+func allowsBIMIUsage(cert *x509.Certificate) bool {
+	if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+		return true
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	for _, usage := range cert.UnknownExtKeyUsage {
+		if usage.Equal(oidBIMIKeyUsage) {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedLogo extracts the SVG logo from the logotype extension, where it is embedded as a data URI of
+// the gzip compressed SVG
+func embeddedLogo(cert *x509.Certificate) ([]byte, error) {
+	for _, extension := range cert.Extensions {
+		if !extension.Id.Equal(oidLogotype) {
+			continue
+		}
+		for _, uri := range ia5Strings(extension.Value) {
+			header, payload, found := strings.Cut(uri, ",")
+			if !found || !strings.HasPrefix(header, "data:image/svg+xml") || !strings.HasSuffix(header, ";base64") {
+				continue
+			}
+			compressed, err := base64.StdEncoding.DecodeString(payload)
+			if err != nil {
+				return nil, fmt.Errorf("the embedded logo is not valid base64: %v", err)
+			}
+			reader, err := gzip.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				// Uncompressed SVG
+				return compressed, nil
+			}
+			svg, err := io.ReadAll(io.LimitReader(reader, maxEvidenceSize))
+			if err != nil {
+				return nil, fmt.Errorf("the embedded logo can't be decompressed: %v", err)
+			}
+			return svg, nil
+		}
+		return nil, fmt.Errorf("the logotype extension contains no embedded SVG logo")
+	}
+	return nil, fmt.Errorf("the certificate has no logotype extension with the logo")
+}
+
+// ia5Strings returns every IA5String found in a DER structure, which is where the logotype extension keeps
+// its URIs
+func ia5Strings(der []byte) []string {
+	var values []string
+	for len(der) > 0 {
+		var value asn1.RawValue
+		rest, err := asn1.Unmarshal(der, &value)
+		if err != nil {
+			return values
+		}
+		switch {
+		case value.IsCompound:
+			values = append(values, ia5Strings(value.Bytes)...)
+		case value.Class == asn1.ClassUniversal && value.Tag == asn1.TagIA5String:
+			values = append(values, string(value.Bytes))
+		}
+		der = rest
+	}
+	return values
+}
@@ -0,0 +1,99 @@
+package caa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Record is a single CAA record
+type Record struct {
+	Flag  uint8
+	Tag   string // Property tag, e.g. "issue", "issuewild" or "iodef"
+	Value string
+}
+
+// Result contains the CAA records relevant for a name
+type Result struct {
+	Name    string   // Name the lookup started at
+	FoundAt string   // Name the relevant CAA records were found at, empty when no CAA restricts the name
+	Records []Record // Relevant CAA records
+	Error   string   // Any error encountered during the lookup
+}
+
+// Has reports whether the records contain a property with the tag. Issuance of certificates for the name
+// itself is only restricted by issue properties, and of wildcard certificates by issuewild properties, or by
+// issue properties when there are none (RFC 8659 section 4).
+func (r *Result) Has(tag string) bool {
+	for _, record := range r.Records {
+		if record.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Issuers returns the certificate authorities the issue or issuewild properties allow to issue certificates.
+// An empty issuer (";") is not listed, so properties without any issuer forbid issuance.
+func (r *Result) Issuers(tag string) []string {
+	seen := make(map[string]bool)
+	issuers := []string{}
+	for _, record := range r.Records {
+		if record.Tag != tag {
+			continue
+		}
+		issuer := strings.TrimSpace(strings.SplitN(record.Value, ";", 2)[0])
+		if issuer != "" && !seen[issuer] {
+			seen[issuer] = true
+			issuers = append(issuers, issuer)
+		}
+	}
+	return issuers
+}
+
+// Lookup returns the CAA records relevant for the name: the CAA records of the name itself or, when it has
+// none, of the closest parent domain that has them (RFC 8659 section 3)
+func Lookup(name string, nameserver string) Result {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	name = strings.TrimSuffix(name, ".")
+	result := Result{Name: name, Records: []Record{}}
+
+	c := new(dns.Client)
+	labels := dns.SplitDomainName(name)
+	// The top level domain is not climbed to, registries don't publish CAA for their customers
+	for i := 0; i < len(labels)-1; i++ {
+		current := strings.Join(labels[i:], ".")
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(current), dns.TypeCAA)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			result.Error = fmt.Sprintf("DNS query failed: %v", err)
+			return result
+		}
+		if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+			result.Error = fmt.Sprintf("DNS query for %s returned non-success code: %v", current, dns.RcodeToString[r.Rcode])
+			return result
+		}
+
+		for _, a := range r.Answer {
+			if record, ok := a.(*dns.CAA); ok {
+				result.Records = append(result.Records, Record{
+					Flag:  record.Flag,
+					Tag:   strings.ToLower(record.Tag),
+					Value: record.Value,
+				})
+			}
+		}
+		if len(result.Records) > 0 {
+			result.FoundAt = current
+			return result
+		}
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package check
+
+import (
+	"fmt"
+	"net"
+	"runtime/debug"
+
+	"check-maildomain/internal/dns"
+	"check-maildomain/internal/host"
+	"check-maildomain/internal/idn"
+	"check-maildomain/internal/rules"
+)
+
+// Run checks a single input (mail domain, hostname or IP) and applies the matching rule set.
+// A panic anywhere in the pipeline is recovered and converted into an error result for this input,
+// so that a malformed record or parser bug cannot take down the whole process.
+func Run(input string, nameserver string, inputType string, opts dns.Options) (*rules.EnhancedDomainInfo, error) {
+	return RunWithSettings(input, nameserver, inputType, opts, rules.DefaultSettings())
+}
+
+// RunWithSettings is Run with configured rule settings
+func RunWithSettings(input string, nameserver string, inputType string, opts dns.Options, settings rules.Settings) (result *rules.EnhancedDomainInfo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = panicResult(input, r)
+			err = nil
+		}
+	}()
+
+	// Internationalized names are queried in their ASCII (punycode) form
+	if net.ParseIP(input) == nil {
+		ascii, err := idn.ToASCII(input)
+		if err != nil {
+			return nil, err
+		}
+		input = ascii
+	}
+
+	// Detect whether the input is a mail domain, a mail server hostname or a bare IP
+	if inputType == "" || inputType == "auto" {
+		inputType = host.DetectInputType(input, nameserver)
+	}
+
+	// Collect all DNS information
+	var info *dns.DomainInfo
+	switch inputType {
+	case host.TypeDomain:
+		info, err = dns.CollectDNSInfoWithOptions(input, nameserver, opts)
+	case host.TypeHost, host.TypeIP:
+		info, err = dns.CollectHostInfo(input, nameserver, opts)
+	default:
+		return nil, fmt.Errorf("unknown input type: %s", inputType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if idn.IsInternationalized(info.Domain) {
+		info.UnicodeDomain = idn.ToUnicode(info.Domain)
+	}
+
+	// Create enhanced domain info and apply rules
+	result = rules.NewEnhancedDomainInfoWithSettings(info, settings)
+	rules.ApplyAllRules(result)
+
+	return result, nil
+}
+
+// panicResult converts a recovered panic into a structured error result
+func panicResult(input string, r interface{}) *rules.EnhancedDomainInfo {
+	info := dns.NewDomainInfo(input)
+	info.Errors["panic"] = fmt.Errorf("%v\n%s", r, debug.Stack())
+
+	result := rules.NewEnhancedDomainInfo(info)
+	rules.ApplyErrorRules(result, fmt.Sprintf("%v", r))
+	return result
+}
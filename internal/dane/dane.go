@@ -0,0 +1,170 @@
+package dane
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Certificate usages (RFC 6698 section 2.1.1, RFC 7218)
+const (
+	UsagePKIXTA = 0 // CA constraint, validated against the public CA system
+	UsagePKIXEE = 1 // Service certificate constraint, validated against the public CA system
+	UsageDANETA = 2 // Trust anchor assertion, the issuing CA of the server certificate
+	UsageDANEEE = 3 // Domain-issued certificate, the server certificate itself
+)
+
+// Selectors (RFC 6698 section 2.1.2)
+const (
+	SelectorCert = 0 // The full certificate
+	SelectorSPKI = 1 // The SubjectPublicKeyInfo of the certificate
+)
+
+// Matching types (RFC 6698 section 2.1.3)
+const (
+	MatchingFull     = 0 // The selected content itself
+	MatchingSHA2_256 = 1 // SHA-256 hash of the selected content
+	MatchingSHA2_512 = 2 // SHA-512 hash of the selected content
+)
+
+var usageNames = map[uint8]string{
+	UsagePKIXTA: "PKIX-TA",
+	UsagePKIXEE: "PKIX-EE",
+	UsageDANETA: "DANE-TA",
+	UsageDANEEE: "DANE-EE",
+}
+
+var selectorNames = map[uint8]string{
+	SelectorCert: "Cert",
+	SelectorSPKI: "SPKI",
+}
+
+var matchingNames = map[uint8]string{
+	MatchingFull:     "Full",
+	MatchingSHA2_256: "SHA2-256",
+	MatchingSHA2_512: "SHA2-512",
+}
+
+// digestLengths are the lengths in bytes of the association data of the hash matching types
+var digestLengths = map[uint8]int{
+	MatchingSHA2_256: 32,
+	MatchingSHA2_512: 64,
+}
+
+// TLSARecord represents a parsed TLSA record
+type TLSARecord struct {
+	Usage        uint8  // Certificate usage field
+	Selector     uint8  // Which part of the certificate is matched
+	MatchingType uint8  // How the certificate association data is matched
+	Data         string // Certificate association data in hex
+	Problem      string // Why SMTP senders can't use the record, empty when usable
+}
+
+// HostTLSA contains the TLSA records published for SMTP on an MX host
+type HostTLSA struct {
+	Host          string         // MX host
+	Name          string         // Name the TLSA records were queried at
+	Records       []TLSARecord   // TLSA records at the name
+	Authenticated bool           // Whether the resolver validated the answer with DNSSEC (AD bit)
+	Verifications []Verification // Matches against the certificates presented on port 25, only with an SMTP probe
+	Error         string         // Any error encountered during the lookup
+}
+
+// String returns the record in the mnemonic form of RFC 7218, e.g. "DANE-EE(3) SPKI(1) SHA2-256(1)"
+func (r TLSARecord) String() string {
+	return fmt.Sprintf("%s(%d) %s(%d) %s(%d)", nameOf(usageNames, r.Usage), r.Usage, nameOf(selectorNames, r.Selector), r.Selector,
+		nameOf(matchingNames, r.MatchingType), r.MatchingType)
+}
+
+// Usable reports whether SMTP senders can use the record to authenticate the server
+func (r TLSARecord) Usable() bool {
+	return r.Problem == ""
+}
+
+// UsableRecords returns the records SMTP senders can use
+func (h HostTLSA) UsableRecords() []TLSARecord {
+	var usable []TLSARecord
+	for _, record := range h.Records {
+		if record.Usable() {
+			usable = append(usable, record)
+		}
+	}
+	return usable
+}
+
+// nameOf returns the mnemonic of a TLSA field value, or "unknown"
+func nameOf(names map[uint8]string, value uint8) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// LookupTLSA queries the TLSA records for SMTP (_25._tcp) of an MX host. The DO bit is set so that a
+// validating resolver reports with the AD bit whether the records are DNSSEC-authenticated, without
+// which senders ignore them.
+func LookupTLSA(host string, nameserver string) HostTLSA {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	result := HostTLSA{
+		Host: host,
+		Name: "_25._tcp." + host,
+	}
+
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(result.Name), dns.TypeTLSA)
+	m.RecursionDesired = true
+	m.SetEdns0(4096, true)
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		result.Error = fmt.Sprintf("DNS query failed: %v", err)
+		return result
+	}
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		result.Error = fmt.Sprintf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+		return result
+	}
+
+	result.Authenticated = r.AuthenticatedData
+	for _, a := range r.Answer {
+		if tlsa, ok := a.(*dns.TLSA); ok {
+			result.Records = append(result.Records, ParseTLSA(tlsa))
+		}
+	}
+	return result
+}
+
+// ParseTLSA converts a TLSA resource record and determines whether SMTP senders can use it. RFC 7672
+// section 3.1.3 makes senders treat the PKIX usages as unusable, since the MX hostname is not
+// authenticated the way the public CA system expects.
+func ParseTLSA(tlsa *dns.TLSA) TLSARecord {
+	record := TLSARecord{
+		Usage:        tlsa.Usage,
+		Selector:     tlsa.Selector,
+		MatchingType: tlsa.MatchingType,
+		Data:         strings.ToLower(tlsa.Certificate),
+	}
+
+	data, err := hex.DecodeString(record.Data)
+	switch {
+	case record.Usage == UsagePKIXTA || record.Usage == UsagePKIXEE:
+		record.Problem = fmt.Sprintf("usage %s is not used for SMTP, senders only accept DANE-TA(2) and DANE-EE(3)", usageNames[record.Usage])
+	case record.Usage > UsageDANEEE:
+		record.Problem = fmt.Sprintf("unknown certificate usage %d", record.Usage)
+	case record.Selector > SelectorSPKI:
+		record.Problem = fmt.Sprintf("unknown selector %d", record.Selector)
+	case record.MatchingType > MatchingSHA2_512:
+		record.Problem = fmt.Sprintf("unknown matching type %d", record.MatchingType)
+	case err != nil || len(data) == 0:
+		record.Problem = "the certificate association data is not valid hex"
+	case digestLengths[record.MatchingType] != 0 && len(data) != digestLengths[record.MatchingType]:
+		record.Problem = fmt.Sprintf("%s data must be %d bytes, not %d", matchingNames[record.MatchingType], digestLengths[record.MatchingType], len(data))
+	}
+	return record
+}
@@ -0,0 +1,104 @@
+package dane
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Verification contains the result of matching the TLSA records of an MX host against the certificate
+// chain presented on port 25 of one of its addresses
+type Verification struct {
+	IP        string // Address that was probed
+	Connected bool   // Whether the SMTP session was established, so that a failure is the server's fault
+	Matched   string // The TLSA record that matched, in mnemonic form, empty when none matched
+	Error     string // Why the chain did not match or could not be retrieved
+}
+
+// Verify matches the certificate chain presented by an MX host against its usable TLSA records (RFC 7672
+// section 3.2). A DANE-EE(3) record must match the server certificate itself, without name or expiry
+// checks. A DANE-TA(2) record must match a certificate of the chain that issues the server certificate,
+// which must then be valid for the MX hostname.
+func Verify(records []TLSARecord, chain []*x509.Certificate, host string) (TLSARecord, error) {
+	if len(chain) == 0 {
+		return TLSARecord{}, fmt.Errorf("no certificate presented")
+	}
+
+	var problems []string
+	for _, record := range records {
+		if !record.Usable() {
+			continue
+		}
+
+		switch record.Usage {
+		case UsageDANEEE:
+			if matches(record, chain[0]) {
+				return record, nil
+			}
+		case UsageDANETA:
+			for _, cert := range chain[1:] {
+				if !matches(record, cert) {
+					continue
+				}
+				if err := verifyTrustAnchor(chain, cert, host); err != nil {
+					problems = append(problems, fmt.Sprintf("%s matches %s, but %v", record, cert.Subject, err))
+					continue
+				}
+				return record, nil
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return TLSARecord{}, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return TLSARecord{}, fmt.Errorf("none of the TLSA records matches the presented certificate chain (%s)", chain[0].Subject)
+}
+
+// matches reports whether the certificate matches the selector, matching type and data of the record
+func matches(record TLSARecord, cert *x509.Certificate) bool {
+	expected, err := hex.DecodeString(record.Data)
+	if err != nil {
+		return false
+	}
+
+	content := cert.Raw
+	if record.Selector == SelectorSPKI {
+		content = cert.RawSubjectPublicKeyInfo
+	}
+
+	switch record.MatchingType {
+	case MatchingFull:
+		return bytes.Equal(content, expected)
+	case MatchingSHA2_256:
+		digest := sha256.Sum256(content)
+		return bytes.Equal(digest[:], expected)
+	case MatchingSHA2_512:
+		digest := sha512.Sum512(content)
+		return bytes.Equal(digest[:], expected)
+	}
+	return false
+}
+
+// verifyTrustAnchor checks that the server certificate chains to the trust anchor and is valid for the
+// MX hostname
+func verifyTrustAnchor(chain []*x509.Certificate, anchor *x509.Certificate, host string) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(anchor)
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
@@ -2,6 +2,7 @@ package dkim
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/miekg/dns"
@@ -15,6 +16,37 @@ type DKIMInfo struct {
 	Selectors    []string // List of discovered selectors
 	ResponseCode string   // DNS response code (NOERROR, NXDOMAIN, etc.)
 	Error        string   // Any error encountered during the check
+
+	SelectorTTLs map[string]uint32 // TTL in seconds of each discovered selector as configured at the authoritative nameservers, missing when unknown
+	Keys         []*DKIMKey        // Parsed key records of the discovered selectors
+
+	CustomSelectors bool             // Whether selectors that are expected to exist were supplied
+	SelectorResults []SelectorResult // Result of every selector that was queried
+
+	Walked          bool     // Whether the selectors were enumerated by walking the NSEC chain instead of guessed
+	WalkedSelectors []string // Selectors found in the NSEC chain below _domainkey
+	WalkError       string   // Why walking the NSEC chain failed, the selectors are guessed instead
+}
+
+// SelectorOptions controls which selectors are queried
+type SelectorOptions struct {
+	Supplied  []string // Selectors that are expected to exist, each reported individually
+	Discovery []string // Selectors to try for discovery, e.g. loaded from a wordlist
+	Walk      bool     // Enumerate the selectors by walking the NSEC chain of a DNSSEC-signed _domainkey zone
+}
+
+// SelectorResult contains the outcome of querying a single selector
+type SelectorResult struct {
+	Selector     string // Selector that was queried
+	Supplied     bool   // Whether the selector was supplied as expected to exist
+	Found        bool   // Whether the selector, or the end of its CNAME chain, answered with a TXT record
+	ResponseCode string // DNS response code (NOERROR, NXDOMAIN, etc.)
+	Error        string // Any error encountered during the query
+
+	TXT        []string // Raw character-strings of the TXT answer, exactly as published
+	CNAMEChain []string // CNAME targets followed when the selector is delegated, in order
+	Provider   string   // Signing provider identified from the CNAME targets
+	TCPOnly    bool     // Whether the record was only retrievable over TCP because UDP answers were truncated
 }
 
 // CommonSelectors is a list of commonly used DKIM selector names to check
@@ -23,8 +55,14 @@ var CommonSelectors = []string{
 	"google", "zoho", "mx", "key", "mta", "pm", "dkim-smtp", "s1", "s2",
 }
 
-// CheckDKIM checks if a domain has DKIM configured by looking for _domainkey record
+// CheckDKIM checks if a domain has DKIM configured by looking for _domainkey record and the CommonSelectors
 func CheckDKIM(domain string, nameserver string) (*DKIMInfo, error) {
+	return CheckDKIMSelectors(domain, nameserver, SelectorOptions{})
+}
+
+// CheckDKIMSelectors checks the _domainkey record and the selectors in opts. Without discovery selectors
+// the CommonSelectors are tried, unless only supplied selectors are given.
+func CheckDKIMSelectors(domain string, nameserver string, opts SelectorOptions) (*DKIMInfo, error) {
 	if !strings.HasSuffix(nameserver, ":53") {
 		nameserver = nameserver + ":53"
 	}
@@ -34,6 +72,46 @@ func CheckDKIM(domain string, nameserver string) (*DKIMInfo, error) {
 		HasDomainKey: false,
 		HasSelectors: false,
 		Selectors:    []string{},
+		SelectorTTLs: make(map[string]uint32),
+		Keys:         []*DKIMKey{},
+	}
+
+	// Query the supplied selectors first, then the discovery selectors
+	supplied := make(map[string]bool)
+	var selectors []string
+	for _, selector := range opts.Supplied {
+		selector = strings.TrimSpace(selector)
+		if selector != "" && !supplied[selector] {
+			supplied[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	info.CustomSelectors = len(selectors) > 0
+
+	discovery := opts.Discovery
+	if len(discovery) == 0 && !info.CustomSelectors {
+		discovery = CommonSelectors
+	}
+	if opts.Walk {
+		walked, err := WalkSelectors(domain, nameserver)
+		info.WalkedSelectors = walked
+		if err != nil {
+			// Guess the selectors, trying the ones found before the walk failed first
+			info.WalkError = err.Error()
+			discovery = append(walked, discovery...)
+		} else {
+			// The NSEC chain lists every existing name, there is nothing left to guess
+			info.Walked = true
+			discovery = walked
+		}
+	}
+	seen := make(map[string]bool)
+	for _, selector := range discovery {
+		selector = strings.TrimSpace(selector)
+		if selector != "" && !supplied[selector] && !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
 	}
 
 	c := dns.Client{}
@@ -58,35 +136,141 @@ func CheckDKIM(domain string, nameserver string) (*DKIMInfo, error) {
 		info.HasDomainKey = true
 	}
 
-	// Try to find some common selectors
-	for _, selector := range CommonSelectors {
-		selectorName := fmt.Sprintf("%s._domainkey.%s", selector, domain)
-		m := dns.Msg{}
-		m.SetQuestion(dns.Fqdn(selectorName), dns.TypeTXT)
-		m.RecursionDesired = true
+	// Try to find the selectors
+	for _, selector := range selectors {
+		result := SelectorResult{Selector: selector, Supplied: supplied[selector]}
 
-		r, _, err := c.Exchange(&m, nameserver)
+		selectorName := fmt.Sprintf("%s._domainkey.%s", selector, domain)
+		answer, err := querySelector(&c, selectorName, nameserver)
 		if err != nil {
+			result.Error = fmt.Sprintf("DNS query failed: %v", err)
+			info.SelectorResults = append(info.SelectorResults, result)
 			continue
 		}
+		r, txt := answer.msg, answer.txt
+		result.ResponseCode = dns.RcodeToString[r.Rcode]
+		result.CNAMEChain = answer.chain
+		result.TCPOnly = answer.tcp
+		for _, target := range answer.chain {
+			if provider, ok := LookupProvider(target); ok {
+				result.Provider = provider
+				break
+			}
+		}
 
-		// If we get a successful response and have answers, this selector exists
-		if r.Rcode == dns.RcodeSuccess && len(r.Answer) > 0 {
+		// The selector exists when a TXT record is returned, a CNAME without one at its end is dangling
+		if r.Rcode == dns.RcodeSuccess && txt != nil {
+			result.Found = true
+			result.TXT = txt.Txt
 			info.HasSelectors = true
 			info.Selectors = append(info.Selectors, selector)
+			info.Keys = append(info.Keys, ParseKey(selector, strings.Join(txt.Txt, "")))
 		}
+		info.SelectorResults = append(info.SelectorResults, result)
 	}
 
 	return info, nil
 }
 
+// maxCNAMEHops limits the number of CNAME targets followed for a single selector
+const maxCNAMEHops = 8
+
+// selectorAnswer is the outcome of querying a selector
+type selectorAnswer struct {
+	msg   *dns.Msg // The last response
+	chain []string // CNAME targets followed, in order
+	txt   *dns.TXT // The first TXT answer, if any
+	tcp   bool     // Whether the answer was only retrievable over TCP
+}
+
+// ednsBufferSize is the EDNS0 UDP buffer size used when a plain UDP answer is truncated
+const ednsBufferSize = 4096
+
+// querySelector queries the TXT record of a selector, following a CNAME chain the resolver did not chase
+func querySelector(c *dns.Client, name string, nameserver string) (selectorAnswer, error) {
+	var answer selectorAnswer
+	for hop := 0; hop < maxCNAMEHops; hop++ {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+		m.RecursionDesired = true
+
+		r, tcp, err := exchangeLarge(c, m, nameserver)
+		if err != nil {
+			return answer, err
+		}
+		answer.msg = r
+		answer.tcp = answer.tcp || tcp
+
+		for _, a := range r.Answer {
+			if cname, ok := a.(*dns.CNAME); ok {
+				answer.chain = append(answer.chain, strings.TrimSuffix(cname.Target, "."))
+			}
+		}
+		for _, a := range r.Answer {
+			if txt, ok := a.(*dns.TXT); ok {
+				answer.txt = txt
+				return answer, nil
+			}
+		}
+
+		// Query the end of the chain when the resolver did not chase it
+		if len(answer.chain) == 0 || strings.EqualFold(answer.chain[len(answer.chain)-1], name) {
+			break
+		}
+		name = answer.chain[len(answer.chain)-1]
+	}
+	return answer, nil
+}
+
+// exchangeLarge sends the query over UDP and, when the answer is truncated, retries with a larger EDNS0
+// buffer and finally over TCP. Large keys often don't fit a plain UDP response.
+func exchangeLarge(c *dns.Client, m *dns.Msg, nameserver string) (*dns.Msg, bool, error) {
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil || !r.Truncated {
+		return r, false, err
+	}
+
+	m.SetEdns0(ednsBufferSize, false)
+	r, _, err = c.Exchange(m, nameserver)
+	if err != nil || !r.Truncated {
+		return r, false, err
+	}
+
+	tcp := &dns.Client{Net: "tcp", Timeout: c.Timeout}
+	r, _, err = tcp.Exchange(m, nameserver)
+	return r, true, err
+}
+
 // CheckDKIMWithFallback tries to use the specified nameserver, but falls back to 8.8.4.4 if that fails
 func CheckDKIMWithFallback(domain string, nameserver string) (*DKIMInfo, error) {
-	info, err := CheckDKIM(domain, nameserver)
+	return CheckDKIMSelectorsWithFallback(domain, nameserver, SelectorOptions{})
+}
+
+// CheckDKIMSelectorsWithFallback checks the selectors in opts, falling back to 8.8.4.4 if the nameserver fails
+func CheckDKIMSelectorsWithFallback(domain string, nameserver string, opts SelectorOptions) (*DKIMInfo, error) {
+	info, err := CheckDKIMSelectors(domain, nameserver, opts)
 	if err == nil {
 		return info, nil
 	}
 
 	// Fallback to Google DNS
-	return CheckDKIM(domain, "8.8.4.4:53")
+	return CheckDKIMSelectors(domain, "8.8.4.4:53", opts)
+}
+
+// LoadSelectors reads a selector wordlist with one selector per line, skipping empty lines and # comments
+func LoadSelectors(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading selector file failed: %v", err)
+	}
+
+	var selectors []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		selectors = append(selectors, line)
+	}
+	return selectors, nil
 }
@@ -0,0 +1,161 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DKIMKey represents a parsed DKIM key record (RFC 6376 section 3.6.1)
+type DKIMKey struct {
+	Selector       string            // Selector the record was published under
+	Raw            string            // The complete raw TXT record
+	Version        string            // v tag, "DKIM1" when present
+	KeyType        string            // k tag, defaults to "rsa"
+	PublicKey      string            // p tag, base64 encoded public key (empty when revoked)
+	Flags          []string          // t tag flags, e.g. "y" (testing) and "s" (strict)
+	HashAlgorithms []string          // h tag, acceptable hash algorithms (empty means all)
+	ServiceTypes   []string          // s tag, defaults to "*"
+	Notes          string            // n tag, notes for humans
+	Tags           map[string]string // All tags and their values
+
+	KeyBits  int              // Size of the decoded public key in bits, 0 when not decoded
+	KeyError string           // Why the public key could not be decoded, if it could not
+	Decoded  crypto.PublicKey `json:"-"` // The decoded public key, used to verify signatures
+
+	SyntaxErrors   []string // Violations of the RFC 6376 key record syntax, in record order
+	SyntaxWarnings []string // Unknown tags, hash algorithms and flags, which verifiers ignore (RFC 6376 section 3.6.1)
+}
+
+// ParseKey parses a raw DKIM key record published under the given selector
+func ParseKey(selector string, raw string) *DKIMKey {
+	key := &DKIMKey{
+		Selector:     selector,
+		Raw:          raw,
+		KeyType:      "rsa",
+		ServiceTypes: []string{"*"},
+		Tags:         make(map[string]string),
+	}
+
+	position := 0
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		position++
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			key.SyntaxErrors = append(key.SyntaxErrors, fmt.Sprintf("stray data without a tag: %q", part))
+			continue
+		}
+		name := strings.TrimSpace(kv[0])
+		value := strings.TrimSpace(kv[1])
+
+		if _, duplicate := key.Tags[name]; duplicate {
+			key.SyntaxErrors = append(key.SyntaxErrors, fmt.Sprintf("duplicate tag %s", name))
+		}
+		if name == "v" && position != 1 {
+			key.SyntaxErrors = append(key.SyntaxErrors, "v must be the first tag")
+		}
+		key.Tags[name] = value
+
+		switch name {
+		case "v":
+			key.Version = value
+		case "k":
+			key.KeyType = strings.ToLower(value)
+		case "p":
+			// Base64 may be folded with whitespace
+			key.PublicKey = strings.Join(strings.Fields(value), "")
+		case "t":
+			key.Flags = splitList(value, ":")
+		case "h":
+			key.HashAlgorithms = splitList(value, ":")
+		case "s":
+			key.ServiceTypes = splitList(value, ":")
+		case "n":
+			key.Notes = value
+		}
+	}
+
+	key.decodePublicKey()
+	key.validate()
+	return key
+}
+
+// decodePublicKey decodes the p tag and determines the key size
+func (k *DKIMKey) decodePublicKey() {
+	if k.PublicKey == "" {
+		// An empty p tag means the key was revoked
+		return
+	}
+
+	der, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	if err != nil {
+		k.KeyError = fmt.Sprintf("invalid base64 in p tag: %v", err)
+		return
+	}
+
+	switch k.KeyType {
+	case "ed25519":
+		// RFC 8463 publishes the raw 32-byte public key
+		if len(der) != ed25519.PublicKeySize {
+			k.KeyError = fmt.Sprintf("ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(der))
+			return
+		}
+		k.KeyBits = ed25519.PublicKeySize * 8
+		k.Decoded = ed25519.PublicKey(der)
+		return
+	case "rsa":
+	default:
+		return
+	}
+
+	// Keys are SubjectPublicKeyInfo structures, some signers publish a bare RSAPublicKey
+	if parsed, err := x509.ParsePKIXPublicKey(der); err == nil {
+		rsaKey, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			k.KeyError = fmt.Sprintf("k=rsa but p contains a %T key", parsed)
+			return
+		}
+		k.KeyBits = rsaKey.N.BitLen()
+		k.Decoded = rsaKey
+		return
+	}
+	if rsaKey, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		k.KeyBits = rsaKey.N.BitLen()
+		k.Decoded = rsaKey
+		return
+	}
+	k.KeyError = "p tag does not contain a valid RSA public key"
+}
+
+// Revoked reports whether the record has an empty p tag, which revokes the key (RFC 6376 section 3.6.1)
+func (k *DKIMKey) Revoked() bool {
+	p, ok := k.Tags["p"]
+	return ok && strings.TrimSpace(p) == ""
+}
+
+// HasFlag reports whether the t tag contains the given flag
+func (k *DKIMKey) HasFlag(flag string) bool {
+	return slices.ContainsFunc(k.Flags, func(f string) bool { return strings.EqualFold(f, flag) })
+}
+
+// splitList splits a separated tag value into its trimmed, non-empty elements
+func splitList(value string, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
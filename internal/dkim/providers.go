@@ -0,0 +1,46 @@
+package dkim
+
+import (
+	"strings"
+)
+
+// Provider maps a CNAME target domain of a delegated selector to the signing provider behind it
+type Provider struct {
+	Domain string // Target domain (or parent domain) of the selector CNAME
+	Name   string // Human readable provider name
+}
+
+// KnownProviders is a list of well-known CNAME targets used for delegated DKIM selectors
+var KnownProviders = []Provider{
+	{"onmicrosoft.com", "Microsoft 365"},
+	{"sendgrid.net", "SendGrid"},
+	{"dkim.amazonses.com", "Amazon SES"},
+	{"dkim.mcsv.net", "Mailchimp"},
+	{"mandrillapp.com", "Mandrill"},
+	{"mailgun.org", "Mailgun"},
+	{"hubspotemail.net", "HubSpot"},
+	{"zendesk.com", "Zendesk"},
+	{"dkim.fmhosted.com", "Fastmail"},
+	{"domains.proton.ch", "Proton Mail"},
+	{"sparkpostmail.com", "SparkPost"},
+	{"mtasv.net", "Postmark"},
+	{"exacttarget.com", "Salesforce Marketing Cloud"},
+	{"klaviyo.com", "Klaviyo"},
+	{"sendinblue.com", "Brevo"},
+	{"brevo.com", "Brevo"},
+	{"mktoweb.com", "Marketo"},
+	{"freshdesk.com", "Freshdesk"},
+	{"intercom-mail.com", "Intercom"},
+	{"mimecast.com", "Mimecast"},
+}
+
+// LookupProvider returns the provider name for a selector CNAME target, if it is known
+func LookupProvider(target string) (string, bool) {
+	target = strings.ToLower(strings.TrimSuffix(target, "."))
+	for _, provider := range KnownProviders {
+		if target == provider.Domain || strings.HasSuffix(target, "."+provider.Domain) {
+			return provider.Name, true
+		}
+	}
+	return "", false
+}
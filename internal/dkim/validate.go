@@ -0,0 +1,73 @@
+package dkim
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// knownTags are the tags defined for DKIM key records (RFC 6376 section 3.6.1)
+var knownTags = []string{"v", "h", "k", "n", "p", "s", "t"}
+
+// knownKeyTypes are the registered key types (RFC 6376, RFC 8463)
+var knownKeyTypes = []string{"rsa", "ed25519"}
+
+// knownHashAlgorithms are the registered hash algorithms for the h tag
+var knownHashAlgorithms = []string{"sha1", "sha256"}
+
+// knownServiceTypes are the registered service types for the s tag
+var knownServiceTypes = []string{"*", "email"}
+
+// knownFlags are the registered flags for the t tag
+var knownFlags = []string{"y", "s"}
+
+// validate checks the parsed tags against the key record syntax and appends every problem to SyntaxErrors.
+// Unknown tags, hash algorithms and flags are ignored by verifiers and go to SyntaxWarnings instead.
+func (k *DKIMKey) validate() {
+	var unknown []string
+	for name := range k.Tags {
+		// Tag names are case-sensitive (RFC 6376 section 3.2)
+		if !slices.Contains(knownTags, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		k.SyntaxWarnings = append(k.SyntaxWarnings, fmt.Sprintf("unknown tag %s", name))
+	}
+
+	if v, ok := k.Tags["v"]; ok && v != "DKIM1" {
+		k.SyntaxErrors = append(k.SyntaxErrors, fmt.Sprintf("v must be DKIM1, got %q", v))
+	}
+	if _, ok := k.Tags["p"]; !ok {
+		k.SyntaxErrors = append(k.SyntaxErrors, "missing required p tag")
+	}
+	if !slices.ContainsFunc(knownKeyTypes, func(item string) bool { return strings.EqualFold(item, k.KeyType) }) {
+		k.SyntaxErrors = append(k.SyntaxErrors, fmt.Sprintf("unknown key type k=%s", k.KeyType))
+	}
+	if k.KeyError != "" {
+		k.SyntaxErrors = append(k.SyntaxErrors, k.KeyError)
+	}
+	known := 0
+	for _, hash := range k.HashAlgorithms {
+		if slices.ContainsFunc(knownHashAlgorithms, func(item string) bool { return strings.EqualFold(item, hash) }) {
+			known++
+		} else {
+			k.SyntaxWarnings = append(k.SyntaxWarnings, fmt.Sprintf("unknown hash algorithm h=%s", hash))
+		}
+	}
+	if len(k.HashAlgorithms) > 0 && known == 0 {
+		k.SyntaxErrors = append(k.SyntaxErrors, "h= lists no known hash algorithm, so no signature can be verified")
+	}
+	for _, service := range k.ServiceTypes {
+		if !slices.ContainsFunc(knownServiceTypes, func(item string) bool { return strings.EqualFold(item, service) }) {
+			k.SyntaxErrors = append(k.SyntaxErrors, fmt.Sprintf("invalid service type s=%s, expected * or email", service))
+		}
+	}
+	for _, flag := range k.Flags {
+		if !slices.ContainsFunc(knownFlags, func(item string) bool { return strings.EqualFold(item, flag) }) {
+			k.SyntaxWarnings = append(k.SyntaxWarnings, fmt.Sprintf("unknown flag t=%s", flag))
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package dkim
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// maxWalkSteps limits the number of NSEC records followed when walking the _domainkey zone
+const maxWalkSteps = 256
+
+// WalkSelectors enumerates the selectors of a DNSSEC-signed domain by following the NSEC chain
+// below _domainkey. Zones signed with NSEC3 hash their names and can't be walked, and neither can zones
+// signed online with minimally covering NSEC records, which only ever name the queried name's neighbours.
+func WalkSelectors(domain string, nameserver string) ([]string, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	zone := strings.ToLower(dns.Fqdn("_domainkey." + domain))
+	c := new(dns.Client)
+
+	var selectors []string
+	seen := make(map[string]bool)
+	current := zone
+	for step := 0; step < maxWalkSteps; step++ {
+		// The \000 label sorts directly after the current name, so the NSEC covering it names the next one
+		qname := `\000.` + current
+		m := new(dns.Msg)
+		m.SetQuestion(qname, dns.TypeA)
+		m.RecursionDesired = true
+		m.SetEdns0(ednsBufferSize, true)
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			return selectors, fmt.Errorf("DNS query failed: %v", err)
+		}
+
+		nsec, err := coveringNSEC(r, qname)
+		if err != nil {
+			return selectors, err
+		}
+
+		next := strings.ToLower(nsec.NextDomain)
+		if !strings.HasSuffix(next, "."+zone) || seen[next] {
+			// The chain left the _domainkey zone or wrapped around
+			return selectors, nil
+		}
+		seen[next] = true
+		selectors = append(selectors, strings.TrimSuffix(next, "."+zone))
+		current = next
+	}
+	return selectors, fmt.Errorf("stopped walking after %d names", maxWalkSteps)
+}
+
+// coveringNSEC returns the NSEC record proving that qname does not exist
+func coveringNSEC(r *dns.Msg, qname string) (*dns.NSEC, error) {
+	hasNSEC := false
+	for _, rr := range r.Ns {
+		switch record := rr.(type) {
+		case *dns.NSEC3:
+			return nil, fmt.Errorf("the zone is signed with NSEC3, which hashes the names and can't be walked")
+		case *dns.NSEC:
+			hasNSEC = true
+			owner, next := record.Header().Name, record.NextDomain
+			if minimallyCovering(owner, next, qname) {
+				return nil, fmt.Errorf("the zone is signed online with minimally covering NSEC records, which don't name the real neighbours and can't be walked")
+			}
+			if canonicalLess(owner, qname) && (canonicalLess(qname, next) || !canonicalLess(owner, next)) {
+				return record, nil
+			}
+		}
+	}
+	if hasNSEC {
+		return nil, fmt.Errorf("no NSEC record covers %s", qname)
+	}
+	if r.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("unexpected %s response for %s", dns.RcodeToString[r.Rcode], qname)
+	}
+	return nil, fmt.Errorf("no NSEC records returned, the zone is not DNSSEC-signed or the resolver strips them")
+}
+
+// minimallyCovering reports whether the NSEC record was synthesized by an online signer for qname
+// alone (RFC 4470): its owner is the queried name itself, or its next name is the \000 child of a name
+func minimallyCovering(owner string, next string, qname string) bool {
+	if strings.EqualFold(owner, qname) {
+		return true
+	}
+	labels := dns.SplitDomainName(next)
+	return len(labels) > 0 && labels[0] == `\000`
+}
+
+// canonicalLess reports whether name a sorts before name b in canonical DNS order (RFC 4034 section 6.1)
+func canonicalLess(a string, b string) bool {
+	la, lb := dns.SplitDomainName(a), dns.SplitDomainName(b)
+	for i := 1; i <= len(la) && i <= len(lb); i++ {
+		if cmp := bytes.Compare(labelBytes(la[len(la)-i]), labelBytes(lb[len(lb)-i])); cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return len(la) < len(lb)
+}
+
+// labelBytes returns the lowercased wire form of a presentation format label, resolving \DDD and \X escapes
+func labelBytes(label string) []byte {
+	var out []byte
+	for i := 0; i < len(label); i++ {
+		if label[i] == '\\' && i+3 < len(label) && isDigits(label[i+1:i+4]) {
+			value, _ := strconv.Atoi(label[i+1 : i+4])
+			out = append(out, byte(value))
+			i += 3
+			continue
+		}
+		if label[i] == '\\' && i+1 < len(label) {
+			i++
+		}
+		c := label[i]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// isDigits reports whether s consists of decimal digits only
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
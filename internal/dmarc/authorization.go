@@ -0,0 +1,81 @@
+package dmarc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/publicsuffix"
+)
+
+// OrganizationalDomain returns the organizational domain of a domain name: the public suffix plus one label
+// (RFC 7489 section 3.2), using the public suffix list. A public suffix itself is returned unchanged.
+func OrganizationalDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	orgDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+	return orgDomain
+}
+
+// ReportAuthorization contains the result of an external report destination authorization check (RFC 7489 section 7.1)
+type ReportAuthorization struct {
+	URI          string // The rua or ruf URI pointing to the external destination
+	ReportDomain string // Domain of the report destination mailbox
+	Query        string // The name that was queried for the authorization record
+	Authorized   bool   // Whether the destination published an authorization record
+	Error        string // Any error encountered during the check
+}
+
+// CheckReportAuthorizations verifies that every rua/ruf destination in another organizational domain
+// has authorized receiving reports for the domain
+func CheckReportAuthorizations(domain string, policy DMARCPolicy, nameserver string) []ReportAuthorization {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	results := []ReportAuthorization{}
+	orgDomain := OrganizationalDomain(domain)
+	uris := append(ParseReportURIs(policy.AggregateReportURI), ParseReportURIs(policy.ForensicReportURI)...)
+
+	for _, uri := range uris {
+		if !uri.Valid || OrganizationalDomain(uri.Domain) == orgDomain {
+			continue
+		}
+
+		result := ReportAuthorization{
+			URI:          uri.Raw,
+			ReportDomain: uri.Domain,
+			Query:        fmt.Sprintf("%s._report._dmarc.%s", domain, uri.Domain),
+		}
+
+		c := new(dns.Client)
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(result.Query), dns.TypeTXT)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			result.Error = fmt.Sprintf("DNS query failed: %v", err)
+			results = append(results, result)
+			continue
+		}
+		if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+			// A failing lookup doesn't mean the destination hasn't authorized the reports
+			result.Error = fmt.Sprintf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+			results = append(results, result)
+			continue
+		}
+
+		for _, a := range r.Answer {
+			if txt, ok := a.(*dns.TXT); ok && strings.HasPrefix(strings.ToLower(strings.Join(txt.Txt, "")), "v=dmarc1") {
+				result.Authorized = true
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
@@ -0,0 +1,133 @@
+package dmarc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ReportDestination contains the result of checking whether a rua/ruf mailbox domain can receive mail
+type ReportDestination struct {
+	URI        string   // The rua or ruf URI
+	Domain     string   // Domain of the report mailbox
+	MXHosts    []string // MX hosts of the mailbox domain
+	NullMX     bool     // Whether the domain publishes a null MX (RFC 7505)
+	HasAddress bool     // Whether the domain has an A/AAAA record used as implicit MX
+	CanReceive bool     // Whether the domain can accept mail
+	Error      string   // Any error encountered during the check
+}
+
+// CheckReportDestinations resolves the MX (or fallback A/AAAA) records of every rua/ruf mailbox domain
+func CheckReportDestinations(policy DMARCPolicy, nameserver string) []ReportDestination {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	results := []ReportDestination{}
+	checked := make(map[string]ReportDestination)
+	uris := append(ParseReportURIs(policy.AggregateReportURI), ParseReportURIs(policy.ForensicReportURI)...)
+
+	for _, uri := range uris {
+		if !uri.Valid {
+			continue
+		}
+
+		// Check every mailbox domain only once
+		if previous, ok := checked[uri.Domain]; ok {
+			previous.URI = uri.Raw
+			results = append(results, previous)
+			continue
+		}
+
+		result := checkReportDestination(uri, nameserver)
+		checked[uri.Domain] = result
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// checkReportDestination checks a single report mailbox domain
+func checkReportDestination(uri ReportURI, nameserver string) ReportDestination {
+	result := ReportDestination{
+		URI:    uri.Raw,
+		Domain: uri.Domain,
+	}
+
+	r, err := query(uri.Domain, dns.TypeMX, nameserver)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if r.Rcode == dns.RcodeNameError {
+		result.Error = "domain does not exist"
+		return result
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		// A failing lookup says nothing about whether the domain can receive mail
+		result.Error = fmt.Sprintf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+		return result
+	}
+
+	for _, a := range r.Answer {
+		if mx, ok := a.(*dns.MX); ok {
+			if mx.Mx == "." {
+				result.NullMX = true
+				continue
+			}
+			result.MXHosts = append(result.MXHosts, strings.TrimSuffix(mx.Mx, "."))
+		}
+	}
+	if result.NullMX && len(result.MXHosts) == 0 {
+		return result
+	}
+
+	// Without MX records the domain's own address is used as implicit MX (RFC 5321 section 5.1)
+	hosts := result.MXHosts
+	if len(hosts) == 0 {
+		hosts = []string{uri.Domain}
+	}
+	for _, host := range hosts {
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			r, err := query(host, qtype, nameserver)
+			if err != nil {
+				result.Error = err.Error()
+				continue
+			}
+			if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+				result.Error = fmt.Sprintf("DNS query for %s returned non-success code: %v", host, dns.RcodeToString[r.Rcode])
+				continue
+			}
+			for _, a := range r.Answer {
+				switch a.(type) {
+				case *dns.A, *dns.AAAA:
+					result.CanReceive = true
+					if len(result.MXHosts) == 0 {
+						result.HasAddress = true
+					}
+				}
+			}
+		}
+		if result.CanReceive {
+			result.Error = ""
+			break
+		}
+	}
+
+	return result
+}
+
+// query sends a single recursive DNS query
+func query(name string, qtype uint16, nameserver string) (*dns.Msg, error) {
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query failed: %v", err)
+	}
+	return r, nil
+}
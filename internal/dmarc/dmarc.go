@@ -13,8 +13,23 @@ type DMARCRecord struct {
 	Raw      string            // The complete raw TXT record
 	Version  string            // Should be "DMARC1"
 	Tags     map[string]string // All DMARC tags and their values
-	Valid    bool              // Whether the record is valid
+	Valid    bool              // Whether the record is valid, derived from ValidationErrors
 	Location string            // Where the record was found
+	TTL      uint32            // TTL of the TXT record in seconds as configured at the authoritative nameservers
+	TTLKnown bool              // Whether the TTL could be read from an authoritative nameserver
+
+	ValidationErrors []string // Every problem that makes the record invalid, in record order
+	StrictViolations []string // Deviations from the RFC 7489 grammar that lenient receivers accept
+	Strict           bool     // Whether the strict violations are reported, each as a failure of the strict syntax rule
+
+	InheritedFrom string // Organizational domain the record was inherited from when the queried domain has none
+
+	CNAMEChain []string // CNAME targets followed from the _dmarc name to the record, in order
+	HostedBy   string   // Vendor hosting the record when _dmarc is delegated through a CNAME
+
+	InvalidTags map[string]string // Tags whose values violate RFC 7489, with the reason
+	TagWarnings map[string]string // Tags with invalid values that receivers ignore without invalidating the record
+	UnknownTags []string          // Tags not defined by RFC 7489
 }
 
 // DMARCPolicy represents the parsed policy values
@@ -31,7 +46,8 @@ type DMARCPolicy struct {
 	ASPF                   string   // aspf tag value (r=relaxed, s=strict)
 }
 
-// LookupDMARC looks up DMARC record for the specified domain using the given nameserver
+// LookupDMARC looks up DMARC record for the specified domain using the given nameserver.
+// A _dmarc name delegated through a CNAME is followed and the chain is recorded on the record.
 func LookupDMARC(domain string, nameserver string) (*DMARCRecord, error) {
 	if !strings.HasSuffix(nameserver, ":53") {
 		nameserver = nameserver + ":53"
@@ -40,37 +56,94 @@ func LookupDMARC(domain string, nameserver string) (*DMARCRecord, error) {
 	dmarcDomain := "_dmarc." + domain
 
 	c := new(dns.Client)
-	m := new(dns.Msg)
-	m.SetQuestion(dns.Fqdn(dmarcDomain), dns.TypeTXT)
-	m.RecursionDesired = true
+	var chain []string
+	name := dmarcDomain
+	for hop := 0; hop < maxCNAMEHops; hop++ {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			return nil, fmt.Errorf("DNS query failed: %v", err)
+		}
 
-	r, _, err := c.Exchange(m, nameserver)
-	if err != nil {
-		return nil, fmt.Errorf("DNS query failed: %v", err)
-	}
+		if r.Rcode != dns.RcodeSuccess {
+			return nil, fmt.Errorf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+		}
 
-	if r.Rcode != dns.RcodeSuccess {
-		return nil, fmt.Errorf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
-	}
+		// Follow the CNAME chain included in the answer
+		for _, a := range r.Answer {
+			if cname, ok := a.(*dns.CNAME); ok {
+				chain = append(chain, strings.TrimSuffix(cname.Target, "."))
+			}
+		}
 
-	// Look for DMARC record in TXT records
-	for _, a := range r.Answer {
-		if txt, ok := a.(*dns.TXT); ok {
-			// Join TXT chunks into single string
-			txtValue := strings.Join(txt.Txt, "")
-
-			// Check if this is a DMARC record
-			if strings.HasPrefix(strings.ToLower(txtValue), "v=dmarc1") {
-				return parseDMARCRecord(txtValue, dmarcDomain), nil
+		// Look for DMARC record in TXT records
+		for _, a := range r.Answer {
+			if txt, ok := a.(*dns.TXT); ok {
+				// Join TXT chunks into single string
+				txtValue := strings.Join(txt.Txt, "")
+
+				// Check if this is a DMARC record
+				if strings.HasPrefix(strings.ToLower(txtValue), "v=dmarc1") {
+					record := parseDMARCRecord(txtValue, dmarcDomain)
+					record.setCNAMEChain(chain)
+					return record, nil
+				}
 			}
 		}
+
+		// Query the end of the chain when the resolver did not chase it
+		if len(chain) == 0 || strings.EqualFold(chain[len(chain)-1], name) {
+			break
+		}
+		name = chain[len(chain)-1]
 	}
 
 	return nil, fmt.Errorf("no DMARC record found for domain: %s", dmarcDomain)
 }
 
-// LookupDMARCWithFallback tries to use the specified nameserver, but falls back to the system resolver if that fails
+// maxCNAMEHops limits the number of CNAME targets followed for a single lookup
+const maxCNAMEHops = 8
+
+// setCNAMEChain records the followed CNAME chain and the vendor hosting the record
+func (r *DMARCRecord) setCNAMEChain(chain []string) {
+	if len(chain) == 0 {
+		return
+	}
+	r.CNAMEChain = chain
+	for _, target := range chain {
+		if vendor, ok := LookupVendor(target); ok {
+			r.HostedBy = vendor
+			return
+		}
+	}
+}
+
+// LookupDMARCWithFallback tries to use the specified nameserver, but falls back to the system resolver if that fails.
+// When the domain has no DMARC record, the record of the organizational domain is returned with InheritedFrom set.
 func LookupDMARCWithFallback(domain string, nameserver string) (*DMARCRecord, error) {
+	record, err := lookupDMARCAt(domain, nameserver)
+	if err == nil {
+		return record, nil
+	}
+
+	// Try the organizational domain if the domain itself has no record
+	orgDomain := OrganizationalDomain(domain)
+	if orgDomain != strings.ToLower(domain) {
+		orgRecord, orgErr := lookupDMARCAt(orgDomain, nameserver)
+		if orgErr == nil {
+			orgRecord.InheritedFrom = orgDomain
+			return orgRecord, nil
+		}
+	}
+
+	return nil, err
+}
+
+// lookupDMARCAt looks up the DMARC record of exactly the given domain, falling back to the system resolver
+func lookupDMARCAt(domain string, nameserver string) (*DMARCRecord, error) {
 	record, err := LookupDMARC(domain, nameserver)
 	if err == nil {
 		return record, nil
@@ -80,56 +153,111 @@ func LookupDMARCWithFallback(domain string, nameserver string) (*DMARCRecord, er
 	dmarcDomain := "_dmarc." + domain
 	txtRecords, err := net.LookupTXT(dmarcDomain)
 	if err != nil {
-		// Try the organizational domain if subdomain lookup fails
-		parts := strings.Split(domain, ".")
-		if len(parts) > 2 {
-			orgDomain := strings.Join(parts[len(parts)-2:], ".")
-			return LookupDMARCWithFallback(orgDomain, nameserver)
-		}
 		return nil, fmt.Errorf("DMARC TXT lookup failed: %v", err)
 	}
 
 	// Look for DMARC record in TXT records
 	for _, txt := range txtRecords {
 		if strings.HasPrefix(strings.ToLower(txt), "v=dmarc1") {
-			return parseDMARCRecord(txt, dmarcDomain), nil
+			record := parseDMARCRecord(txt, dmarcDomain)
+			if cname, err := net.LookupCNAME(dmarcDomain); err == nil && !strings.EqualFold(strings.TrimSuffix(cname, "."), dmarcDomain) {
+				record.setCNAMEChain([]string{strings.TrimSuffix(cname, ".")})
+			}
+			return record, nil
 		}
 	}
 
 	return nil, fmt.Errorf("no DMARC record found for domain: %s", dmarcDomain)
 }
 
+// ParseOptions controls how DMARC records are parsed
+type ParseOptions struct {
+	Strict bool // Reject records that deviate from the RFC 7489 grammar instead of accepting them leniently
+}
+
+// ParseRecord parses a raw DMARC record without any DNS lookups, e.g. for linting a record before publication
+func ParseRecord(rawRecord string) *DMARCRecord {
+	return ParseRecordWithOptions(rawRecord, ParseOptions{})
+}
+
+// ParseRecordWithOptions parses a raw DMARC record without any DNS lookups using the given options
+func ParseRecordWithOptions(rawRecord string, opts ParseOptions) *DMARCRecord {
+	record := parseDMARCRecord(strings.TrimSpace(rawRecord), "")
+	if opts.Strict {
+		record.EnforceStrict()
+	}
+	return record
+}
+
+// EnforceStrict marks the strict violations of the record for reporting. They stay out of the validation
+// errors, so the syntax rule and the record validity only reflect what receivers reject.
+func (r *DMARCRecord) EnforceStrict() {
+	r.Strict = true
+}
+
 // parseDMARCRecord parses a DMARC record string into a structured format
 func parseDMARCRecord(rawRecord, location string) *DMARCRecord {
 	record := &DMARCRecord{
-		Raw:      rawRecord,
-		Tags:     make(map[string]string),
-		Location: location,
-		Valid:    true,
+		Raw:              rawRecord,
+		Tags:             make(map[string]string),
+		Location:         location,
+		ValidationErrors: []string{},
+		InvalidTags:      make(map[string]string),
+		TagWarnings:      make(map[string]string),
+		UnknownTags:      []string{},
 	}
 
 	// Split the record into tag-value pairs
 	parts := strings.Split(rawRecord, ";")
+	position := 0
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
 			continue
 		}
+		position++
 
 		// Split tag=value
 		kv := strings.SplitN(part, "=", 2)
 		if len(kv) != 2 {
-			record.Valid = false
+			stray := fmt.Sprintf("stray data without a tag: %q", part)
+			record.ValidationErrors = append(record.ValidationErrors, stray)
+			record.StrictViolations = append(record.StrictViolations, stray)
 			continue
 		}
 
 		key := strings.TrimSpace(kv[0])
 		value := strings.TrimSpace(kv[1])
 
+		if _, duplicate := record.Tags[key]; duplicate {
+			record.ValidationErrors = append(record.ValidationErrors, fmt.Sprintf("duplicate tag %s", key))
+			record.StrictViolations = append(record.StrictViolations, fmt.Sprintf("duplicate tag %s", key))
+		}
+
+		// RFC 7489 section 6.3 requires v to be the first tag
+		if position == 1 && key != "v" {
+			record.StrictViolations = append(record.StrictViolations, fmt.Sprintf("v must be the first tag, found %s", key))
+		}
+		if !isValidTagName(key) {
+			record.StrictViolations = append(record.StrictViolations, fmt.Sprintf("invalid tag name %q", key))
+		}
+		if value == "" {
+			record.StrictViolations = append(record.StrictViolations, fmt.Sprintf("tag %s has an empty value", key))
+		}
+
 		if key == "v" {
 			record.Version = value
-			if value != "DMARC1" {
-				record.Valid = false
+		}
+
+		// Validate the tag against RFC 7489
+		if !isKnownTag(key) {
+			record.UnknownTags = append(record.UnknownTags, key)
+		} else if err := validateTag(key, value); err != nil {
+			if isLenientTag(key) {
+				record.TagWarnings[key] = err.Error()
+			} else {
+				record.InvalidTags[key] = err.Error()
+				record.ValidationErrors = append(record.ValidationErrors, err.Error())
 			}
 		}
 
@@ -137,10 +265,14 @@ func parseDMARCRecord(rawRecord, location string) *DMARCRecord {
 	}
 
 	// Ensure required tags are present
+	if _, ok := record.Tags["v"]; !ok {
+		record.ValidationErrors = append(record.ValidationErrors, "missing required v tag")
+	}
 	if _, ok := record.Tags["p"]; !ok {
-		record.Valid = false
+		record.ValidationErrors = append(record.ValidationErrors, "missing required p tag")
 	}
 
+	record.Valid = len(record.ValidationErrors) == 0
 	return record
 }
 
@@ -0,0 +1,78 @@
+package dmarc
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ReportURI represents a parsed rua or ruf destination
+type ReportURI struct {
+	Raw     string // The URI as it appears in the record
+	Scheme  string // URI scheme, should be "mailto"
+	Address string // Mailbox the reports are sent to
+	Domain  string // Domain part of the mailbox
+	MaxSize string // Optional maximum report size (e.g. "10m")
+	Valid   bool   // Whether the URI is usable by receivers
+	Error   string // Why the URI is not usable
+}
+
+var sizeSuffixPattern = regexp.MustCompile(`^[0-9]+[kmgt]?$`)
+
+// ParseReportURI parses and validates a single rua or ruf URI
+func ParseReportURI(raw string) ReportURI {
+	uri := ReportURI{Raw: raw}
+	value := strings.TrimSpace(raw)
+
+	// Split off the optional size limit suffix
+	if i := strings.LastIndex(value, "!"); i >= 0 {
+		uri.MaxSize = strings.ToLower(value[i+1:])
+		value = value[:i]
+		if !sizeSuffixPattern.MatchString(uri.MaxSize) {
+			uri.Error = fmt.Sprintf("invalid size limit %q, expected a number with an optional k, m, g or t unit", uri.MaxSize)
+			return uri
+		}
+	}
+
+	colon := strings.Index(value, ":")
+	if colon < 0 {
+		uri.Error = "missing URI scheme, the destination must start with mailto:"
+		return uri
+	}
+
+	uri.Scheme = strings.ToLower(value[:colon])
+	if uri.Scheme != "mailto" {
+		uri.Error = fmt.Sprintf("unsupported scheme %q, receivers only send reports to mailto: URIs", uri.Scheme)
+		return uri
+	}
+
+	address := value[colon+1:]
+	if i := strings.Index(address, "?"); i >= 0 {
+		address = address[:i]
+	}
+	if unescaped, err := url.PathUnescape(address); err == nil {
+		address = unescaped
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		uri.Error = fmt.Sprintf("invalid mailbox %q", address)
+		return uri
+	}
+
+	uri.Address = parsed.Address
+	uri.Domain = strings.ToLower(parsed.Address[strings.LastIndex(parsed.Address, "@")+1:])
+	uri.Valid = true
+	return uri
+}
+
+// ParseReportURIs parses and validates a list of rua or ruf URIs
+func ParseReportURIs(uris []string) []ReportURI {
+	var result []ReportURI
+	for _, uri := range uris {
+		result = append(result, ParseReportURI(uri))
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package dmarc
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// KnownTags lists the tags defined by RFC 7489
+var KnownTags = []string{"v", "p", "sp", "pct", "rua", "ruf", "adkim", "aspf", "fo", "rf", "ri"}
+
+// isKnownTag reports whether the tag is defined by RFC 7489
+func isKnownTag(key string) bool {
+	return slices.Contains(KnownTags, key)
+}
+
+// isLenientTag reports whether invalid values of the tag are ignored by receivers instead of
+// invalidating the record. RFC 7489 requires unknown fo options to be ignored, and receivers fall back to
+// the default interval for an invalid ri.
+func isLenientTag(key string) bool {
+	return key == "fo" || key == "ri"
+}
+
+// validateTag checks a single tag value against RFC 7489
+func validateTag(key, value string) error {
+	switch key {
+	case "v":
+		if value != "DMARC1" {
+			return fmt.Errorf("version must be DMARC1, got %q", value)
+		}
+	case "p", "sp":
+		switch strings.ToLower(value) {
+		case "none", "quarantine", "reject":
+		default:
+			return fmt.Errorf("%s must be one of none, quarantine or reject, got %q", key, value)
+		}
+	case "pct":
+		pct, err := strconv.Atoi(value)
+		if err != nil || pct < 0 || pct > 100 {
+			return fmt.Errorf("pct must be an integer between 0 and 100, got %q", value)
+		}
+	case "adkim", "aspf":
+		switch strings.ToLower(value) {
+		case "r", "s":
+		default:
+			return fmt.Errorf("%s must be r (relaxed) or s (strict), got %q", key, value)
+		}
+	case "fo":
+		for _, option := range strings.Split(value, ":") {
+			switch strings.ToLower(strings.TrimSpace(option)) {
+			case "0", "1", "d", "s":
+			default:
+				return fmt.Errorf("fo must be a colon-separated list of 0, 1, d and s, got %q", value)
+			}
+		}
+	case "rf":
+		for _, format := range strings.Split(value, ":") {
+			if strings.ToLower(strings.TrimSpace(format)) != "afrf" {
+				return fmt.Errorf("rf only supports afrf, got %q", value)
+			}
+		}
+	case "ri":
+		if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+			return fmt.Errorf("ri must be a non-negative number of seconds, got %q", value)
+		}
+	case "rua", "ruf":
+		if len(parseDMARCUris(value)) == 0 {
+			return fmt.Errorf("%s must contain at least one URI", key)
+		}
+	}
+	return nil
+}
+
+// isValidTagName reports whether a tag name consists of letters, digits and underscores, starting with a letter (RFC 6376 tag-name)
+func isValidTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && (i == 0 || (!isDigit && r != '_')) {
+			return false
+		}
+	}
+	return true
+}
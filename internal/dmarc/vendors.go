@@ -0,0 +1,66 @@
+package dmarc
+
+import (
+	"strings"
+)
+
+// Vendor maps a domain used by a DMARC reporting and analytics platform to its name
+type Vendor struct {
+	Domain string // Domain (or parent domain) used for hosted records and report mailboxes
+	Name   string // Human readable vendor name
+}
+
+// KnownVendors is a list of well-known DMARC reporting and hosting vendors
+var KnownVendors = []Vendor{
+	{"dmarcian.com", "dmarcian"},
+	{"dmarcian.eu", "dmarcian"},
+	{"dmarcian-eu.com", "dmarcian"},
+	{"valimail.com", "Valimail"},
+	{"vali.email", "Valimail"},
+	{"agari.com", "Agari"},
+	{"ondmarc.com", "Red Sift OnDMARC"},
+	{"redsift.cloud", "Red Sift OnDMARC"},
+	{"easydmarc.com", "EasyDMARC"},
+	{"easydmarc.us", "EasyDMARC"},
+	{"easydmarc.eu", "EasyDMARC"},
+	{"dmarcanalyzer.com", "Mimecast DMARC Analyzer"},
+	{"powerdmarc.com", "PowerDMARC"},
+	{"uriports.com", "URIports"},
+	{"emaildefense.proofpoint.com", "Proofpoint Email Fraud Defense"},
+	{"dmarc-reports.cloudflare.net", "Cloudflare DMARC Management"},
+	{"dmarc.postmarkapp.com", "Postmark DMARC Digests"},
+	{"fraudmarc.com", "Fraudmarc"},
+	{"sendmarc.com", "Sendmarc"},
+	{"dmarcly.com", "DMARCLY"},
+	{"dmarcadvisor.com", "DMARC Advisor"},
+	{"dmarc-report.com", "MxToolbox"},
+	{"mailhardener.com", "Mailhardener"},
+}
+
+// LookupVendor returns the vendor name for a hosted record target or report mailbox domain, if it is known
+func LookupVendor(domain string) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for _, vendor := range KnownVendors {
+		if domain == vendor.Domain || strings.HasSuffix(domain, "."+vendor.Domain) {
+			return vendor.Name, true
+		}
+	}
+	return "", false
+}
+
+// ReportVendors returns the deduplicated list of known vendors receiving the aggregate reports
+func (p DMARCPolicy) ReportVendors() []string {
+	seen := make(map[string]bool)
+	vendors := []string{}
+	for _, uri := range ParseReportURIs(p.AggregateReportURI) {
+		if !uri.Valid {
+			continue
+		}
+		name, ok := LookupVendor(uri.Domain)
+		if ok && !seen[name] {
+			seen[name] = true
+			vendors = append(vendors, name)
+		}
+	}
+	return vendors
+}
@@ -1,26 +1,107 @@
 package dns
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
+	"check-maildomain/internal/bimi"
+	"check-maildomain/internal/caa"
+	"check-maildomain/internal/dane"
 	"check-maildomain/internal/dkim"
 	"check-maildomain/internal/dmarc"
 	"check-maildomain/internal/dnssec"
+	"check-maildomain/internal/host"
+	"check-maildomain/internal/mtasts"
 	"check-maildomain/internal/mx"
 	"check-maildomain/internal/spf"
+	"check-maildomain/internal/subdomain"
+	"check-maildomain/internal/txt"
+	"check-maildomain/internal/web"
+	"check-maildomain/internal/zone"
 )
 
 // DomainInfo represents collected DNS information about a domain
 type DomainInfo struct {
-	Domain      string
-	QueryTime   time.Time
-	MXRecords   []mx.MXRecord
-	SPFRecord   *spf.SPFRecord
-	DMARCRecord *dmarc.DMARCRecord
-	DMARCPolicy dmarc.DMARCPolicy
-	DNSSECInfo  *dnssec.DNSSECInfo
-	DKIMInfo    *dkim.DKIMInfo
-	Errors      map[string]error
+	Domain                    string
+	UnicodeDomain             string // Unicode form of an internationalized domain, empty for ASCII domains
+	QueryTime                 time.Time
+	MXRecords                 []mx.MXRecord
+	ApexAddresses             []string                    // A and AAAA addresses of the domain itself, the implicit MX without MX records
+	WWWAddresses              []string                    // A and AAAA addresses of the www host
+	InboundProviders          []string                    // Email providers handling inbound mail, identified from the MX hosts
+	MXReverseDNS              map[string][]host.PTRResult // Reverse DNS of the MX addresses, keyed by MX host
+	Wildcard                  *subdomain.Wildcard         // Records a wildcard returns for non-existent names under the domain, nil without wildcard
+	SOA                       *zone.SOA                   // SOA record of the zone the domain belongs to
+	Nameservers               []string                    // NS records of the zone
+	Delegation                *zone.Delegation            // NS records of the zone as seen by the parent and each authoritative server
+	RecordComparison          []zone.RecordComparison     // Mail records per authoritative nameserver, only with Options.CompareNameservers
+	ZoneTransfers             []zone.TransferResult       // Zone transfer attempts per nameserver, only with Options.TryZoneTransfer
+	MXTLSA                    []dane.HostTLSA             // TLSA records for SMTP of each MX host
+	MTASTS                    *mtasts.MTASTSInfo          // MTA-STS TXT record and policy, nil when the lookup failed
+	CAA                       *caa.Result                 // CAA records relevant for the domain
+	MXCAA                     []caa.Result                // CAA records relevant for each MX host
+	MXTransportSecurity       []string                    // Mechanisms (MTA-STS, DANE) making senders authenticate the MX certificates
+	TXTRecords                *txt.Inventory              // All TXT records at the apex, categorized
+	SPFRecord                 *spf.SPFRecord
+	SPFExpansion              *spf.Expansion // Networks authorized by the recursively evaluated SPF record
+	Providers                 []string       // Email providers authorized through SPF includes
+	DMARCRecord               *dmarc.DMARCRecord
+	DMARCPolicy               dmarc.DMARCPolicy
+	DMARCVendors              []string                    // DMARC analytics vendors receiving the aggregate reports
+	DMARCReportAuthorizations []dmarc.ReportAuthorization // Authorization of external rua/ruf destinations
+	DMARCReportDestinations   []dmarc.ReportDestination   // Whether the rua/ruf mailbox domains can receive mail
+	BIMI                      *bimi.BIMIInfo              // BIMI assertion record, nil when the lookup failed
+	DNSSECInfo                *dnssec.DNSSECInfo
+	MXZones                   []dnssec.ZoneStatus // DNSSEC status of the zones of MX targets outside the domain
+	DKIMInfo                  *dkim.DKIMInfo
+	Subdomains                []subdomain.SubdomainInfo // Results of the optional subdomain scan
+	WebInfo                   *web.WebInfo              // Results of the optional website probe
+	HostInfo                  *host.HostInfo            // Results of the reduced check set when the input is a hostname or IP
+	Errors                    map[string]error
+}
+
+// Options controls optional parts of the DNS collection
+type Options struct {
+	Subdomains         []string             // Subdomain labels to scan for SPF, MX and DMARC records (scan is skipped when empty)
+	ProbeWeb           bool                 // Probe the apex and www website to detect parked or dead domains
+	SMTPProbe          bool                 // Actively probe port 25 for SMTP and STARTTLS support, and verify the TLSA records of the MX hosts
+	DMARC              dmarc.ParseOptions   // How the DMARC record is parsed
+	DKIMSelectors      dkim.SelectorOptions // Supplied and discovery DKIM selectors, the common selectors are tried when empty
+	DNSSEC             dnssec.Options       // How the DNSSEC queries are sent
+	CompareNameservers bool                 // Query the mail records at every authoritative nameserver and compare them
+	TryZoneTransfer    bool                 // Attempt a zone transfer (AXFR) against every authoritative nameserver
+}
+
+// verifyTLSA probes port 25 of every address of the MX host and matches the presented certificates
+// against the TLSA records
+func verifyTLSA(record mx.MXRecord, tlsaRecords []dane.TLSARecord) []dane.Verification {
+	var verifications []dane.Verification
+	for _, address := range record.Records {
+		if address.Type != "A" && address.Type != "AAAA" {
+			continue
+		}
+
+		verification := dane.Verification{IP: address.Value}
+		probe := host.ProbeSMTP(address.Value, record.Host)
+		switch {
+		case probe.Error != "":
+			verification.Error = probe.Error
+		case !probe.STARTTLS:
+			verification.Connected = true
+			verification.Error = "STARTTLS is not offered"
+		default:
+			verification.Connected = true
+			matched, err := dane.Verify(tlsaRecords, probe.PeerCertificates, record.Host)
+			if err != nil {
+				verification.Error = err.Error()
+			} else {
+				verification.Matched = matched.String()
+			}
+		}
+		verifications = append(verifications, verification)
+	}
+	return verifications
 }
 
 // NewDomainInfo creates a new DomainInfo structure
@@ -34,6 +115,11 @@ func NewDomainInfo(domain string) *DomainInfo {
 
 // CollectDNSInfo gathers all DNS information for the domain
 func CollectDNSInfo(domain string, nameserver string) (*DomainInfo, error) {
+	return CollectDNSInfoWithOptions(domain, nameserver, Options{})
+}
+
+// CollectDNSInfoWithOptions gathers all DNS information for the domain, including the optional checks enabled in opts
+func CollectDNSInfoWithOptions(domain string, nameserver string, opts Options) (*DomainInfo, error) {
 	info := NewDomainInfo(domain)
 
 	// Collect MX records
@@ -42,6 +128,99 @@ func CollectDNSInfo(domain string, nameserver string) (*DomainInfo, error) {
 		info.Errors["mx"] = err
 	} else {
 		info.MXRecords = mxRecords
+		info.InboundProviders = mx.Providers(mxRecords, domain)
+	}
+
+	// MX hosts usually send mail as well, receivers expect forward-confirmed reverse DNS for them
+	if len(info.MXRecords) > 0 {
+		info.MXReverseDNS = make(map[string][]host.PTRResult)
+		for _, record := range info.MXRecords {
+			for _, address := range record.Records {
+				if address.Type == "A" || address.Type == "AAAA" {
+					info.MXReverseDNS[record.Host] = append(info.MXReverseDNS[record.Host], host.CheckPTR(address.Value, nameserver))
+				}
+			}
+		}
+	}
+
+	// Collect the apex and www addresses, used for the implicit MX and to compare the MX with the website
+	if addresses, err := host.LookupAddresses(domain, nameserver); err == nil {
+		info.ApexAddresses = addresses
+	}
+	if addresses, err := host.LookupAddresses("www."+domain, nameserver); err == nil {
+		info.WWWAddresses = addresses
+	}
+
+	// A wildcard fakes the existence of MX records, selectors and subdomains, which the rules need to know
+	wildcard, err := subdomain.DetectWildcard(domain, nameserver)
+	if err != nil {
+		info.Errors["wildcard"] = err
+	} else {
+		info.Wildcard = wildcard
+	}
+
+	// Collect the SOA and NS records of the zone
+	soa, err := zone.LookupSOA(domain, nameserver)
+	if err != nil {
+		info.Errors["soa"] = err
+	} else {
+		info.SOA = soa
+		if nameservers, err := zone.LookupNS(soa.Zone, nameserver); err == nil {
+			info.Nameservers = nameservers
+		} else {
+			info.Errors["ns"] = err
+		}
+		info.Delegation = zone.CheckDelegation(soa.Zone, nameserver)
+		// The resolver returns the remaining cache time as TTL, prefer the SOA as the nameservers serve it
+		if authoritative, err := zone.AuthoritativeSOA(info.Delegation); err == nil {
+			info.SOA = authoritative
+		}
+	}
+
+	// Collect the MTA-STS record and policy
+	mtastsInfo, err := mtasts.Lookup(domain, nameserver)
+	if err != nil {
+		info.Errors["mta-sts"] = err
+	} else {
+		info.MTASTS = mtastsInfo
+	}
+
+	// Collect the TLSA records of the MX hosts
+	daneSeen := false
+	for _, record := range info.MXRecords {
+		if record.Host == "" {
+			continue
+		}
+		tlsa := dane.LookupTLSA(record.Host, nameserver)
+		if opts.SMTPProbe && len(tlsa.UsableRecords()) > 0 {
+			tlsa.Verifications = verifyTLSA(record, tlsa.Records)
+		}
+		info.MXTLSA = append(info.MXTLSA, tlsa)
+		daneSeen = daneSeen || len(tlsa.UsableRecords()) > 0
+	}
+
+	// Collect the CAA records of the domain and of the MX hosts, whose certificates senders authenticate
+	// when MTA-STS or DANE is deployed
+	domainCAA := caa.Lookup(domain, nameserver)
+	info.CAA = &domainCAA
+	if info.MTASTS != nil && info.MTASTS.Enforced() {
+		info.MXTransportSecurity = append(info.MXTransportSecurity, "MTA-STS")
+	}
+	if daneSeen {
+		info.MXTransportSecurity = append(info.MXTransportSecurity, "DANE")
+	}
+	for _, record := range info.MXRecords {
+		if record.Host != "" {
+			info.MXCAA = append(info.MXCAA, caa.Lookup(record.Host, nameserver))
+		}
+	}
+
+	// Collect all TXT records at the apex
+	txtRecords, err := txt.Lookup(domain, nameserver)
+	if err != nil {
+		info.Errors["txt"] = err
+	} else {
+		info.TXTRecords = txtRecords
 	}
 
 	// Collect SPF record
@@ -50,6 +229,8 @@ func CollectDNSInfo(domain string, nameserver string) (*DomainInfo, error) {
 		info.Errors["spf"] = err
 	} else {
 		info.SPFRecord = spfRecord
+		info.SPFExpansion = spf.Expand(spfRecord, domain, nameserver)
+		info.Providers = spfRecord.Providers()
 	}
 
 	// Collect DMARC record
@@ -57,24 +238,121 @@ func CollectDNSInfo(domain string, nameserver string) (*DomainInfo, error) {
 	if err != nil {
 		info.Errors["dmarc"] = err
 	} else {
+		if opts.DMARC.Strict {
+			dmarcRecord.EnforceStrict()
+		}
 		info.DMARCRecord = dmarcRecord
 		info.DMARCPolicy = dmarcRecord.GetPolicy()
+		info.DMARCVendors = info.DMARCPolicy.ReportVendors()
+		// Report destinations authorize the domain that published the record
+		policyDomain := domain
+		if dmarcRecord.InheritedFrom != "" {
+			policyDomain = dmarcRecord.InheritedFrom
+		}
+		info.DMARCReportAuthorizations = dmarc.CheckReportAuthorizations(policyDomain, info.DMARCPolicy, nameserver)
+		info.DMARCReportDestinations = dmarc.CheckReportDestinations(info.DMARCPolicy, nameserver)
 	}
 
-	dnssecInfo, err := dnssec.CheckDNSSECWithFallback(domain, nameserver)
+	// Collect the BIMI record, which only takes effect next to an enforcing DMARC policy
+	bimiInfo, err := bimi.Lookup(domain, nameserver)
+	if err != nil {
+		info.Errors["bimi"] = err
+	} else {
+		info.BIMI = bimiInfo
+	}
+
+	dnssecInfo, err := dnssec.CheckDNSSECWithOptionsFallback(domain, nameserver, opts.DNSSEC)
 	if err != nil {
 		info.Errors["dnssec"] = err
 	} else {
 		info.DNSSECInfo = dnssecInfo
 	}
 
-	dkimInfo, err := dkim.CheckDKIMWithFallback(domain, nameserver)
+	// DANE and trustworthy MX resolution depend on the zone of the MX host, not the sender's domain
+	for _, record := range info.MXRecords {
+		host := strings.ToLower(strings.TrimSuffix(record.Host, "."))
+		if host == strings.ToLower(domain) || strings.HasSuffix(host, "."+strings.ToLower(domain)) {
+			continue
+		}
+		info.MXZones = append(info.MXZones, dnssec.CheckZone(host, nameserver))
+	}
+
+	dkimInfo, err := dkim.CheckDKIMSelectorsWithFallback(domain, nameserver, opts.DKIMSelectors)
 	if err != nil {
 		info.Errors["dkim"] = err
 	} else {
 		info.DKIMInfo = dkimInfo
 	}
 
+	// Resolvers answer with the remaining cache time, read the TTLs of the MX and policy records from an
+	// authoritative nameserver instead
+	if len(info.MXRecords) > 0 {
+		if ttl, err := zone.LookupTTL(info.Delegation, domain, "MX", ""); err == nil {
+			for i := range info.MXRecords {
+				info.MXRecords[i].TTL, info.MXRecords[i].TTLKnown = ttl, true
+			}
+		}
+	}
+	if info.SPFRecord != nil {
+		if ttl, err := zone.LookupTTL(info.Delegation, domain, "TXT", "v=spf1"); err == nil {
+			info.SPFRecord.TTL, info.SPFRecord.TTLKnown = ttl, true
+		}
+	}
+	if info.DMARCRecord != nil {
+		if ttl, err := zone.LookupTTL(info.Delegation, info.DMARCRecord.Location, "TXT", "v=dmarc1"); err == nil {
+			info.DMARCRecord.TTL, info.DMARCRecord.TTLKnown = ttl, true
+		}
+	}
+	if info.DKIMInfo != nil {
+		for _, selector := range info.DKIMInfo.Selectors {
+			if ttl, err := zone.LookupTTL(info.Delegation, selector+"._domainkey."+domain, "TXT", ""); err == nil {
+				info.DKIMInfo.SelectorTTLs[selector] = ttl
+			}
+		}
+	}
+
+	// A signed apex with unsigned mail records gives a false sense of security, validate the mail RRsets themselves
+	if info.DNSSECInfo != nil && info.DNSSECInfo.HasDNSKEY {
+		var selectors []string
+		if info.DKIMInfo != nil {
+			selectors = info.DKIMInfo.Selectors
+		}
+		info.DNSSECInfo.MailRRsets = dnssec.VerifyRRsets(dnssec.MailRRsets(domain, selectors), nameserver)
+	}
+
+	if opts.TryZoneTransfer && info.Delegation != nil {
+		info.ZoneTransfers = zone.TryTransfers(info.Delegation)
+	}
+
+	// Stale secondaries serve outdated mail records to some senders, compare the records of every nameserver
+	if opts.CompareNameservers && info.Delegation != nil {
+		var selectors []string
+		if info.DKIMInfo != nil {
+			selectors = info.DKIMInfo.Selectors
+		}
+		info.RecordComparison = zone.CompareRecords(info.Delegation, zone.MailRecordQueries(domain, selectors))
+	}
+
+	// Scan subdomains when requested
+	if len(opts.Subdomains) > 0 {
+		info.Subdomains = subdomain.Scan(domain, opts.Subdomains, nameserver)
+	}
+
+	// Probe the website when requested
+	if opts.ProbeWeb {
+		info.WebInfo = web.Probe(domain)
+	}
+
+	return info, nil
+}
+
+// CollectHostInfo runs the reduced check set for a mail server hostname or bare IP address
+func CollectHostInfo(input string, nameserver string, opts Options) (*DomainInfo, error) {
+	info := NewDomainInfo(input)
+	info.HostInfo = host.CheckHost(input, nameserver, opts.SMTPProbe)
+	if info.HostInfo.Error != "" {
+		info.Errors["host"] = fmt.Errorf("%s", info.HostInfo.Error)
+	}
 	return info, nil
 }
 
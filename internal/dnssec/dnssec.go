@@ -1,7 +1,9 @@
 package dnssec
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
@@ -10,51 +12,121 @@ import (
 
 // DNSSECInfo contains basic DNSSEC information for a domain
 type DNSSECInfo struct {
-	Domain           string    // The domain name that was checked
-	Enabled          bool      // Whether DNSSEC is enabled
-	HasDNSKEY        bool      // Whether DNSKEY records were found
-	HasDS            bool      // Whether DS records were found
-	KeyCount         int       // Number of DNSKEY records found
-	Algorithm        []int     // DNSSEC algorithms in use
-	KeyTags          []uint16  // Key tags of the keys
-	LastSignatureExp time.Time // Expiration time of the most recent signature
-	Error            string    // Any error encountered during the check
+	Domain           string            // The domain name that was checked
+	Enabled          bool              // Whether DNSSEC is enabled
+	HasDNSKEY        bool              // Whether DNSKEY records were found
+	HasDS            bool              // Whether DS records were found
+	KeyCount         int               // Number of DNSKEY records found
+	Keys             []DNSKEYDetail    // Details of every DNSKEY record
+	LastSignatureExp time.Time         // Expiration time of the most recent signature
+	Signatures       []SignatureDetail // RRSIGs over the DNSKEY, MX and TXT RRsets of the domain
+	MailRRsets       []RRsetSignature  // Signature validation of the MX, SPF, DMARC and DKIM RRsets of a signed zone
+	NSEC3            *NSEC3Params      // NSEC3PARAM of the zone, nil when the zone uses NSEC or is unsigned
+	DSRecords        []DSRecord        // DS records published in the parent zone and whether they match a DNSKEY
+	Validation       string            // Validation result at the resolver: secure, insecure, bogus or indeterminate
+	TCPRequired      bool              // Whether an answer was truncated over UDP and had to be retrieved over TCP
+	Error            string            // Any error encountered during the check
+}
+
+// DNSKEYDetail describes a single DNSKEY record
+type DNSKEYDetail struct {
+	KeyTag        uint16 // Key tag of the key
+	Algorithm     int    // DNSSEC algorithm number
+	AlgorithmName string // Mnemonic of the algorithm, e.g. "ECDSAP256SHA256"
+	Flags         uint16 // DNSKEY flags field, 257 for a KSK and 256 for a ZSK
+	Role          string // "KSK" when the secure entry point flag is set, "ZSK" otherwise
+	KeySize       int    // Size of the public key in bits, 0 when unknown
+	Protocol      uint8  // Protocol field, always 3
+}
+
+// SignatureDetail describes an RRSIG covering an RRset of the domain
+type SignatureDetail struct {
+	TypeCovered string    // Type of the signed RRset, e.g. "MX"
+	KeyTag      uint16    // Key tag of the signing DNSKEY
+	Inception   time.Time // Start of the validity period
+	Expiration  time.Time // End of the validity period
+}
+
+// NSEC3Params contains the NSEC3 hashing parameters of a zone (RFC 5155 section 4)
+type NSEC3Params struct {
+	HashAlgorithm uint8  // Hash algorithm, 1 (SHA-1)
+	Flags         uint8  // Flags field, 0 in NSEC3PARAM records
+	Iterations    uint16 // Number of additional hash iterations
+	Salt          string // Hex encoded salt, empty when no salt is used
+}
+
+// DSRecord is a DS record of the parent zone, checked against the published DNSKEY records
+type DSRecord struct {
+	KeyTag     uint16 // Key tag of the DNSKEY the DS refers to
+	Algorithm  uint8  // DNSSEC algorithm of the referenced key
+	DigestType uint8  // Digest algorithm: 1 (SHA-1), 2 (SHA-256), 4 (SHA-384)
+	Digest     string // Hex encoded digest of the referenced key
+	Matches    bool   // Whether a published DNSKEY has this key tag, algorithm and digest
+	Problem    string // Why the DS doesn't match a DNSKEY
+}
+
+// AlgorithmName returns the mnemonic of a DNSSEC algorithm number, e.g. "RSASHA256" for 8
+func AlgorithmName(algorithm int) string {
+	if name, ok := dns.AlgorithmToString[uint8(algorithm)]; ok {
+		return name
+	}
+	return fmt.Sprintf("algorithm %d", algorithm)
+}
+
+// Validation results of a validating resolver
+const (
+	ValidationSecure        = "secure"        // The resolver validated the answer (AD bit set)
+	ValidationInsecure      = "insecure"      // The zone is not signed or has no DS at the parent
+	ValidationBogus         = "bogus"         // Validation fails: SERVFAIL normally, an answer with checking disabled
+	ValidationIndeterminate = "indeterminate" // The zone has a DS, but the resolver does not appear to validate
+)
+
+// DefaultEDNSBufferSize is the EDNS0 UDP buffer size advertised when none is configured
+const DefaultEDNSBufferSize = 4096
+
+// MinEDNSBufferSize is the smallest EDNS0 UDP buffer size, values below it are treated as 512 (RFC 6891 section 6.2.5)
+const MinEDNSBufferSize = 512
+
+// Options controls how the DNSSEC queries are sent
+type Options struct {
+	EDNSBufferSize uint16 // EDNS0 UDP buffer size to advertise, DefaultEDNSBufferSize when 0
 }
 
 // CheckDNSSEC retrieves DNSSEC information for a domain using the specified nameserver
 func CheckDNSSEC(domain string, nameserver string) (*DNSSECInfo, error) {
+	return CheckDNSSECWithOptions(domain, nameserver, Options{})
+}
+
+// CheckDNSSECWithOptions retrieves DNSSEC information for a domain, sending the queries as configured in opts
+func CheckDNSSECWithOptions(domain string, nameserver string, opts Options) (*DNSSECInfo, error) {
 	if !strings.HasSuffix(nameserver, ":53") {
 		nameserver = nameserver + ":53"
 	}
 
 	info := &DNSSECInfo{
-		Domain:    domain,
-		Enabled:   false,
-		Algorithm: []int{},
-		KeyTags:   []uint16{},
+		Domain:  domain,
+		Enabled: false,
+		Keys:    []DNSKEYDetail{},
 	}
 
-	// Check for DNSKEY records
-	c := dns.Client{}
-	m := dns.Msg{}
-	m.SetQuestion(dns.Fqdn(domain), dns.TypeDNSKEY)
-	m.SetEdns0(4096, true)
-	m.RecursionDesired = true
-
-	r, _, err := c.Exchange(&m, nameserver)
+	// Check for DNSKEY records. They are collected with checking disabled, a validating resolver would
+	// answer SERVFAIL for exactly the zones whose DS records don't match them.
+	q := newQuerier(nameserver, opts)
+	r, err := q.collect(domain, dns.TypeDNSKEY)
 	if err != nil {
 		info.Error = fmt.Sprintf("DNS query failed: %v", err)
 		return info, err
 	}
 
 	// Process DNSKEY records
+	var dnskeys []*dns.DNSKEY
 	for _, ans := range r.Answer {
 		if dnskey, ok := ans.(*dns.DNSKEY); ok {
+			dnskeys = append(dnskeys, dnskey)
 			info.HasDNSKEY = true
 			info.Enabled = true
 			info.KeyCount++
-			info.Algorithm = append(info.Algorithm, int(dnskey.Algorithm))
-			info.KeyTags = append(info.KeyTags, dnskey.KeyTag())
+			info.Keys = append(info.Keys, keyDetail(dnskey))
 		}
 
 		// Check for signature expiration
@@ -63,18 +135,43 @@ func CheckDNSSEC(domain string, nameserver string) (*DNSSECInfo, error) {
 			if expiration.After(info.LastSignatureExp) {
 				info.LastSignatureExp = expiration
 			}
+			info.Signatures = append(info.Signatures, signatureDetail(rrsig))
 		}
 	}
 
-	// Check for DS records in the parent zone
-	m = dns.Msg{}
-	m.SetQuestion(dns.Fqdn(domain), dns.TypeDS)
-	m.SetEdns0(4096, true)
-	m.RecursionDesired = true
+	// Collect the NSEC3 parameters and the signatures over the mail related RRsets of a signed zone
+	if info.HasDNSKEY {
+		if r, err := q.collect(domain, dns.TypeNSEC3PARAM); err == nil {
+			for _, ans := range r.Answer {
+				if param, ok := ans.(*dns.NSEC3PARAM); ok {
+					info.NSEC3 = &NSEC3Params{
+						HashAlgorithm: param.Hash,
+						Flags:         param.Flags,
+						Iterations:    param.Iterations,
+						Salt:          strings.ToLower(strings.TrimPrefix(param.Salt, "-")),
+					}
+				}
+			}
+		}
 
-	r, _, err = c.Exchange(&m, nameserver)
+		for _, qtype := range []uint16{dns.TypeMX, dns.TypeTXT} {
+			r, err := q.collect(domain, qtype)
+			if err != nil {
+				continue
+			}
+			for _, ans := range r.Answer {
+				if rrsig, ok := ans.(*dns.RRSIG); ok && rrsig.TypeCovered == qtype {
+					info.Signatures = append(info.Signatures, signatureDetail(rrsig))
+				}
+			}
+		}
+	}
+
+	// Check for DS records in the parent zone
+	r, err = q.collect(domain, dns.TypeDS)
 	if err != nil {
 		info.Error = fmt.Sprintf("DS record query failed: %v", err)
+		info.TCPRequired = q.tcpUsed
 		return info, err
 	}
 
@@ -82,17 +179,189 @@ func CheckDNSSEC(domain string, nameserver string) (*DNSSECInfo, error) {
 		info.HasDS = true
 		info.Enabled = true
 	}
+	for _, ans := range r.Answer {
+		if ds, ok := ans.(*dns.DS); ok {
+			info.DSRecords = append(info.DSRecords, matchDS(ds, dnskeys))
+		}
+	}
 
+	info.Validation = validate(q, domain, info.HasDS)
+	info.TCPRequired = q.tcpUsed
 	return info, nil
 }
 
+// validate compares the answers of the resolver with and without checking disabled (CD bit) to tell a
+// bogus zone apart from an unsigned one: a validating resolver answers SERVFAIL for bogus data, but
+// returns it when checking is disabled
+func validate(q *querier, domain string, hasDS bool) string {
+	r, err := q.queryCD(domain, dns.TypeSOA, false)
+	if err != nil {
+		return ValidationIndeterminate
+	}
+	if r.Rcode == dns.RcodeServerFailure {
+		unchecked, err := q.queryCD(domain, dns.TypeSOA, true)
+		if err == nil && unchecked.Rcode != dns.RcodeServerFailure {
+			return ValidationBogus
+		}
+		return ValidationIndeterminate
+	}
+	if r.AuthenticatedData {
+		return ValidationSecure
+	}
+	if hasDS {
+		return ValidationIndeterminate
+	}
+	return ValidationInsecure
+}
+
+// querier sends DNSSEC queries with the DO bit, retrying truncated UDP answers over TCP
+type querier struct {
+	client     dns.Client
+	nameserver string
+	bufferSize uint16
+	tcpUsed    bool // Whether any answer was truncated over UDP and retrieved over TCP
+}
+
+// newQuerier creates a querier for the nameserver using the buffer size in opts
+func newQuerier(nameserver string, opts Options) *querier {
+	bufferSize := opts.EDNSBufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultEDNSBufferSize
+	}
+	return &querier{nameserver: nameserver, bufferSize: bufferSize}
+}
+
+// collect sends a query with the DO and CD bits set. With checking disabled a validating resolver returns
+// the records and signatures of a bogus zone instead of SERVFAIL, so the checks can tell why it fails;
+// only validate relies on the resolver's own validation.
+func (q *querier) collect(name string, qtype uint16) (*dns.Msg, error) {
+	return q.queryCD(name, qtype, true)
+}
+
+// queryCD sends a query with the DO bit set and the CD bit as given. Large DNSKEY RRsets with their
+// signatures often exceed the UDP buffer, a truncated answer lacks records without any error, so it is
+// always retried over TCP.
+func (q *querier) queryCD(name string, qtype uint16, checkingDisabled bool) (*dns.Msg, error) {
+	m := dns.Msg{}
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.SetEdns0(q.bufferSize, true)
+	m.RecursionDesired = true
+	m.CheckingDisabled = checkingDisabled
+
+	r, _, err := q.client.Exchange(&m, q.nameserver)
+	if err != nil || !r.Truncated {
+		return r, err
+	}
+
+	tcp := dns.Client{Net: "tcp", Timeout: q.client.Timeout}
+	r, _, err = tcp.Exchange(&m, q.nameserver)
+	if err == nil {
+		q.tcpUsed = true
+	}
+	return r, err
+}
+
+// signatureDetail describes the validity period of an RRSIG
+func signatureDetail(rrsig *dns.RRSIG) SignatureDetail {
+	return SignatureDetail{
+		TypeCovered: dns.TypeToString[rrsig.TypeCovered],
+		KeyTag:      rrsig.KeyTag,
+		Inception:   time.Unix(int64(rrsig.Inception), 0).UTC(),
+		Expiration:  time.Unix(int64(rrsig.Expiration), 0).UTC(),
+	}
+}
+
+// keyDetail describes a DNSKEY record, determining its role and key size
+func keyDetail(key *dns.DNSKEY) DNSKEYDetail {
+	detail := DNSKEYDetail{
+		KeyTag:        key.KeyTag(),
+		Algorithm:     int(key.Algorithm),
+		AlgorithmName: AlgorithmName(int(key.Algorithm)),
+		Flags:         key.Flags,
+		Role:          "ZSK",
+		KeySize:       keySize(key),
+		Protocol:      key.Protocol,
+	}
+	if key.Flags&dns.SEP != 0 {
+		detail.Role = "KSK"
+	}
+	return detail
+}
+
+// keySize returns the size of the public key in bits (RFC 3110, RFC 6605, RFC 8080), or 0 when it can't be determined
+func keySize(key *dns.DNSKEY) int {
+	switch key.Algorithm {
+	case dns.RSAMD5, dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512:
+		data, err := base64.StdEncoding.DecodeString(key.PublicKey)
+		if err != nil || len(data) < 3 {
+			return 0
+		}
+		// The exponent length is one byte, or three bytes when the first is zero
+		exponentLength, offset := int(data[0]), 1
+		if exponentLength == 0 {
+			exponentLength, offset = int(data[1])<<8|int(data[2]), 3
+		}
+		if offset+exponentLength >= len(data) {
+			return 0
+		}
+		return new(big.Int).SetBytes(data[offset+exponentLength:]).BitLen()
+	case dns.ECDSAP256SHA256, dns.ED25519:
+		return 256
+	case dns.ECDSAP384SHA384:
+		return 384
+	case dns.ED448:
+		return 456
+	}
+	return 0
+}
+
+// matchDS checks whether a DS record refers to one of the published DNSKEY records (RFC 4034 section 5)
+func matchDS(ds *dns.DS, dnskeys []*dns.DNSKEY) DSRecord {
+	record := DSRecord{
+		KeyTag:     ds.KeyTag,
+		Algorithm:  ds.Algorithm,
+		DigestType: ds.DigestType,
+		Digest:     strings.ToLower(ds.Digest),
+	}
+
+	var candidates []*dns.DNSKEY
+	for _, key := range dnskeys {
+		if key.KeyTag() == ds.KeyTag && key.Algorithm == ds.Algorithm {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		record.Problem = fmt.Sprintf("no DNSKEY with key tag %d and algorithm %d is published", ds.KeyTag, ds.Algorithm)
+		return record
+	}
+
+	for _, key := range candidates {
+		computed := key.ToDS(ds.DigestType)
+		if computed == nil {
+			record.Problem = fmt.Sprintf("unsupported digest type %d", ds.DigestType)
+			return record
+		}
+		if strings.EqualFold(computed.Digest, ds.Digest) {
+			record.Matches = true
+			return record
+		}
+	}
+	record.Problem = fmt.Sprintf("the digest does not match the DNSKEY with key tag %d", ds.KeyTag)
+	return record
+}
+
 // CheckDNSSECWithFallback tries to use the specified nameserver, but falls back to 8.8.4.4 if that fails
 func CheckDNSSECWithFallback(domain string, nameserver string) (*DNSSECInfo, error) {
-	info, err := CheckDNSSEC(domain, nameserver)
+	return CheckDNSSECWithOptionsFallback(domain, nameserver, Options{})
+}
+
+// CheckDNSSECWithOptionsFallback is CheckDNSSECWithOptions, falling back to 8.8.4.4 if the nameserver fails
+func CheckDNSSECWithOptionsFallback(domain string, nameserver string, opts Options) (*DNSSECInfo, error) {
+	info, err := CheckDNSSECWithOptions(domain, nameserver, opts)
 	if err == nil {
 		return info, nil
 	}
 
 	// Fallback to Google DNS
-	return CheckDNSSEC(domain, "8.8.4.4:53")
+	return CheckDNSSECWithOptions(domain, "8.8.4.4:53", opts)
 }
@@ -0,0 +1,184 @@
+package dnssec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Signature status of an RRset
+const (
+	RRsetSigned       = "signed"       // A signature verified against a published DNSKEY
+	RRsetUnsigned     = "unsigned"     // The RRset carries no RRSIG
+	RRsetInvalid      = "invalid"      // Every signature failed to verify or is outside its validity period
+	RRsetUnverifiable = "unverifiable" // The DNSKEY of the signer could not be retrieved
+)
+
+// RRsetSignature contains the outcome of validating the signatures over a single RRset
+type RRsetSignature struct {
+	Name   string // Owner name of the RRset
+	Type   string // Record type, e.g. "MX" or "TXT"
+	Status string // One of the RRset status constants
+	Error  string // Why the signatures did not verify
+}
+
+// MailRRset is a name and type of a mail related RRset to validate
+type MailRRset struct {
+	Name  string
+	Qtype uint16
+}
+
+// MailRRsets returns the mail related RRsets of a domain: MX and SPF at the apex, the DMARC record
+// and the key records of the given DKIM selectors
+func MailRRsets(domain string, selectors []string) []MailRRset {
+	rrsets := []MailRRset{
+		{Name: domain, Qtype: dns.TypeMX},
+		{Name: domain, Qtype: dns.TypeTXT},
+		{Name: "_dmarc." + domain, Qtype: dns.TypeTXT},
+	}
+	for _, selector := range selectors {
+		rrsets = append(rrsets, MailRRset{Name: selector + "._domainkey." + domain, Qtype: dns.TypeTXT})
+	}
+	return rrsets
+}
+
+// VerifyRRsets queries each RRset with DNSSEC records and verifies its signatures against the DNSKEY
+// records of the signer. Every RRset in the answer is checked, including CNAMEs followed to the target.
+func VerifyRRsets(rrsets []MailRRset, nameserver string) []RRsetSignature {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	q := newQuerier(nameserver, Options{})
+	keys := make(map[string][]*dns.DNSKEY)
+	var results []RRsetSignature
+	for _, rrset := range rrsets {
+		r, err := q.collect(rrset.Name, rrset.Qtype)
+		if err != nil {
+			results = append(results, RRsetSignature{
+				Name:   rrset.Name,
+				Type:   dns.TypeToString[rrset.Qtype],
+				Status: RRsetUnverifiable,
+				Error:  fmt.Sprintf("DNS query failed: %v", err),
+			})
+			continue
+		}
+
+		for _, group := range groupRRsets(r.Answer) {
+			results = append(results, verifyRRset(q, group, keys))
+		}
+	}
+	return results
+}
+
+// answerRRset is an RRset from an answer section together with the signatures covering it
+type answerRRset struct {
+	name       string
+	rrtype     uint16
+	records    []dns.RR
+	signatures []*dns.RRSIG
+}
+
+// groupRRsets splits an answer section into RRsets by owner name and type, attaching their RRSIGs
+func groupRRsets(answer []dns.RR) []*answerRRset {
+	var groups []*answerRRset
+	find := func(name string, rrtype uint16) *answerRRset {
+		for _, group := range groups {
+			if strings.EqualFold(group.name, name) && group.rrtype == rrtype {
+				return group
+			}
+		}
+		group := &answerRRset{name: name, rrtype: rrtype}
+		groups = append(groups, group)
+		return group
+	}
+
+	for _, rr := range answer {
+		if rrsig, ok := rr.(*dns.RRSIG); ok {
+			group := find(rr.Header().Name, rrsig.TypeCovered)
+			group.signatures = append(group.signatures, rrsig)
+			continue
+		}
+		group := find(rr.Header().Name, rr.Header().Rrtype)
+		group.records = append(group.records, rr)
+	}
+
+	// Signatures without records can't be verified
+	var complete []*answerRRset
+	for _, group := range groups {
+		if len(group.records) > 0 {
+			complete = append(complete, group)
+		}
+	}
+	return complete
+}
+
+// verifyRRset checks whether any of the signatures over the RRset verifies
+func verifyRRset(q *querier, rrset *answerRRset, keys map[string][]*dns.DNSKEY) RRsetSignature {
+	result := RRsetSignature{
+		Name:   strings.TrimSuffix(rrset.name, "."),
+		Type:   dns.TypeToString[rrset.rrtype],
+		Status: RRsetUnsigned,
+	}
+	if len(rrset.signatures) == 0 {
+		return result
+	}
+
+	var problems []string
+	verifiable := false
+	for _, rrsig := range rrset.signatures {
+		signer := strings.ToLower(rrsig.SignerName)
+		if _, ok := keys[signer]; !ok {
+			keys[signer] = queryDNSKEYs(q, signer)
+		}
+
+		var key *dns.DNSKEY
+		for _, candidate := range keys[signer] {
+			if candidate.KeyTag() == rrsig.KeyTag && candidate.Algorithm == rrsig.Algorithm {
+				key = candidate
+				break
+			}
+		}
+		if key == nil {
+			problems = append(problems, fmt.Sprintf("no DNSKEY with key tag %d at %s", rrsig.KeyTag, strings.TrimSuffix(rrsig.SignerName, ".")))
+			continue
+		}
+		verifiable = true
+
+		if !rrsig.ValidityPeriod(time.Now()) {
+			problems = append(problems, fmt.Sprintf("signature by key tag %d is outside its validity period", rrsig.KeyTag))
+			continue
+		}
+		if err := rrsig.Verify(key, rrset.records); err != nil {
+			problems = append(problems, fmt.Sprintf("signature by key tag %d does not verify: %v", rrsig.KeyTag, err))
+			continue
+		}
+		result.Status = RRsetSigned
+		return result
+	}
+
+	result.Status = RRsetInvalid
+	if !verifiable {
+		result.Status = RRsetUnverifiable
+	}
+	result.Error = strings.Join(problems, "; ")
+	return result
+}
+
+// queryDNSKEYs returns the DNSKEY records published at the signer name
+func queryDNSKEYs(q *querier, signer string) []*dns.DNSKEY {
+	r, err := q.collect(signer, dns.TypeDNSKEY)
+	if err != nil {
+		return nil
+	}
+
+	var dnskeys []*dns.DNSKEY
+	for _, ans := range r.Answer {
+		if dnskey, ok := ans.(*dns.DNSKEY); ok {
+			dnskeys = append(dnskeys, dnskey)
+		}
+	}
+	return dnskeys
+}
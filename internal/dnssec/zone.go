@@ -0,0 +1,80 @@
+package dnssec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ZoneStatus contains the DNSSEC status of the zone a host name belongs to
+type ZoneStatus struct {
+	Host   string // Host name that was checked, e.g. an MX target
+	Zone   string // Apex of the zone containing the host
+	Signed bool   // Whether the zone publishes DNSKEY records and a DS at the parent
+	Error  string // Any error encountered during the check
+}
+
+// CheckZone finds the zone containing the host and checks whether it is DNSSEC-signed
+func CheckZone(host string, nameserver string) ZoneStatus {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	status := ZoneStatus{Host: strings.TrimSuffix(host, ".")}
+	c := dns.Client{}
+
+	zone, err := findZone(&c, host, nameserver)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Zone = strings.TrimSuffix(zone, ".")
+
+	// A zone is only validated when the parent has a DS for it, DNSKEYs alone form an island of trust
+	hasDNSKEY, hasDS := false, false
+	for _, qtype := range []uint16{dns.TypeDNSKEY, dns.TypeDS} {
+		m := dns.Msg{}
+		m.SetQuestion(zone, qtype)
+		m.SetEdns0(4096, true)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(&m, nameserver)
+		if err != nil {
+			status.Error = fmt.Sprintf("DNS query failed: %v", err)
+			return status
+		}
+		for _, ans := range r.Answer {
+			switch ans.(type) {
+			case *dns.DNSKEY:
+				hasDNSKEY = true
+			case *dns.DS:
+				hasDS = true
+			}
+		}
+	}
+	status.Signed = hasDNSKEY && hasDS
+	return status
+}
+
+// findZone returns the apex of the zone containing the name, taken from the SOA record in the response
+func findZone(c *dns.Client, name string, nameserver string) (string, error) {
+	m := dns.Msg{}
+	m.SetQuestion(dns.Fqdn(name), dns.TypeSOA)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(&m, nameserver)
+	if err != nil {
+		return "", fmt.Errorf("DNS query failed: %v", err)
+	}
+
+	// The SOA is in the answer at the apex and in the authority section below it
+	for _, section := range [][]dns.RR{r.Answer, r.Ns} {
+		for _, rr := range section {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return strings.ToLower(soa.Hdr.Name), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no SOA record found for %s", strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,122 @@
+package generate
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"check-maildomain/internal/dkim"
+)
+
+// maxTXTStringLength is the maximum length of a single character-string in a TXT record
+const maxTXTStringLength = 255
+
+// DKIMKeyPair contains a generated DKIM key pair and the record to publish
+type DKIMKeyPair struct {
+	Domain        string   // Domain the key signs for
+	Selector      string   // Selector the record is published under
+	KeyType       string   // "rsa" or "ed25519"
+	PrivateKeyPEM string   // PKCS#8 private key for the signer
+	PublicKey     string   // Base64 encoded public key as published in p=
+	Record        string   // The complete TXT record value
+	Chunks        []string // The record split into 255-character strings
+}
+
+// Name returns the DNS name the record must be published at
+func (k *DKIMKeyPair) Name() string {
+	return fmt.Sprintf("%s._domainkey.%s", k.Selector, k.Domain)
+}
+
+// GenerateDKIMKey creates a new RSA or Ed25519 key pair and the TXT record to publish for the selector
+func GenerateDKIMKey(domain string, selector string, keyType string, bits int) (*DKIMKeyPair, error) {
+	var private crypto.PrivateKey
+	var public []byte
+
+	switch keyType {
+	case "rsa":
+		if bits < 1024 {
+			return nil, fmt.Errorf("RSA keys must be at least 1024 bits, got %d", bits)
+		}
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, fmt.Errorf("generating RSA key failed: %v", err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("encoding RSA public key failed: %v", err)
+		}
+		private, public = key, der
+	case "ed25519":
+		// RFC 8463 publishes the raw 32-byte public key
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generating Ed25519 key failed: %v", err)
+		}
+		private, public = key, pub
+	default:
+		return nil, fmt.Errorf("unsupported key type %q, expected rsa or ed25519", keyType)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("encoding private key failed: %v", err)
+	}
+
+	pair := &DKIMKeyPair{
+		Domain:        domain,
+		Selector:      selector,
+		KeyType:       keyType,
+		PrivateKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		PublicKey:     base64.StdEncoding.EncodeToString(public),
+	}
+	pair.Record = fmt.Sprintf("v=DKIM1; k=%s; p=%s", keyType, pair.PublicKey)
+	pair.Chunks = SplitTXT(pair.Record)
+	return pair, nil
+}
+
+// SplitTXT splits a record value into strings of at most 255 characters, as required for TXT records
+func SplitTXT(record string) []string {
+	var chunks []string
+	for len(record) > maxTXTStringLength {
+		chunks = append(chunks, record[:maxTXTStringLength])
+		record = record[maxTXTStringLength:]
+	}
+	return append(chunks, record)
+}
+
+// ZoneRecord formats the record as a zone file line with quoted strings
+func (k *DKIMKeyPair) ZoneRecord() string {
+	quoted := make([]string, len(k.Chunks))
+	for i, chunk := range k.Chunks {
+		quoted[i] = `"` + chunk + `"`
+	}
+	return fmt.Sprintf("%s. IN TXT ( %s )", k.Name(), strings.Join(quoted, " "))
+}
+
+// VerifyDKIMPublication checks that the selector publishes exactly the generated public key
+func VerifyDKIMPublication(pair *DKIMKeyPair, nameserver string) error {
+	info, err := dkim.CheckDKIMSelectors(pair.Domain, nameserver, dkim.SelectorOptions{Supplied: []string{pair.Selector}})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range info.Keys {
+		if key.Selector != pair.Selector {
+			continue
+		}
+		if key.PublicKey != pair.PublicKey {
+			return fmt.Errorf("%s publishes a different public key", pair.Name())
+		}
+		if len(key.SyntaxErrors) > 0 {
+			return fmt.Errorf("%s is invalid: %s", pair.Name(), strings.Join(key.SyntaxErrors, "; "))
+		}
+		return nil
+	}
+	return fmt.Errorf("no key record found at %s", pair.Name())
+}
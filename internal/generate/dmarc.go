@@ -0,0 +1,189 @@
+package generate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"check-maildomain/internal/dmarc"
+	"check-maildomain/internal/dns"
+	"check-maildomain/internal/rules"
+)
+
+// DMARCOptions contains the choices used to build a DMARC record
+type DMARCOptions struct {
+	Domain          string // Domain the record is published for
+	Policy          string // p tag value
+	SubdomainPolicy string // sp tag value, omitted when empty
+	Percentage      int    // pct tag value, omitted when 100
+	AggregateReport string // rua mailbox, omitted when empty
+	ForensicReport  string // ruf mailbox, omitted when empty
+	ADKIM           string // adkim tag value, omitted when relaxed
+	ASPF            string // aspf tag value, omitted when relaxed
+}
+
+// BuildDMARCRecord assembles a DMARC record from the options, leaving out tags that equal their default
+func BuildDMARCRecord(opts DMARCOptions) string {
+	tags := []string{"v=DMARC1", "p=" + opts.Policy}
+	if opts.SubdomainPolicy != "" && opts.SubdomainPolicy != opts.Policy {
+		tags = append(tags, "sp="+opts.SubdomainPolicy)
+	}
+	if opts.Percentage != 100 && opts.Policy != "none" {
+		tags = append(tags, fmt.Sprintf("pct=%d", opts.Percentage))
+	}
+	if opts.AggregateReport != "" {
+		tags = append(tags, "rua=mailto:"+strings.TrimPrefix(opts.AggregateReport, "mailto:"))
+	}
+	if opts.ForensicReport != "" {
+		tags = append(tags, "ruf=mailto:"+strings.TrimPrefix(opts.ForensicReport, "mailto:"))
+	}
+	if opts.ADKIM == "s" {
+		tags = append(tags, "adkim=s")
+	}
+	if opts.ASPF == "s" {
+		tags = append(tags, "aspf=s")
+	}
+	return strings.Join(tags, "; ") + ";"
+}
+
+// DMARCRolloutPlan returns a staged rollout suggestion from monitoring towards the chosen policy. Every stage
+// keeps the chosen report destinations, alignment modes and a subdomain policy that differs from the policy;
+// the last stage is the chosen record itself.
+func DMARCRolloutPlan(opts DMARCOptions) []string {
+	plan := []string{
+		fmt.Sprintf("Monitor: %s (2-4 weeks, review aggregate reports and fix legitimate senders)", stageRecord(opts, "none", 100)),
+	}
+	if opts.Policy == "none" {
+		return plan
+	}
+
+	plan = append(plan, fmt.Sprintf("Quarantine a sample: %s (1-2 weeks)", stageRecord(opts, "quarantine", 25)))
+	if opts.Policy == "quarantine" {
+		return append(plan, fmt.Sprintf("Quarantine all: %s", BuildDMARCRecord(opts)))
+	}
+	return append(plan,
+		fmt.Sprintf("Quarantine all: %s (2-4 weeks)", stageRecord(opts, "quarantine", 100)),
+		fmt.Sprintf("Enforce: %s", BuildDMARCRecord(opts)),
+	)
+}
+
+// stageRecord builds the record of a rollout stage: the chosen record with the policy and percentage of
+// the stage. A subdomain policy that equals the chosen policy follows the stage.
+func stageRecord(opts DMARCOptions, policy string, percentage int) string {
+	stage := opts
+	stage.Policy = policy
+	stage.Percentage = percentage
+	if opts.SubdomainPolicy == opts.Policy {
+		stage.SubdomainPolicy = ""
+	}
+	return BuildDMARCRecord(stage)
+}
+
+// ValidateDMARCRecord runs the DMARC rules against a record without any DNS lookups
+func ValidateDMARCRecord(domain string, record string) *rules.EnhancedDomainInfo {
+	return ValidateDMARCRecordWithOptions(domain, record, dmarc.ParseOptions{})
+}
+
+// ValidateDMARCRecordWithOptions runs the DMARC rules against a record parsed with the given options
+func ValidateDMARCRecordWithOptions(domain string, record string, opts dmarc.ParseOptions) *rules.EnhancedDomainInfo {
+	info := dns.NewDomainInfo(domain)
+	info.DMARCRecord = dmarc.ParseRecordWithOptions(record, opts)
+	info.DMARCRecord.Location = "_dmarc." + domain
+	info.DMARCPolicy = info.DMARCRecord.GetPolicy()
+	info.DMARCVendors = info.DMARCPolicy.ReportVendors()
+
+	enhanced := rules.NewEnhancedDomainInfo(info)
+	rules.ApplyDMARCRules(enhanced)
+	return enhanced
+}
+
+// RunDMARCWizard interactively asks for the DMARC options, then prints the validated record and a rollout plan
+func RunDMARCWizard(in io.Reader, out io.Writer, domain string) DMARCOptions {
+	reader := bufio.NewReader(in)
+	opts := DMARCOptions{Domain: domain}
+
+	if opts.Domain == "" {
+		opts.Domain = ask(reader, out, "Domain", "", nil)
+	}
+	opts.Policy = ask(reader, out, "Policy (none, quarantine, reject)", "none", []string{"none", "quarantine", "reject"})
+	opts.SubdomainPolicy = ask(reader, out, "Subdomain policy (none, quarantine, reject)", opts.Policy, []string{"none", "quarantine", "reject"})
+	if opts.Policy != "none" {
+		opts.Percentage = askPercentage(reader, out, "Percentage of failing mail to apply the policy to (0-100)", 100)
+	} else {
+		opts.Percentage = 100
+	}
+	opts.AggregateReport = ask(reader, out, "Aggregate report (rua) mailbox", "dmarc-reports@"+opts.Domain, nil)
+	opts.ForensicReport = ask(reader, out, "Failure report (ruf) mailbox, empty for none", "", nil)
+	opts.ADKIM = ask(reader, out, "DKIM alignment (r=relaxed, s=strict)", "r", []string{"r", "s"})
+	opts.ASPF = ask(reader, out, "SPF alignment (r=relaxed, s=strict)", "r", []string{"r", "s"})
+
+	record := BuildDMARCRecord(opts)
+	fmt.Fprintf(out, "\nPublish the following TXT record at _dmarc.%s:\n\n%s\n", opts.Domain, record)
+
+	fmt.Fprintln(out, "\nValidation:")
+	for _, result := range ValidateDMARCRecord(opts.Domain, record).RuleResults {
+		fmt.Fprintf(out, "[%s] %s: %s\n", result.Status, result.Description, result.Message)
+	}
+
+	fmt.Fprintln(out, "\nSuggested staged rollout:")
+	for i, step := range DMARCRolloutPlan(opts) {
+		fmt.Fprintf(out, "%d. %s\n", i+1, step)
+	}
+
+	return opts
+}
+
+// ask prompts for a single value, returning the default for empty input and re-asking for values not in choices
+func ask(reader *bufio.Reader, out io.Writer, question string, def string, choices []string) string {
+	for {
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+
+		if len(choices) == 0 {
+			return answer
+		}
+
+		answer = strings.ToLower(answer)
+		if slices.Contains(choices, answer) {
+			return answer
+		}
+		if err != nil {
+			// Input ended, don't keep asking
+			return def
+		}
+		fmt.Fprintf(out, "Please answer one of: %s\n", strings.Join(choices, ", "))
+	}
+}
+
+// askPercentage prompts for a percentage, re-asking until the answer is a number from 0 to 100
+func askPercentage(reader *bufio.Reader, out io.Writer, question string, def int) int {
+	for {
+		fmt.Fprintf(out, "%s [%d]: ", question, def)
+
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			return def
+		}
+		if pct, convErr := strconv.Atoi(answer); convErr == nil && pct >= 0 && pct <= 100 {
+			return pct
+		}
+		if err != nil {
+			// Input ended, don't keep asking
+			return def
+		}
+		fmt.Fprintln(out, "Please answer a number from 0 to 100")
+	}
+}
@@ -0,0 +1,53 @@
+package host
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// LookupASN returns the number of the autonomous system announcing the IP address, using the IP to ASN
+// mapping of Team Cymru over DNS
+func LookupASN(address string, nameserver string) (string, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address: %s", address)
+	}
+
+	reversed, err := dns.ReverseAddr(address)
+	if err != nil {
+		return "", err
+	}
+	zone := "origin.asn.cymru.com."
+	if ip.To4() == nil {
+		zone = "origin6.asn.cymru.com."
+	}
+	reversed = strings.TrimSuffix(strings.TrimSuffix(reversed, "in-addr.arpa."), "ip6.arpa.")
+
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(reversed+zone, dns.TypeTXT)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return "", fmt.Errorf("DNS query failed: %v", err)
+	}
+
+	// The answer looks like "13335 | 1.1.1.0/24 | US | arin | 2010-07-14"
+	for _, a := range r.Answer {
+		if record, ok := a.(*dns.TXT); ok {
+			fields := strings.Split(strings.Join(record.Txt, ""), "|")
+			if asn := strings.Fields(fields[0]); len(asn) > 0 {
+				return "AS" + asn[0], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no origin AS found for %s", address)
+}
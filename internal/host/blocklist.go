@@ -0,0 +1,94 @@
+package host
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// BlocklistResult contains the result of a DNS blocklist lookup for a single IP address
+type BlocklistResult struct {
+	IP       string // Address that was looked up
+	Zone     string // Blocklist zone that was queried
+	Listed   bool   // Whether the address is listed
+	Response string // Return code(s) of the blocklist, if listed
+	Error    string // Any error encountered during the lookup, including refused queries
+}
+
+// Blocklist describes a DNS blocklist zone
+type Blocklist struct {
+	Zone string // DNS zone to query
+	IPv6 bool   // Whether the zone supports IPv6 lookups
+}
+
+// Blocklists is the list of DNS blocklists checked for each address
+var Blocklists = []Blocklist{
+	{Zone: "zen.spamhaus.org", IPv6: true},
+	{Zone: "bl.spamcop.net"},
+	{Zone: "b.barracudacentral.org"},
+	{Zone: "psbl.surriel.com"},
+}
+
+// CheckBlocklists looks up an IP address in each configured DNS blocklist
+func CheckBlocklists(address string, nameserver string) []BlocklistResult {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil
+	}
+
+	reversed, err := dns.ReverseAddr(address)
+	if err != nil {
+		return nil
+	}
+	// Strip the in-addr.arpa. / ip6.arpa. suffix to get the reversed address labels
+	reversed = strings.TrimSuffix(strings.TrimSuffix(reversed, "in-addr.arpa."), "ip6.arpa.")
+
+	var results []BlocklistResult
+	c := new(dns.Client)
+	for _, blocklist := range Blocklists {
+		if ip.To4() == nil && !blocklist.IPv6 {
+			continue
+		}
+
+		result := BlocklistResult{
+			IP:   address,
+			Zone: blocklist.Zone,
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(reversed+blocklist.Zone), dns.TypeA)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			result.Error = fmt.Sprintf("DNS query failed: %v", err)
+			results = append(results, result)
+			continue
+		}
+
+		var codes []string
+		for _, a := range r.Answer {
+			if record, ok := a.(*dns.A); ok {
+				codes = append(codes, record.A.String())
+			}
+		}
+
+		// 127.255.255.x answers mean the blocklist refused the query (e.g. from a public resolver)
+		if len(codes) > 0 && strings.HasPrefix(codes[0], "127.255.255.") {
+			result.Error = fmt.Sprintf("query refused by blocklist (%s)", codes[0])
+		} else if len(codes) > 0 {
+			result.Listed = true
+			result.Response = strings.Join(codes, ", ")
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
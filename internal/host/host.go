@@ -0,0 +1,261 @@
+package host
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Input types supported by the checker
+const (
+	TypeDomain = "domain" // A mail domain, checked with the full rule set
+	TypeHost   = "host"   // A mail server hostname, checked with the reduced host rule set
+	TypeIP     = "ip"     // A bare IP address, checked with the reduced host rule set
+)
+
+// HostInfo contains the results of the reduced check set for a mail server hostname or IP
+type HostInfo struct {
+	Input      string            // Hostname or IP address that was checked
+	Type       string            // host or ip
+	Addresses  []string          // IP addresses that were checked
+	PTR        []PTRResult       // Reverse DNS results per address
+	Blocklists []BlocklistResult // DNS blocklist results per address and zone
+	SMTP       []SMTPResult      // SMTP/TLS probe results per address (only when probing is enabled)
+	Error      string            // Any error encountered during the check
+}
+
+// PTRResult contains the reverse DNS lookup result for a single IP address
+type PTRResult struct {
+	IP               string   // Address that was looked up
+	Names            []string // PTR names returned for the address
+	ForwardConfirmed bool     // Whether one of the PTR names resolves back to the address (FCrDNS)
+	Error            string   // Any error encountered during the lookup
+}
+
+// DetectInputType determines whether the input is a bare IP, a mail server hostname or a mail domain.
+// A name is treated as a hostname when it has address records but no MX, SPF or DMARC records, and is not
+// the apex of a zone: a domain without mail records is still checked as a domain unless -input-type host is
+// given.
+func DetectInputType(input string, nameserver string) string {
+	if net.ParseIP(input) != nil {
+		return TypeIP
+	}
+
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	if hasRecord(input, dns.TypeMX, nameserver) {
+		return TypeDomain
+	}
+	if hasTXTPrefix(input, "v=spf1", nameserver) || hasTXTPrefix("_dmarc."+input, "v=dmarc1", nameserver) {
+		return TypeDomain
+	}
+	if isZoneApex(input, nameserver) {
+		return TypeDomain
+	}
+	if hasRecord(input, dns.TypeA, nameserver) || hasRecord(input, dns.TypeAAAA, nameserver) {
+		return TypeHost
+	}
+
+	return TypeDomain
+}
+
+// CheckHost runs the reduced check set against a hostname or IP address
+func CheckHost(input string, nameserver string, smtpProbe bool) *HostInfo {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	info := &HostInfo{
+		Input:      input,
+		Type:       TypeHost,
+		Addresses:  []string{},
+		PTR:        []PTRResult{},
+		Blocklists: []BlocklistResult{},
+	}
+
+	if ip := net.ParseIP(input); ip != nil {
+		info.Type = TypeIP
+		info.Addresses = append(info.Addresses, ip.String())
+	} else {
+		addresses, err := LookupAddresses(input, nameserver)
+		if err != nil {
+			info.Error = err.Error()
+			return info
+		}
+		info.Addresses = addresses
+	}
+
+	for _, address := range info.Addresses {
+		info.PTR = append(info.PTR, CheckPTR(address, nameserver))
+		info.Blocklists = append(info.Blocklists, CheckBlocklists(address, nameserver)...)
+	}
+
+	if smtpProbe {
+		serverName := input
+		if info.Type == TypeIP && len(info.PTR) > 0 && len(info.PTR[0].Names) > 0 {
+			serverName = info.PTR[0].Names[0]
+		}
+		for _, address := range info.Addresses {
+			info.SMTP = append(info.SMTP, ProbeSMTP(address, serverName))
+		}
+	}
+
+	return info
+}
+
+// CheckPTR looks up the PTR records of an IP address and verifies forward-confirmed reverse DNS
+func CheckPTR(address string, nameserver string) PTRResult {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	result := PTRResult{
+		IP:    address,
+		Names: []string{},
+	}
+
+	reverse, err := dns.ReverseAddr(address)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(reverse, dns.TypePTR)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		result.Error = fmt.Sprintf("DNS query failed: %v", err)
+		return result
+	}
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		result.Error = fmt.Sprintf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+		return result
+	}
+
+	for _, a := range r.Answer {
+		if ptr, ok := a.(*dns.PTR); ok {
+			result.Names = append(result.Names, strings.TrimSuffix(ptr.Ptr, "."))
+		}
+	}
+
+	// Verify that one of the PTR names resolves back to the address
+	ip := net.ParseIP(address)
+	for _, name := range result.Names {
+		addresses, err := LookupAddresses(name, nameserver)
+		if err != nil {
+			continue
+		}
+		for _, a := range addresses {
+			if net.ParseIP(a).Equal(ip) {
+				result.ForwardConfirmed = true
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// LookupAddresses returns the IPv4 and IPv6 addresses of a hostname
+func LookupAddresses(hostname string, nameserver string) ([]string, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	c := new(dns.Client)
+	addresses := []string{}
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(hostname), qtype)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			return nil, fmt.Errorf("DNS query failed: %v", err)
+		}
+
+		for _, a := range r.Answer {
+			switch record := a.(type) {
+			case *dns.A:
+				addresses = append(addresses, record.A.String())
+			case *dns.AAAA:
+				addresses = append(addresses, record.AAAA.String())
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no A or AAAA records found for host: %s", hostname)
+	}
+
+	return addresses, nil
+}
+
+// hasRecord reports whether a name has at least one record of the given type
+func hasRecord(name string, qtype uint16, nameserver string) bool {
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil || r.Rcode != dns.RcodeSuccess {
+		return false
+	}
+
+	for _, a := range r.Answer {
+		if a.Header().Rrtype == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+// isZoneApex reports whether a SOA or NS record is published at the name itself, which makes it the apex of
+// a zone rather than a host inside one
+func isZoneApex(name string, nameserver string) bool {
+	c := new(dns.Client)
+	for _, qtype := range []uint16{dns.TypeSOA, dns.TypeNS} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), qtype)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil || r.Rcode != dns.RcodeSuccess {
+			continue
+		}
+		for _, a := range r.Answer {
+			if a.Header().Rrtype == qtype && strings.EqualFold(a.Header().Name, dns.Fqdn(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasTXTPrefix reports whether a name has a TXT record starting with the given prefix
+func hasTXTPrefix(name string, prefix string, nameserver string) bool {
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil || r.Rcode != dns.RcodeSuccess {
+		return false
+	}
+
+	for _, a := range r.Answer {
+		if txt, ok := a.(*dns.TXT); ok && strings.HasPrefix(strings.ToLower(strings.Join(txt.Txt, "")), prefix) {
+			return true
+		}
+	}
+	return false
+}
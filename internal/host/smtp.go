@@ -0,0 +1,116 @@
+package host
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// smtpTimeout limits how long a single SMTP probe may take
+const smtpTimeout = 15 * time.Second
+
+// SMTPResult contains the result of an SMTP/STARTTLS probe against a single address
+type SMTPResult struct {
+	IP                 string   // Address that was probed
+	ServerName         string   // Name used for certificate verification
+	Banner             string   // SMTP greeting banner
+	STARTTLS           bool     // Whether STARTTLS is offered
+	TLSVersion         string   // Negotiated TLS version
+	CertificateSubject string   // Subject of the presented leaf certificate
+	CertificateNames   []string // DNS names of the presented leaf certificate
+	CertificateExpiry  time.Time
+	CertificateValid   bool                // Whether the certificate chain verifies for ServerName
+	CertificateError   string              // Why the certificate did not verify
+	PeerCertificates   []*x509.Certificate `json:"-"` // Presented certificate chain, for further verification
+	Error              string              // Any error encountered during the probe
+}
+
+// ProbeSMTP connects to port 25 of an address, reads the banner, negotiates STARTTLS and inspects the certificate
+func ProbeSMTP(address string, serverName string) SMTPResult {
+	result := SMTPResult{
+		IP:         address,
+		ServerName: serverName,
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(address, "25"), smtpTimeout)
+	if err != nil {
+		result.Error = fmt.Sprintf("connection failed: %v", err)
+		return result
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	text := textproto.NewConn(conn)
+	_, banner, err := text.ReadResponse(220)
+	if err != nil {
+		result.Error = fmt.Sprintf("unexpected greeting: %v", err)
+		return result
+	}
+	result.Banner = banner
+
+	if err := text.PrintfLine("EHLO check-maildomain.invalid"); err != nil {
+		result.Error = fmt.Sprintf("EHLO failed: %v", err)
+		return result
+	}
+	_, extensions, err := text.ReadResponse(250)
+	if err != nil {
+		result.Error = fmt.Sprintf("EHLO rejected: %v", err)
+		return result
+	}
+
+	for _, line := range strings.Split(extensions, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), "STARTTLS") {
+			result.STARTTLS = true
+		}
+	}
+
+	if !result.STARTTLS {
+		text.PrintfLine("QUIT")
+		return result
+	}
+
+	if err := text.PrintfLine("STARTTLS"); err != nil {
+		result.Error = fmt.Sprintf("STARTTLS failed: %v", err)
+		return result
+	}
+	if _, _, err := text.ReadResponse(220); err != nil {
+		result.Error = fmt.Sprintf("STARTTLS rejected: %v", err)
+		return result
+	}
+
+	// Verification is done separately so that the certificate can be inspected even when it is invalid
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err := tlsConn.Handshake(); err != nil {
+		result.Error = fmt.Sprintf("TLS handshake failed: %v", err)
+		return result
+	}
+	defer tlsConn.Close()
+
+	state := tlsConn.ConnectionState()
+	result.TLSVersion = tls.VersionName(state.Version)
+	result.PeerCertificates = state.PeerCertificates
+
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		result.CertificateSubject = leaf.Subject.String()
+		result.CertificateNames = leaf.DNSNames
+		result.CertificateExpiry = leaf.NotAfter
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: serverName, Intermediates: intermediates}); err != nil {
+			result.CertificateError = err.Error()
+		} else {
+			result.CertificateValid = true
+		}
+	}
+
+	fmt.Fprintf(tlsConn, "QUIT\r\n")
+	return result
+}
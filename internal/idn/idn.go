@@ -0,0 +1,99 @@
+package idn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// ToASCII converts an internationalized domain name to its ASCII (punycode, xn--) form as used in DNS.
+// ASCII names are returned unchanged, so names with e.g. underscores remain usable.
+func ToASCII(domain string) (string, error) {
+	if isASCII(domain) {
+		return domain, nil
+	}
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil {
+		return "", fmt.Errorf("invalid internationalized domain name %q: %v", domain, err)
+	}
+	return ascii, nil
+}
+
+// ToUnicode converts the ASCII form of a domain name to Unicode. The name is returned unchanged when it
+// contains no punycode labels or can't be converted.
+func ToUnicode(domain string) string {
+	unicodeName, err := idna.Display.ToUnicode(domain)
+	if err != nil {
+		return domain
+	}
+	return unicodeName
+}
+
+// IsInternationalized reports whether the ASCII form of the domain contains punycode labels
+func IsInternationalized(domain string) bool {
+	for _, label := range strings.Split(strings.ToLower(domain), ".") {
+		if strings.HasPrefix(label, "xn--") {
+			return true
+		}
+	}
+	return false
+}
+
+// isASCII reports whether the name consists of ASCII characters only
+func isASCII(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// cjkScripts are combined into one group, since Japanese and Korean names mix them legitimately
+var cjkScripts = map[string]bool{
+	"Han": true, "Hiragana": true, "Katakana": true, "Hangul": true, "Bopomofo": true,
+}
+
+// MixedScriptLabels returns the labels of the Unicode domain that mix letters of several scripts, such as
+// Latin and Cyrillic, together with the scripts they mix. Such labels are a common homograph technique.
+func MixedScriptLabels(domain string) map[string][]string {
+	mixed := make(map[string][]string)
+	for _, label := range strings.Split(domain, ".") {
+		scripts := labelScripts(label)
+		if len(scripts) > 1 {
+			mixed[label] = scripts
+		}
+	}
+	return mixed
+}
+
+// labelScripts returns the sorted scripts of the letters in the label, ignoring digits and punctuation
+func labelScripts(label string) []string {
+	seen := make(map[string]bool)
+	for _, r := range label {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for name, table := range unicode.Scripts {
+			if name == "Common" || name == "Inherited" || !unicode.Is(table, r) {
+				continue
+			}
+			if cjkScripts[name] {
+				name = "CJK"
+			}
+			seen[name] = true
+			break
+		}
+	}
+
+	var scripts []string
+	for name := range seen {
+		scripts = append(scripts, name)
+	}
+	sort.Strings(scripts)
+	return scripts
+}
@@ -0,0 +1,63 @@
+package message
+
+import (
+	"bytes"
+	"strings"
+)
+
+// canonicalHeader canonicalizes a header field with the simple or relaxed algorithm (RFC 6376 section 3.4)
+func canonicalHeader(header Header, relaxed bool) string {
+	if !relaxed {
+		return header.Raw
+	}
+	name, value, _ := strings.Cut(header.Raw, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value + "\r\n"
+}
+
+// canonicalBody canonicalizes the body with the simple or relaxed algorithm (RFC 6376 section 3.4)
+func canonicalBody(body []byte, relaxed bool) []byte {
+	lines := bytes.Split(body, []byte("\r\n"))
+	if relaxed {
+		for i, line := range lines {
+			lines[i] = compressWSP(line)
+		}
+	}
+
+	// Empty lines at the end of the body are ignored
+	for len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		// The simple algorithm turns an empty body into a single CRLF, relaxed leaves it empty
+		if relaxed {
+			return nil
+		}
+		return []byte("\r\n")
+	}
+	return append(bytes.Join(lines, []byte("\r\n")), "\r\n"...)
+}
+
+// compressWSP reduces every run of whitespace in a body line to a single space and strips it from the end
+func compressWSP(line []byte) []byte {
+	var out []byte
+	space := false
+	for _, c := range line {
+		if c == ' ' || c == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			out = append(out, ' ')
+			space = false
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// isWSP reports whether the rune is whitespace as defined for canonicalization
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
@@ -0,0 +1,47 @@
+package message
+
+import "testing"
+
+// The canonicalization examples of RFC 6376 section 3.4.5
+const (
+	rfc6376Header1 = "A: X\r\n"
+	rfc6376Header2 = "B : Y\t\r\n\tZ  \r\n"
+	rfc6376Body    = " C \r\nD \t E\r\n\r\n\r\n"
+)
+
+func TestCanonicalHeader(t *testing.T) {
+	tests := []struct {
+		raw     string
+		relaxed bool
+		want    string
+	}{
+		{rfc6376Header1, false, "A: X\r\n"},
+		{rfc6376Header2, false, "B : Y\t\r\n\tZ  \r\n"},
+		{rfc6376Header1, true, "a:X\r\n"},
+		{rfc6376Header2, true, "b:Y Z\r\n"},
+	}
+	for _, test := range tests {
+		if got := canonicalHeader(Header{Raw: test.raw}, test.relaxed); got != test.want {
+			t.Errorf("canonicalHeader(%q, relaxed=%t) = %q, want %q", test.raw, test.relaxed, got, test.want)
+		}
+	}
+}
+
+func TestCanonicalBody(t *testing.T) {
+	tests := []struct {
+		body    string
+		relaxed bool
+		want    string
+	}{
+		{rfc6376Body, false, " C \r\nD \t E\r\n"},
+		{rfc6376Body, true, " C\r\nD E\r\n"},
+		// An empty body is a single CRLF with simple and empty with relaxed (RFC 6376 section 3.4.3 and 3.4.4)
+		{"", false, "\r\n"},
+		{"\r\n\r\n", true, ""},
+	}
+	for _, test := range tests {
+		if got := string(canonicalBody([]byte(test.body), test.relaxed)); got != test.want {
+			t.Errorf("canonicalBody(%q, relaxed=%t) = %q, want %q", test.body, test.relaxed, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package message
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// Header is a single header field of a message, kept exactly as it appeared for signature verification
+type Header struct {
+	Name string // Field name as written in the message
+	Raw  string // The complete field including the name and folding, terminated by CRLF
+}
+
+// Value returns the unfolded field value without the field name
+func (h Header) Value() string {
+	_, value, _ := strings.Cut(h.Raw, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.TrimSpace(value)
+}
+
+// Message is a raw email message split into header fields and body
+type Message struct {
+	File       string   // File the message was read from
+	Headers    []Header // Header fields in message order
+	Body       []byte   // The body with CRLF line endings
+	From       string   // Address of the From header
+	FromDomain string   // Domain of the From header, used for DMARC alignment
+}
+
+// ParseFile reads a message from a file such as an .eml export
+func ParseFile(path string) (*Message, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	msg, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	msg.File = path
+	return msg, nil
+}
+
+// Parse reads a raw message, normalizing bare LF line endings to CRLF as they were on the wire
+func Parse(r io.Reader) (*Message, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading message failed: %v", err)
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+
+	head, body, found := bytes.Cut(data, []byte("\r\n\r\n"))
+	if !found {
+		return nil, fmt.Errorf("no empty line between header and body")
+	}
+
+	msg := &Message{Body: body}
+	for _, line := range strings.SplitAfter(string(head)+"\r\n", "\r\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			// Continuation of a folded header field
+			if len(msg.Headers) == 0 {
+				return nil, fmt.Errorf("message starts with a folded line")
+			}
+			msg.Headers[len(msg.Headers)-1].Raw += line
+			continue
+		}
+		name, _, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("malformed header line: %q", strings.TrimSpace(line))
+		}
+		msg.Headers = append(msg.Headers, Header{Name: strings.TrimSpace(name), Raw: line})
+	}
+
+	from := msg.Get("From")
+	if from == "" {
+		return nil, fmt.Errorf("no From header found")
+	}
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("parsing From header failed: %v", err)
+	}
+	msg.From = address.Address
+	if at := strings.LastIndex(address.Address, "@"); at >= 0 {
+		msg.FromDomain = strings.ToLower(address.Address[at+1:])
+	}
+	return msg, nil
+}
+
+// Get returns the value of the first header field with the given name
+func (m *Message) Get(name string) string {
+	for _, header := range m.Headers {
+		if strings.EqualFold(header.Name, name) {
+			return header.Value()
+		}
+	}
+	return ""
+}
+
+// All returns every header field with the given name, in message order
+func (m *Message) All(name string) []Header {
+	var headers []Header
+	for _, header := range m.Headers {
+		if strings.EqualFold(header.Name, name) {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
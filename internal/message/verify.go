@@ -0,0 +1,378 @@
+package message
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"check-maildomain/internal/dkim"
+	"check-maildomain/internal/dmarc"
+)
+
+// Signature is a parsed DKIM-Signature header field and the outcome of verifying it (RFC 6376 section 3.5)
+type Signature struct {
+	Domain           string   // d tag, the signing domain
+	Selector         string   // s tag
+	Identity         string   // i tag, the agent or user the signature is made for, "@" and d tag when absent
+	Algorithm        string   // a tag, e.g. "rsa-sha256"
+	Canonicalization string   // c tag, header and body algorithm
+	SignedHeaders    []string // h tag, the signed header fields
+	BodyLength       int64    // l tag, -1 when the whole body is signed
+	Expires          int64    // x tag as a Unix timestamp, 0 when absent
+
+	Result  string // "pass", "fail", "permerror" or "temperror"
+	Reason  string // Why the signature did not pass
+	Aligned bool   // Whether the signing domain aligns with the From domain under the DMARC adkim mode
+
+	header Header            // The DKIM-Signature header field itself
+	tags   map[string]string // All tags and their values
+}
+
+// Verification contains the result of verifying all DKIM signatures of a message
+type Verification struct {
+	File        string       // File the message was read from
+	From        string       // Address of the From header
+	FromDomain  string       // Domain of the From header
+	Signatures  []*Signature // Every DKIM-Signature in the message, topmost first
+	DMARCRecord string       // The DMARC record applying to the From domain, if any
+	Alignment   string       // DMARC DKIM alignment mode, "r" (relaxed) or "s" (strict)
+	DMARCResult string       // "pass" when an aligned signature verified, "fail" otherwise
+	DMARCError  string       // Any error encountered looking up the DMARC record
+}
+
+// signatureBTag matches the b tag of a DKIM-Signature, whose value is removed before hashing the header
+var signatureBTag = regexp.MustCompile(`(^|;)(\s*b\s*=)[^;]*`)
+
+// Verify verifies every DKIM-Signature of the message using the keys published at the nameserver
+// and evaluates DMARC DKIM alignment against the From domain
+func Verify(msg *Message, nameserver string) *Verification {
+	result := &Verification{
+		File:       msg.File,
+		From:       msg.From,
+		FromDomain: msg.FromDomain,
+		Signatures: []*Signature{},
+		Alignment:  "r",
+	}
+
+	record, err := dmarc.LookupDMARCWithFallback(msg.FromDomain, nameserver)
+	if err != nil {
+		result.DMARCError = err.Error()
+	} else {
+		result.DMARCRecord = record.Raw
+		if strings.ToLower(record.GetPolicy().ADKIM) == "s" {
+			result.Alignment = "s"
+		}
+	}
+
+	result.DMARCResult = "fail"
+	for _, header := range msg.All("DKIM-Signature") {
+		sig := parseSignature(header)
+		if sig.Result == "" {
+			sig.verify(msg, func() (*dkim.DKIMKey, error) {
+				return sig.fetchKey(nameserver)
+			})
+		}
+		sig.Aligned = sig.Domain != "" && aligned(sig.Domain, msg.FromDomain, result.Alignment)
+		if sig.Result == "pass" && sig.Aligned {
+			result.DMARCResult = "pass"
+		}
+		result.Signatures = append(result.Signatures, sig)
+	}
+	return result
+}
+
+// aligned reports whether the signing domain aligns with the From domain (RFC 7489 section 3.1.1)
+func aligned(signer string, from string, mode string) bool {
+	signer = strings.ToLower(strings.TrimSuffix(signer, "."))
+	if mode == "s" {
+		return signer == from
+	}
+	return dmarc.OrganizationalDomain(signer) == dmarc.OrganizationalDomain(from)
+}
+
+// parseSignature reads the tags of a DKIM-Signature, setting a permerror result when required tags are missing or invalid
+func parseSignature(header Header) *Signature {
+	sig := &Signature{
+		BodyLength: -1,
+		header:     header,
+		tags:       make(map[string]string),
+	}
+
+	for _, part := range strings.Split(header.Value(), ";") {
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		// Folding whitespace may appear anywhere in the values
+		sig.tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(value), "")
+	}
+
+	sig.Domain = strings.ToLower(sig.tags["d"])
+	sig.Selector = sig.tags["s"]
+	sig.Algorithm = strings.ToLower(sig.tags["a"])
+	sig.Canonicalization = strings.ToLower(sig.tags["c"])
+	if sig.Canonicalization == "" {
+		sig.Canonicalization = "simple/simple"
+	}
+	for _, name := range strings.Split(sig.tags["h"], ":") {
+		if name != "" {
+			sig.SignedHeaders = append(sig.SignedHeaders, name)
+		}
+	}
+
+	for _, tag := range []string{"v", "a", "b", "bh", "d", "h", "s"} {
+		if _, ok := sig.tags[tag]; !ok {
+			return sig.permerror(fmt.Sprintf("required tag %s is missing", tag))
+		}
+	}
+	if sig.tags["v"] != "1" {
+		return sig.permerror(fmt.Sprintf("unsupported version v=%s", sig.tags["v"]))
+	}
+	if !slices.ContainsFunc(sig.SignedHeaders, func(item string) bool { return strings.EqualFold(strings.TrimSpace(item), "From") }) {
+		return sig.permerror("the From header is not signed")
+	}
+	if l, ok := sig.tags["l"]; ok {
+		length, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || length < 0 {
+			return sig.permerror(fmt.Sprintf("invalid body length l=%s", l))
+		}
+		sig.BodyLength = length
+	}
+	if x, ok := sig.tags["x"]; ok {
+		expires, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return sig.permerror(fmt.Sprintf("invalid expiration x=%s", x))
+		}
+		sig.Expires = expires
+	}
+
+	sig.Identity = sig.tags["i"]
+	if sig.Identity == "" {
+		sig.Identity = "@" + sig.Domain
+	}
+	// The identity must be in the signing domain or a subdomain of it (RFC 6376 section 3.5)
+	if identity := identityDomain(sig.Identity); identity != sig.Domain && !strings.HasSuffix(identity, "."+sig.Domain) {
+		return sig.permerror(fmt.Sprintf("i=%s is not within d=%s", sig.Identity, sig.Domain))
+	}
+	return sig
+}
+
+// identityDomain returns the domain of an i tag, the part after the @
+func identityDomain(identity string) string {
+	_, domain, _ := strings.Cut(identity, "@")
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// permerror marks the signature as permanently failed
+func (s *Signature) permerror(reason string) *Signature {
+	s.Result = "permerror"
+	s.Reason = reason
+	return s
+}
+
+// verify checks the body hash, fetches the key of the selector with fetchKey and checks the signature
+func (s *Signature) verify(msg *Message, fetchKey func() (*dkim.DKIMKey, error)) {
+	var hashFunc crypto.Hash
+	var newHash func() hash.Hash
+	keyType, hashName, _ := strings.Cut(s.Algorithm, "-")
+	switch hashName {
+	case "sha256":
+		hashFunc, newHash = crypto.SHA256, sha256.New
+	case "sha1":
+		hashFunc, newHash = crypto.SHA1, sha1.New
+	default:
+		s.permerror(fmt.Sprintf("unsupported algorithm a=%s", s.Algorithm))
+		return
+	}
+	if keyType != "rsa" && keyType != "ed25519" {
+		s.permerror(fmt.Sprintf("unsupported algorithm a=%s", s.Algorithm))
+		return
+	}
+
+	headerAlgorithm, bodyAlgorithm, found := strings.Cut(s.Canonicalization, "/")
+	if !found {
+		bodyAlgorithm = "simple"
+	}
+	for _, algorithm := range []string{headerAlgorithm, bodyAlgorithm} {
+		if algorithm != "simple" && algorithm != "relaxed" {
+			s.permerror(fmt.Sprintf("unsupported canonicalization c=%s", s.Canonicalization))
+			return
+		}
+	}
+
+	if s.Expires > 0 && time.Now().Unix() > s.Expires {
+		s.permerror(fmt.Sprintf("signature expired at %s", time.Unix(s.Expires, 0).UTC().Format(time.RFC3339)))
+		return
+	}
+
+	// Check the body hash first, a mismatch means the body was modified in transit
+	body := canonicalBody(msg.Body, bodyAlgorithm == "relaxed")
+	if s.BodyLength >= 0 {
+		if s.BodyLength > int64(len(body)) {
+			s.permerror("body length l= exceeds the body")
+			return
+		}
+		body = body[:s.BodyLength]
+	}
+	bodyHash := newHash()
+	bodyHash.Write(body)
+	expected, err := base64.StdEncoding.DecodeString(s.tags["bh"])
+	if err != nil {
+		s.permerror("invalid base64 in bh tag")
+		return
+	}
+	if !bytes.Equal(bodyHash.Sum(nil), expected) {
+		s.Result = "fail"
+		s.Reason = "body hash does not match, the body was modified after signing"
+		return
+	}
+
+	key, err := fetchKey()
+	if err != nil {
+		s.Result = "temperror"
+		s.Reason = err.Error()
+		return
+	}
+	if key == nil {
+		s.permerror(fmt.Sprintf("no key record found at %s._domainkey.%s", s.Selector, s.Domain))
+		return
+	}
+	if key.Revoked() {
+		s.permerror("the key has been revoked")
+		return
+	}
+	if key.KeyType != keyType {
+		s.permerror(fmt.Sprintf("signature algorithm %s does not match key type k=%s", s.Algorithm, key.KeyType))
+		return
+	}
+	if len(key.HashAlgorithms) > 0 && !slices.ContainsFunc(key.HashAlgorithms, func(item string) bool { return strings.EqualFold(item, hashName) }) {
+		s.permerror(fmt.Sprintf("the key does not allow %s (h=%s)", hashName, strings.Join(key.HashAlgorithms, ":")))
+		return
+	}
+	if !slices.ContainsFunc(key.ServiceTypes, func(service string) bool { return service == "*" || strings.EqualFold(service, "email") }) {
+		s.permerror(fmt.Sprintf("the key is not meant for email (s=%s)", strings.Join(key.ServiceTypes, ":")))
+		return
+	}
+	// t=s forbids subdomains of the signing domain in the identity (RFC 6376 section 3.6.1)
+	if key.HasFlag("s") && identityDomain(s.Identity) != s.Domain {
+		s.permerror(fmt.Sprintf("the key requires the i= domain to be exactly d=%s (t=s), got i=%s", s.Domain, s.Identity))
+		return
+	}
+	if key.Decoded == nil {
+		s.permerror(fmt.Sprintf("unusable key: %s", key.KeyError))
+		return
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(s.tags["b"])
+	if err != nil {
+		s.permerror("invalid base64 in b tag")
+		return
+	}
+
+	headerHash := newHash()
+	headerHash.Write(s.signedHeaderData(msg, headerAlgorithm == "relaxed"))
+	digest := headerHash.Sum(nil)
+
+	switch public := key.Decoded.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(public, hashFunc, digest, signature)
+	case ed25519.PublicKey:
+		// RFC 8463 signs the hash of the header data
+		if !ed25519.Verify(public, digest, signature) {
+			err = fmt.Errorf("ed25519 verification failed")
+		}
+	}
+	if err != nil {
+		s.Result = "fail"
+		s.Reason = "signature does not verify, the signed headers were modified or the key does not match"
+		return
+	}
+	s.Result = "pass"
+}
+
+// fetchKey looks up the key record of the selector, returning nil when there is none
+func (s *Signature) fetchKey(nameserver string) (*dkim.DKIMKey, error) {
+	info, err := dkim.CheckDKIMSelectors(s.Domain, nameserver, dkim.SelectorOptions{Supplied: []string{s.Selector}})
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range info.SelectorResults {
+		if result.Error != "" {
+			return nil, fmt.Errorf("%s", result.Error)
+		}
+	}
+	for _, key := range info.Keys {
+		if key.Selector == s.Selector {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// signedHeaderData builds the canonicalized header data the signature covers (RFC 6376 section 5.4.2)
+func (s *Signature) signedHeaderData(msg *Message, relaxed bool) []byte {
+	var data bytes.Buffer
+
+	// Each listed name consumes the next instance of the field from the bottom of the header
+	used := make(map[string]int)
+	for _, name := range s.SignedHeaders {
+		instances := msg.All(name)
+		key := strings.ToLower(name)
+		if used[key] < len(instances) {
+			data.WriteString(canonicalHeader(instances[len(instances)-1-used[key]], relaxed))
+		}
+		used[key]++
+	}
+
+	// The signature header itself is included with an empty b tag and without the trailing CRLF
+	name, value, _ := strings.Cut(s.header.Raw, ":")
+	unsigned := Header{Name: s.header.Name, Raw: name + ":" + signatureBTag.ReplaceAllString(value, "$1$2")}
+	data.WriteString(strings.TrimSuffix(canonicalHeader(unsigned, relaxed), "\r\n"))
+	return data.Bytes()
+}
+
+// Print prints a human readable overview of the verification
+func (v *Verification) Print() {
+	fmt.Println("DKIM Verification:")
+	fmt.Printf("From: %s\n", v.From)
+
+	if len(v.Signatures) == 0 {
+		fmt.Println("❌ The message has no DKIM-Signature")
+	}
+	for _, sig := range v.Signatures {
+		icon := "✅"
+		if sig.Result != "pass" {
+			icon = "❌"
+		}
+		fmt.Printf("%s d=%s s=%s a=%s c=%s: %s\n", icon, sig.Domain, sig.Selector, sig.Algorithm, sig.Canonicalization, sig.Result)
+		if sig.Reason != "" {
+			fmt.Printf("    %s\n", sig.Reason)
+		}
+		if sig.Domain != "" {
+			fmt.Printf("    Aligned with %s: %t\n", v.FromDomain, sig.Aligned)
+		}
+	}
+
+	fmt.Println("\nDMARC:")
+	if v.DMARCError != "" {
+		fmt.Printf("No DMARC record for %s: %s\n", v.FromDomain, v.DMARCError)
+	} else {
+		fmt.Printf("Record: %s\n", v.DMARCRecord)
+	}
+	mode := "relaxed"
+	if v.Alignment == "s" {
+		mode = "strict"
+	}
+	fmt.Printf("DKIM alignment (%s): %s\n", mode, v.DMARCResult)
+}
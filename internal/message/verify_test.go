@@ -0,0 +1,113 @@
+package message
+
+import (
+	"strings"
+	"testing"
+
+	"check-maildomain/internal/dkim"
+)
+
+// rfc8463Message is the Ed25519 signed example message of RFC 8463 appendix A
+const rfc8463Message = `DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;
+ d=football.example.com; i=@football.example.com;
+ q=dns/txt; s=brisbane; t=1528637909; h=from : to :
+ subject : date : message-id : from : subject : date;
+ bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;
+ b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus
+ Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==
+From: Joe SixPack <joe@football.example.com>
+To: Suzie Q <suzie@shopping.example.net>
+Subject: Is dinner ready?
+Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)
+Message-ID: <20030712040037.46341.5F8J@football.example.com>
+
+Hi.
+
+We lost the game.  Are you hungry yet?
+
+Joe.
+`
+
+// rfc8463Key is the brisbane key record of RFC 8463 appendix A
+const rfc8463Key = "v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+
+func TestVerifySignature(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		key        string
+		wantResult string
+		wantReason string
+	}{
+		{
+			name:       "RFC 8463 example",
+			message:    rfc8463Message,
+			key:        rfc8463Key,
+			wantResult: "pass",
+		},
+		{
+			name:       "modified body",
+			message:    strings.Replace(rfc8463Message, "We lost", "We won", 1),
+			key:        rfc8463Key,
+			wantResult: "fail",
+			wantReason: "body hash does not match",
+		},
+		{
+			name:       "modified header",
+			message:    strings.Replace(rfc8463Message, "Is dinner ready?", "Is lunch ready?", 1),
+			key:        rfc8463Key,
+			wantResult: "fail",
+			wantReason: "signature does not verify",
+		},
+		{
+			name:       "identity outside the signing domain",
+			message:    strings.Replace(rfc8463Message, "i=@football.example.com", "i=@example.net", 1),
+			key:        rfc8463Key,
+			wantResult: "permerror",
+			wantReason: "is not within d=football.example.com",
+		},
+		{
+			name:       "key for another service",
+			message:    rfc8463Message,
+			key:        rfc8463Key + "; s=web",
+			wantResult: "permerror",
+			wantReason: "not meant for email",
+		},
+		{
+			name:       "strict key with a subdomain identity",
+			message:    strings.Replace(rfc8463Message, "i=@football.example.com", "i=joe@mail.football.example.com", 1),
+			key:        rfc8463Key + "; t=s",
+			wantResult: "permerror",
+			wantReason: "(t=s)",
+		},
+		{
+			name:       "strict key with the signing domain as identity",
+			message:    rfc8463Message,
+			key:        rfc8463Key + "; t=s",
+			wantResult: "pass",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := Parse(strings.NewReader(test.message))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			headers := msg.All("DKIM-Signature")
+			if len(headers) != 1 {
+				t.Fatalf("found %d DKIM-Signature headers, want 1", len(headers))
+			}
+
+			sig := parseSignature(headers[0])
+			if sig.Result == "" {
+				sig.verify(msg, func() (*dkim.DKIMKey, error) {
+					return dkim.ParseKey("brisbane", test.key), nil
+				})
+			}
+			if sig.Result != test.wantResult || !strings.Contains(sig.Reason, test.wantReason) {
+				t.Errorf("got %s (%s), want %s containing %q", sig.Result, sig.Reason, test.wantResult, test.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package mtasts
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Policy modes (RFC 8461 section 5)
+const (
+	ModeEnforce = "enforce" // Senders refuse delivery to MX hosts that fail authentication
+	ModeTesting = "testing" // Senders deliver anyway and only report failures (TLSRPT)
+	ModeNone    = "none"    // The domain withdraws its policy
+)
+
+// maxPolicySize limits how much of the policy file is read, RFC 8461 section 3.3 suggests 64 KiB
+const maxPolicySize = 64 * 1024
+
+// maxMaxAge is the largest max_age a policy may declare, about one year (RFC 8461 section 3.2)
+const maxMaxAge = 31557600
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,32}$`)
+
+// MTASTSInfo contains the MTA-STS TXT record and the policy fetched over HTTPS
+type MTASTSInfo struct {
+	Domain    string   // Policy domain
+	Records   []string // Every TXT record at _mta-sts starting with v=STSv1
+	Record    *Record  // The parsed TXT record, nil unless exactly one record is published
+	PolicyURL string   // URL the policy was fetched from
+	Policy    *Policy  // The parsed policy, nil when it could not be fetched
+	Error     string   // Why the policy could not be fetched
+}
+
+// Record represents a parsed MTA-STS TXT record
+type Record struct {
+	Raw    string   // The complete raw TXT record
+	ID     string   // id tag, changes whenever the policy changes
+	Errors []string // Problems that make senders ignore the record
+}
+
+// Policy represents a parsed MTA-STS policy file
+type Policy struct {
+	Raw         string   // The policy file as served
+	ContentType string   // Content-Type header of the response
+	Version     string   // Should be "STSv1"
+	Mode        string   // enforce, testing or none
+	MX          []string // MX patterns, e.g. "mail.example.com" or "*.example.net"
+	MaxAge      int      // Seconds senders cache the policy, -1 when missing or invalid
+	Errors      []string // Problems that make senders ignore the policy
+}
+
+// Valid reports whether the TXT record can be used by senders
+func (r *Record) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Valid reports whether the policy can be used by senders
+func (p *Policy) Valid() bool {
+	return len(p.Errors) == 0
+}
+
+// Matches reports whether the MX host matches one of the mx patterns of the policy. A wildcard pattern
+// "*.example.com" matches exactly one label in place of the asterisk (RFC 8461 section 4.1).
+func (p *Policy) Matches(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.MX {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforced reports whether a valid TXT record and policy are published and the policy is not in mode none
+func (i *MTASTSInfo) Enforced() bool {
+	return i.Record != nil && i.Record.Valid() && i.Policy != nil && i.Policy.Valid() && i.Policy.Mode != ModeNone
+}
+
+// Lookup queries the _mta-sts TXT record of the domain and, when a record is published, fetches and
+// parses the policy from https://mta-sts.<domain>/.well-known/mta-sts.txt
+func Lookup(domain string, nameserver string) (*MTASTSInfo, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn("_mta-sts."+domain), dns.TypeTXT)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query failed: %v", err)
+	}
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+	}
+
+	info := &MTASTSInfo{Domain: domain}
+	for _, a := range r.Answer {
+		if record, ok := a.(*dns.TXT); ok {
+			value := strings.Join(record.Txt, "")
+			if strings.HasPrefix(value, "v=STSv1") {
+				info.Records = append(info.Records, value)
+			}
+		}
+	}
+	if len(info.Records) == 0 {
+		return info, nil
+	}
+	if len(info.Records) == 1 {
+		info.Record = ParseRecord(info.Records[0])
+	}
+
+	info.PolicyURL = "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+	policy, err := FetchPolicy(info.PolicyURL)
+	if err != nil {
+		info.Error = err.Error()
+	} else {
+		info.Policy = policy
+	}
+	return info, nil
+}
+
+// ParseRecord parses an MTA-STS TXT record (RFC 8461 section 3.1)
+func ParseRecord(raw string) *Record {
+	record := &Record{Raw: raw}
+
+	fields := strings.Split(raw, ";")
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			record.Errors = append(record.Errors, fmt.Sprintf("field %q is not a key=value pair", field))
+			continue
+		}
+		if i == 0 && (key != "v" || value != "STSv1") {
+			record.Errors = append(record.Errors, "the record must start with v=STSv1")
+		}
+		if key == "id" {
+			record.ID = value
+			if !idPattern.MatchString(value) {
+				record.Errors = append(record.Errors, fmt.Sprintf("id %q must be 1 to 32 letters and digits", value))
+			}
+		}
+	}
+	if record.ID == "" {
+		record.Errors = append(record.Errors, "the id tag is missing")
+	}
+	return record
+}
+
+// FetchPolicy fetches the policy file over HTTPS. The certificate must be valid for the policy host and
+// redirects are not followed, since senders don't follow them either (RFC 8461 section 3.3).
+func FetchPolicy(url string) (*Policy, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching the policy failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if location := resp.Header.Get("Location"); location != "" {
+			return nil, fmt.Errorf("%s returned HTTP %d redirecting to %s, senders don't follow redirects", url, resp.StatusCode, location)
+		}
+		return nil, fmt.Errorf("%s returned HTTP %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading the policy failed: %v", err)
+	}
+	if len(body) > maxPolicySize {
+		return nil, fmt.Errorf("the policy is larger than %d bytes", maxPolicySize)
+	}
+
+	policy := ParsePolicy(string(body))
+	policy.ContentType = resp.Header.Get("Content-Type")
+	if mediaType, _, _ := strings.Cut(policy.ContentType, ";"); !strings.EqualFold(strings.TrimSpace(mediaType), "text/plain") {
+		policy.Errors = append(policy.Errors, fmt.Sprintf("the policy is served as %q instead of text/plain", policy.ContentType))
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses an MTA-STS policy file of "key: value" lines (RFC 8461 section 3.2)
+func ParsePolicy(raw string) *Policy {
+	policy := &Policy{Raw: raw, MaxAge: -1}
+
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			policy.Errors = append(policy.Errors, fmt.Sprintf("line %q is not a key: value pair", line))
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key != "mx" && seen[key] {
+			// Senders use the first occurrence
+			continue
+		}
+		seen[key] = true
+
+		switch key {
+		case "version":
+			policy.Version = value
+			if value != "STSv1" {
+				policy.Errors = append(policy.Errors, fmt.Sprintf("version %q must be STSv1", value))
+			}
+		case "mode":
+			policy.Mode = value
+			if value != ModeEnforce && value != ModeTesting && value != ModeNone {
+				policy.Errors = append(policy.Errors, fmt.Sprintf("mode %q must be enforce, testing or none", value))
+			}
+		case "mx":
+			policy.MX = append(policy.MX, strings.ToLower(strings.TrimSuffix(value, ".")))
+		case "max_age":
+			maxAge, err := strconv.Atoi(value)
+			if err != nil || maxAge < 0 || maxAge > maxMaxAge || len(value) > 10 {
+				policy.Errors = append(policy.Errors, fmt.Sprintf("max_age %q must be a number of seconds up to %d", value, maxMaxAge))
+				continue
+			}
+			policy.MaxAge = maxAge
+		}
+	}
+
+	if !seen["version"] {
+		policy.Errors = append(policy.Errors, "the version field is missing")
+	}
+	if !seen["mode"] {
+		policy.Errors = append(policy.Errors, "the mode field is missing")
+	}
+	if !seen["max_age"] {
+		policy.Errors = append(policy.Errors, "the max_age field is missing")
+	}
+	if len(policy.MX) == 0 && policy.Mode != ModeNone {
+		policy.Errors = append(policy.Errors, "the policy lists no mx patterns")
+	}
+	return policy
+}
@@ -20,6 +20,8 @@ type Record struct {
 type MXRecord struct {
 	Host     string
 	Priority uint16
+	TTL      uint32 // TTL of the MX records in seconds as configured at the authoritative nameservers
+	TTLKnown bool   // Whether the TTL could be read from an authoritative nameserver, never with the system resolver fallback
 	Records  []Record
 }
 
@@ -0,0 +1,69 @@
+package mx
+
+import (
+	"strings"
+)
+
+// SelfHosted is the provider name reported for MX hosts within the domain itself
+const SelfHosted = "Self-hosted"
+
+// Provider maps a well-known MX host domain to the email provider behind it
+type Provider struct {
+	Suffix string // Domain (or parent domain) of the provider's MX hosts
+	Name   string // Human readable provider name
+}
+
+// KnownProviders is a list of well-known MX host domains and their providers
+var KnownProviders = []Provider{
+	{"aspmx.l.google.com", "Google Workspace"},
+	{"googlemail.com", "Google Workspace"},
+	{"smtp.google.com", "Google Workspace"},
+	{"mail.protection.outlook.com", "Microsoft 365"},
+	{"mx.microsoft", "Microsoft 365"},
+	{"pphosted.com", "Proofpoint"},
+	{"ppe-hosted.com", "Proofpoint"},
+	{"mimecast.com", "Mimecast"},
+	{"mimecast.co.za", "Mimecast"},
+	{"zoho.com", "Zoho Mail"},
+	{"zoho.eu", "Zoho Mail"},
+	{"zoho.in", "Zoho Mail"},
+	{"messagingengine.com", "Fastmail"},
+	{"protonmail.ch", "Proton Mail"},
+	{"barracudanetworks.com", "Barracuda"},
+	{"iphmx.com", "Cisco Secure Email"},
+	{"mail.icloud.com", "iCloud Mail"},
+	{"mx.yandex.net", "Yandex Mail"},
+	{"mail.ovh.net", "OVHcloud"},
+	{"transip.email", "TransIP"},
+}
+
+// LookupProvider returns the provider name for an MX host of the domain. Hosts within the domain itself are
+// reported as SelfHosted.
+func LookupProvider(host string, domain string) (string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, provider := range KnownProviders {
+		if host == provider.Suffix || strings.HasSuffix(host, "."+provider.Suffix) {
+			return provider.Name, true
+		}
+	}
+
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if host != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+		return SelfHosted, true
+	}
+	return "", false
+}
+
+// Providers returns the deduplicated list of known inbound providers handling the MX records of the domain
+func Providers(records []MXRecord, domain string) []string {
+	seen := make(map[string]bool)
+	providers := []string{}
+	for _, record := range records {
+		name, ok := LookupProvider(record.Host, domain)
+		if ok && !seen[name] {
+			seen[name] = true
+			providers = append(providers, name)
+		}
+	}
+	return providers
+}
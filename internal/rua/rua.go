@@ -0,0 +1,154 @@
+package rua
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Feedback is a DMARC aggregate report as defined in RFC 7489 appendix C
+type Feedback struct {
+	XMLName         xml.Name        `xml:"feedback"`
+	ReportMetadata  ReportMetadata  `xml:"report_metadata"`
+	PolicyPublished PolicyPublished `xml:"policy_published"`
+	Records         []Record        `xml:"record"`
+}
+
+// ReportMetadata identifies the reporting organization and period
+type ReportMetadata struct {
+	OrgName   string    `xml:"org_name"`
+	Email     string    `xml:"email"`
+	ReportID  string    `xml:"report_id"`
+	DateRange DateRange `xml:"date_range"`
+}
+
+// DateRange is the reporting period in Unix timestamps
+type DateRange struct {
+	Begin int64 `xml:"begin"`
+	End   int64 `xml:"end"`
+}
+
+// PolicyPublished is the DMARC policy the receiver found for the domain
+type PolicyPublished struct {
+	Domain string `xml:"domain"`
+	ADKIM  string `xml:"adkim"`
+	ASPF   string `xml:"aspf"`
+	P      string `xml:"p"`
+	SP     string `xml:"sp"`
+	Pct    int    `xml:"pct"`
+}
+
+// Record contains the results for messages from a single source IP
+type Record struct {
+	Row         Row         `xml:"row"`
+	Identifiers Identifiers `xml:"identifiers"`
+	AuthResults AuthResults `xml:"auth_results"`
+}
+
+// Row contains the source IP, message count and evaluated policy
+type Row struct {
+	SourceIP        string          `xml:"source_ip"`
+	Count           int             `xml:"count"`
+	PolicyEvaluated PolicyEvaluated `xml:"policy_evaluated"`
+}
+
+// PolicyEvaluated contains the DMARC evaluation result of the receiver
+type PolicyEvaluated struct {
+	Disposition string `xml:"disposition"`
+	DKIM        string `xml:"dkim"`
+	SPF         string `xml:"spf"`
+}
+
+// Identifiers contains the domains the messages were sent as
+type Identifiers struct {
+	HeaderFrom   string `xml:"header_from"`
+	EnvelopeFrom string `xml:"envelope_from"`
+}
+
+// AuthResults contains the raw SPF and DKIM results
+type AuthResults struct {
+	DKIM []DKIMResult `xml:"dkim"`
+	SPF  []SPFResult  `xml:"spf"`
+}
+
+// DKIMResult is the result of a single DKIM signature
+type DKIMResult struct {
+	Domain   string `xml:"domain"`
+	Selector string `xml:"selector"`
+	Result   string `xml:"result"`
+}
+
+// SPFResult is the result of the SPF check
+type SPFResult struct {
+	Domain string `xml:"domain"`
+	Scope  string `xml:"scope"`
+	Result string `xml:"result"`
+}
+
+// ParseFile reads one or more aggregate reports from a plain XML, gzip or zip file
+func ParseFile(path string) ([]*Feedback, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading report failed: %v", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads one or more aggregate reports from plain XML, gzip or zip data
+func Parse(data []byte) ([]*Feedback, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("opening gzip report failed: %v", err)
+		}
+		defer reader.Close()
+
+		xmlData, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading gzip report failed: %v", err)
+		}
+		return Parse(xmlData)
+
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("opening zip report failed: %v", err)
+		}
+
+		var reports []*Feedback
+		for _, file := range archive.File {
+			if !strings.HasSuffix(strings.ToLower(file.Name), ".xml") {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("opening %s in zip report failed: %v", file.Name, err)
+			}
+			xmlData, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("reading %s in zip report failed: %v", file.Name, err)
+			}
+
+			parsed, err := Parse(xmlData)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file.Name, err)
+			}
+			reports = append(reports, parsed...)
+		}
+		return reports, nil
+
+	default:
+		feedback := &Feedback{}
+		if err := xml.Unmarshal(data, feedback); err != nil {
+			return nil, fmt.Errorf("parsing XML report failed: %v", err)
+		}
+		return []*Feedback{feedback}, nil
+	}
+}
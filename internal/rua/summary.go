@@ -0,0 +1,227 @@
+package rua
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"check-maildomain/internal/spf"
+)
+
+// SourceSummary aggregates the results of all messages from a single source IP
+type SourceSummary struct {
+	IP              string         // Source IP address
+	Messages        int            // Number of messages
+	DKIMPass        int            // Messages passing DMARC-aligned DKIM
+	DKIMFail        int            // Messages failing DMARC-aligned DKIM
+	SPFPass         int            // Messages passing DMARC-aligned SPF
+	SPFFail         int            // Messages failing DMARC-aligned SPF
+	Dispositions    map[string]int // Messages per applied disposition
+	HeaderFrom      []string       // Header From domains used by the source
+	AuthorizedBySPF bool           // Whether the current SPF record authorizes the source
+	SPFNetwork      string         // The SPF network that authorizes the source
+}
+
+// Signer aggregates the DKIM signatures seen for a single signing domain and selector
+type Signer struct {
+	Domain   string // DKIM d= domain
+	Selector string // DKIM s= selector
+	Messages int    // Number of messages signed
+	Pass     int    // Number of messages with a passing signature
+}
+
+// Summary aggregates one or more aggregate reports
+type Summary struct {
+	Domain      string          // Domain the reports are about
+	Reports     int             // Number of reports
+	Reporters   []string        // Organizations that sent reports
+	Messages    int             // Total number of messages
+	DMARCPass   int             // Messages passing DMARC (aligned SPF or DKIM)
+	DMARCFail   int             // Messages failing DMARC
+	Sources     []SourceSummary // Per source IP, most messages first
+	Signers     []Signer        // DKIM signing domains and selectors observed by receivers
+	DKIMResults map[string]int  // Messages per raw DKIM result
+	SPFResults  map[string]int  // Messages per raw SPF result
+	SPFChecked  bool            // Whether sources were cross-referenced against the SPF record
+}
+
+// Summarize aggregates the reports and cross-references the sources against the expanded SPF record, if given
+func Summarize(reports []*Feedback, expansion *spf.Expansion) *Summary {
+	summary := &Summary{
+		DKIMResults: make(map[string]int),
+		SPFResults:  make(map[string]int),
+		SPFChecked:  expansion != nil,
+	}
+
+	sources := make(map[string]*SourceSummary)
+	signers := make(map[string]*Signer)
+	reporters := make(map[string]bool)
+
+	for _, report := range reports {
+		summary.Reports++
+		if summary.Domain == "" {
+			summary.Domain = report.PolicyPublished.Domain
+		}
+		if report.ReportMetadata.OrgName != "" && !reporters[report.ReportMetadata.OrgName] {
+			reporters[report.ReportMetadata.OrgName] = true
+			summary.Reporters = append(summary.Reporters, report.ReportMetadata.OrgName)
+		}
+
+		for _, record := range report.Records {
+			count := record.Row.Count
+			summary.Messages += count
+
+			source, ok := sources[record.Row.SourceIP]
+			if !ok {
+				source = &SourceSummary{
+					IP:           record.Row.SourceIP,
+					Dispositions: make(map[string]int),
+				}
+				sources[record.Row.SourceIP] = source
+			}
+			source.Messages += count
+			source.Dispositions[record.Row.PolicyEvaluated.Disposition] += count
+			if record.Identifiers.HeaderFrom != "" && !slices.ContainsFunc(source.HeaderFrom, func(item string) bool { return strings.EqualFold(item, record.Identifiers.HeaderFrom) }) {
+				source.HeaderFrom = append(source.HeaderFrom, strings.ToLower(record.Identifiers.HeaderFrom))
+			}
+
+			dkimPass := strings.EqualFold(record.Row.PolicyEvaluated.DKIM, "pass")
+			spfPass := strings.EqualFold(record.Row.PolicyEvaluated.SPF, "pass")
+			if dkimPass {
+				source.DKIMPass += count
+			} else {
+				source.DKIMFail += count
+			}
+			if spfPass {
+				source.SPFPass += count
+			} else {
+				source.SPFFail += count
+			}
+			if dkimPass || spfPass {
+				summary.DMARCPass += count
+			} else {
+				summary.DMARCFail += count
+			}
+
+			for _, dkim := range record.AuthResults.DKIM {
+				summary.DKIMResults[strings.ToLower(dkim.Result)] += count
+				if dkim.Domain == "" {
+					continue
+				}
+				key := strings.ToLower(dkim.Domain + "/" + dkim.Selector)
+				signer, ok := signers[key]
+				if !ok {
+					signer = &Signer{Domain: strings.ToLower(dkim.Domain), Selector: dkim.Selector}
+					signers[key] = signer
+				}
+				signer.Messages += count
+				if strings.EqualFold(dkim.Result, "pass") {
+					signer.Pass += count
+				}
+			}
+			for _, result := range record.AuthResults.SPF {
+				summary.SPFResults[strings.ToLower(result.Result)] += count
+			}
+		}
+	}
+
+	for _, source := range sources {
+		if expansion != nil {
+			if ip := net.ParseIP(source.IP); ip != nil {
+				if network, ok := expansion.Contains(ip); ok {
+					source.AuthorizedBySPF = true
+					source.SPFNetwork = network.CIDR
+					if network.CIDR == "" {
+						source.SPFNetwork = network.Term
+					}
+				}
+			}
+		}
+		summary.Sources = append(summary.Sources, *source)
+	}
+	sort.Slice(summary.Sources, func(i, j int) bool {
+		if summary.Sources[i].Messages != summary.Sources[j].Messages {
+			return summary.Sources[i].Messages > summary.Sources[j].Messages
+		}
+		return summary.Sources[i].IP < summary.Sources[j].IP
+	})
+
+	for _, signer := range signers {
+		summary.Signers = append(summary.Signers, *signer)
+	}
+	sort.Slice(summary.Signers, func(i, j int) bool {
+		if summary.Signers[i].Messages != summary.Signers[j].Messages {
+			return summary.Signers[i].Messages > summary.Signers[j].Messages
+		}
+		return summary.Signers[i].Domain+summary.Signers[i].Selector < summary.Signers[j].Domain+summary.Signers[j].Selector
+	})
+
+	return summary
+}
+
+// Selectors returns the distinct DKIM selectors observed for the given signing domain
+func (s *Summary) Selectors(domain string) []string {
+	var selectors []string
+	for _, signer := range s.Signers {
+		if strings.EqualFold(signer.Domain, domain) && signer.Selector != "" && !slices.ContainsFunc(selectors, func(item string) bool { return strings.EqualFold(item, signer.Selector) }) {
+			selectors = append(selectors, signer.Selector)
+		}
+	}
+	return selectors
+}
+
+// Print prints a human readable overview of the summary
+func (s *Summary) Print() {
+	fmt.Println("DMARC Aggregate Reports:")
+	fmt.Printf("Domain: %s\n", s.Domain)
+	fmt.Printf("Reports: %d (%s)\n", s.Reports, strings.Join(s.Reporters, ", "))
+	fmt.Printf("Messages: %d, DMARC pass: %d, DMARC fail: %d\n", s.Messages, s.DMARCPass, s.DMARCFail)
+
+	fmt.Println("\nSources:")
+	for _, source := range s.Sources {
+		status := "✅"
+		if source.DKIMPass == 0 && source.SPFPass == 0 {
+			status = "❌"
+		} else if source.DKIMFail > 0 || source.SPFFail > 0 {
+			status = "⚠️"
+		}
+		fmt.Printf("%s %s: %d messages, DKIM %d/%d, SPF %d/%d\n", status, source.IP, source.Messages,
+			source.DKIMPass, source.Messages, source.SPFPass, source.Messages)
+		if len(source.HeaderFrom) > 0 {
+			fmt.Printf("    From: %s\n", strings.Join(source.HeaderFrom, ", "))
+		}
+		if s.SPFChecked {
+			if source.AuthorizedBySPF {
+				fmt.Printf("    Authorized by current SPF record (%s)\n", source.SPFNetwork)
+			} else {
+				fmt.Println("    Not authorized by current SPF record")
+			}
+		}
+	}
+
+	if len(s.Signers) > 0 {
+		fmt.Println("\nDKIM Signers:")
+		for _, signer := range s.Signers {
+			fmt.Printf("%s (selector %s): %d/%d passing\n", signer.Domain, signer.Selector, signer.Pass, signer.Messages)
+		}
+	}
+
+	fmt.Println("\nAuthentication Results:")
+	fmt.Printf("DKIM: %s\n", formatCounts(s.DKIMResults))
+	fmt.Printf("SPF: %s\n", formatCounts(s.SPFResults))
+}
+
+// formatCounts formats result counts as a sorted "result=count" list
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	var parts []string
+	for result, count := range counts {
+		parts = append(parts, fmt.Sprintf("%s=%d", result, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,187 @@
+package ruf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FailureReport is a DMARC failure report in the Abuse Reporting Format (RFC 5965, RFC 6591)
+type FailureReport struct {
+	File                  string // File the report was read from
+	FeedbackType          string // Feedback-Type, "auth-failure" for DMARC failure reports
+	UserAgent             string // User-Agent of the reporting receiver
+	ArrivalDate           string // Arrival-Date of the failing message
+	SourceIP              string // Source-IP of the failing message
+	ReportedDomain        string // Reported-Domain
+	OriginalMailFrom      string // Original-Mail-From (envelope sender)
+	OriginalRcptTo        string // Original-Rcpt-To
+	AuthFailure           string // Auth-Failure: dmarc, spf, dkim, ...
+	DeliveryResult        string // Delivery-Result: delivered, spam, policy, reject, ...
+	IdentityAlignment     string // Identity-Alignment: none, spf, dkim or "dkim, spf"
+	DKIMDomain            string // DKIM-Domain of the failing signature
+	DKIMSelector          string // DKIM-Selector of the failing signature
+	AuthenticationResults string // Authentication-Results as seen by the receiver
+	HeaderFrom            string // From header of the original message
+	Subject               string // Subject of the original message
+}
+
+// ParsePath reads failure reports from a single message file or a directory such as a maildir
+func ParsePath(path string) ([]*FailureReport, []error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if !info.IsDir() {
+		report, err := ParseFile(path)
+		if err != nil {
+			return nil, []error{err}
+		}
+		return []*FailureReport{report}, nil
+	}
+
+	var reports []*FailureReport
+	var errs []error
+	filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		// Skip maildir temporary files and hidden files
+		if entry.IsDir() {
+			if entry.Name() == "tmp" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		report, err := ParseFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	return reports, errs
+}
+
+// ParseFile reads a failure report from a single message file
+func ParseFile(path string) (*FailureReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	report, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	report.File = path
+	return report, nil
+}
+
+// Parse reads a failure report from a multipart/report message
+func Parse(r io.Reader) (*FailureReport, error) {
+	message, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading message failed: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing content type failed: %v", err)
+	}
+	if mediaType != "multipart/report" {
+		return nil, fmt.Errorf("not a report message: %s", mediaType)
+	}
+
+	report := &FailureReport{}
+	found := false
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading report part failed: %v", err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/feedback-report":
+			if err := parseFeedbackReport(part, report); err != nil {
+				return nil, err
+			}
+			found = true
+		case "message/rfc822", "text/rfc822-headers":
+			parseOriginalHeaders(part, report)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no message/feedback-report part found")
+	}
+	return report, nil
+}
+
+// parseFeedbackReport reads the machine readable fields of the report
+func parseFeedbackReport(r io.Reader, report *FailureReport) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading feedback report failed: %v", err)
+	}
+	// The fields use header syntax, terminate them so the header reader stops cleanly
+	data = append(bytes.TrimRight(data, "\r\n"), "\r\n\r\n"...)
+
+	fields, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(data))).ReadMIMEHeader()
+	if err != nil {
+		return fmt.Errorf("parsing feedback report failed: %v", err)
+	}
+
+	report.FeedbackType = strings.ToLower(fields.Get("Feedback-Type"))
+	report.UserAgent = fields.Get("User-Agent")
+	report.ArrivalDate = fields.Get("Arrival-Date")
+	report.SourceIP = fields.Get("Source-IP")
+	report.ReportedDomain = strings.ToLower(fields.Get("Reported-Domain"))
+	report.OriginalMailFrom = fields.Get("Original-Mail-From")
+	report.OriginalRcptTo = fields.Get("Original-Rcpt-To")
+	report.AuthFailure = strings.ToLower(fields.Get("Auth-Failure"))
+	report.DeliveryResult = strings.ToLower(fields.Get("Delivery-Result"))
+	report.IdentityAlignment = strings.ToLower(fields.Get("Identity-Alignment"))
+	report.DKIMDomain = strings.ToLower(fields.Get("DKIM-Domain"))
+	report.DKIMSelector = fields.Get("DKIM-Selector")
+	report.AuthenticationResults = fields.Get("Authentication-Results")
+	return nil
+}
+
+// parseOriginalHeaders reads the From and Subject of the original message, which may be redacted or truncated
+func parseOriginalHeaders(r io.Reader, report *FailureReport) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	if !bytes.Contains(data, []byte("\n\n")) && !bytes.Contains(data, []byte("\r\n\r\n")) {
+		data = append(data, "\r\n\r\n"...)
+	}
+
+	message, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	report.HeaderFrom = message.Header.Get("From")
+	report.Subject = message.Header.Get("Subject")
+}
@@ -0,0 +1,203 @@
+package ruf
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+)
+
+// SourceSummary aggregates the failure reports for a single source IP
+type SourceSummary struct {
+	IP          string         // Source IP address
+	Reports     int            // Number of failure reports
+	Causes      map[string]int // Reports per alignment cause
+	HeaderFrom  []string       // From domains used by the source
+	MailFrom    []string       // Envelope sender domains used by the source
+	DKIMSigners []string       // DKIM domains and selectors of failing signatures
+	Reporters   []string       // Receivers that reported the source
+}
+
+// Summary aggregates one or more failure reports
+type Summary struct {
+	Reports    int             // Number of failure reports
+	Domains    []string        // Reported domains
+	Sources    []SourceSummary // Per source IP, most reports first
+	Causes     map[string]int  // Reports per alignment cause
+	Failures   map[string]int  // Reports per Auth-Failure type
+	Deliveries map[string]int  // Reports per Delivery-Result
+}
+
+// Cause explains why the message failed DMARC based on the alignment and authentication results of the report
+func (r *FailureReport) Cause() string {
+	spfPass := resultOf(r.AuthenticationResults, "spf") == "pass"
+	dkimPass := resultOf(r.AuthenticationResults, "dkim") == "pass"
+	spfAligned := strings.Contains(r.IdentityAlignment, "spf")
+	dkimAligned := strings.Contains(r.IdentityAlignment, "dkim")
+
+	switch {
+	case r.AuthFailure != "" && r.AuthFailure != "dmarc":
+		return fmt.Sprintf("%s failure", r.AuthFailure)
+	case spfAligned || dkimAligned:
+		return "aligned identifier did not pass"
+	case spfPass && dkimPass:
+		return "SPF and DKIM pass but neither is aligned"
+	case spfPass:
+		return "SPF passes but is not aligned, no passing DKIM"
+	case dkimPass:
+		return "DKIM passes but is not aligned, SPF fails"
+	case r.AuthenticationResults == "" && r.IdentityAlignment == "":
+		return "unknown"
+	default:
+		return "SPF and DKIM both fail"
+	}
+}
+
+// Summarize aggregates the failure reports per source IP and alignment cause
+func Summarize(reports []*FailureReport) *Summary {
+	summary := &Summary{
+		Causes:     make(map[string]int),
+		Failures:   make(map[string]int),
+		Deliveries: make(map[string]int),
+	}
+
+	sources := make(map[string]*SourceSummary)
+	for _, report := range reports {
+		summary.Reports++
+		if report.ReportedDomain != "" {
+			summary.Domains = appendUnique(summary.Domains, report.ReportedDomain)
+		}
+
+		cause := report.Cause()
+		summary.Causes[cause]++
+		if report.AuthFailure != "" {
+			summary.Failures[report.AuthFailure]++
+		}
+		if report.DeliveryResult != "" {
+			summary.Deliveries[report.DeliveryResult]++
+		}
+
+		ip := report.SourceIP
+		if ip == "" {
+			ip = "unknown"
+		}
+		source, ok := sources[ip]
+		if !ok {
+			source = &SourceSummary{IP: ip, Causes: make(map[string]int)}
+			sources[ip] = source
+		}
+		source.Reports++
+		source.Causes[cause]++
+		if domain := addressDomain(report.HeaderFrom); domain != "" {
+			source.HeaderFrom = appendUnique(source.HeaderFrom, domain)
+		}
+		if domain := addressDomain(report.OriginalMailFrom); domain != "" {
+			source.MailFrom = appendUnique(source.MailFrom, domain)
+		}
+		if report.DKIMDomain != "" {
+			source.DKIMSigners = appendUnique(source.DKIMSigners, report.DKIMDomain+" ("+report.DKIMSelector+")")
+		}
+		if report.UserAgent != "" {
+			source.Reporters = appendUnique(source.Reporters, report.UserAgent)
+		}
+	}
+
+	for _, source := range sources {
+		summary.Sources = append(summary.Sources, *source)
+	}
+	sort.Slice(summary.Sources, func(i, j int) bool {
+		if summary.Sources[i].Reports != summary.Sources[j].Reports {
+			return summary.Sources[i].Reports > summary.Sources[j].Reports
+		}
+		return summary.Sources[i].IP < summary.Sources[j].IP
+	})
+
+	return summary
+}
+
+// Print prints a human readable overview of the summary
+func (s *Summary) Print() {
+	fmt.Println("DMARC Failure Reports:")
+	fmt.Printf("Domains: %s\n", strings.Join(s.Domains, ", "))
+	fmt.Printf("Reports: %d\n", s.Reports)
+
+	fmt.Println("\nSources:")
+	for _, source := range s.Sources {
+		fmt.Printf("❌ %s: %d reports\n", source.IP, source.Reports)
+		if len(source.HeaderFrom) > 0 {
+			fmt.Printf("    From: %s\n", strings.Join(source.HeaderFrom, ", "))
+		}
+		if len(source.MailFrom) > 0 {
+			fmt.Printf("    Envelope from: %s\n", strings.Join(source.MailFrom, ", "))
+		}
+		if len(source.DKIMSigners) > 0 {
+			fmt.Printf("    DKIM: %s\n", strings.Join(source.DKIMSigners, ", "))
+		}
+		for _, cause := range sortedKeys(source.Causes) {
+			fmt.Printf("    %s: %d\n", cause, source.Causes[cause])
+		}
+	}
+
+	fmt.Println("\nFailure Causes:")
+	for _, cause := range sortedKeys(s.Causes) {
+		fmt.Printf("%s: %d\n", cause, s.Causes[cause])
+	}
+	if len(s.Deliveries) > 0 {
+		fmt.Println("\nDelivery Results:")
+		for _, result := range sortedKeys(s.Deliveries) {
+			fmt.Printf("%s: %d\n", result, s.Deliveries[result])
+		}
+	}
+}
+
+// resultOf extracts the result of a method such as "spf" or "dkim" from an Authentication-Results value
+func resultOf(authResults string, method string) string {
+	for _, field := range strings.FieldsFunc(strings.ToLower(authResults), func(r rune) bool {
+		return r == ';' || r == ' ' || r == '\t'
+	}) {
+		if value, ok := strings.CutPrefix(field, method+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// addressDomain returns the lowercased domain of an email address, accepting bare and angle-bracketed addresses
+func addressDomain(address string) string {
+	if address == "" {
+		return ""
+	}
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+	address = strings.Trim(address, "<> ")
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return strings.ToLower(address[at+1:])
+	}
+	return ""
+}
+
+// appendUnique appends the value when it is not yet in the list
+func appendUnique(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// sortedKeys returns the keys of the counts, most frequent first
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,245 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"check-maildomain/internal/bimi"
+)
+
+// CheckBIMIRecord validates the BIMI assertion record at default._bimi. Receivers ignore an invalid record,
+// and more than one record, so no logo is displayed.
+func CheckBIMIRecord(info *EnhancedDomainInfo) {
+	if info.BIMI == nil {
+		return
+	}
+
+	bimi := info.BIMI
+	switch {
+	case len(bimi.Records) == 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      99,
+			Description: "BIMI record",
+			Status:      "info",
+			Message:     fmt.Sprintf("No BIMI record found at default._bimi.%s. BIMI lets supporting mailbox providers display the brand logo next to authenticated mail.", info.Domain),
+		})
+	case len(bimi.Records) > 1:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      99,
+			Description: "BIMI record",
+			Status:      "fail",
+			Message:     fmt.Sprintf("%d BIMI records are published at %s, receivers ignore all of them. Publish a single record.", len(bimi.Records), bimi.Name),
+		})
+	case !bimi.Record.Valid():
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      99,
+			Description: "BIMI record",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The BIMI record at %s is invalid: %s. Receivers ignore it.", bimi.Name, strings.Join(bimi.Record.Errors, "; ")),
+		})
+	case bimi.Record.Declined:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      99,
+			Description: "BIMI record",
+			Status:      "info",
+			Message:     fmt.Sprintf("The BIMI record at %s declines to publish a logo.", bimi.Name),
+		})
+	default:
+		message := fmt.Sprintf("A valid BIMI record is published at %s with logo %s", bimi.Name, bimi.Record.Logo)
+		if bimi.Record.Authority != "" {
+			message += fmt.Sprintf(" and evidence document %s.", bimi.Record.Authority)
+		} else {
+			message += ". Without a mark certificate (a tag) most mailbox providers, including Gmail and Apple Mail, don't display the logo."
+		}
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      99,
+			Description: "BIMI record",
+			Status:      "pass",
+			Message:     message,
+		})
+	}
+}
+
+// CheckBIMIDMARCPolicy verifies that a BIMI record is backed by an enforcing DMARC policy. Receivers only
+// display the logo when DMARC is at quarantine or reject for 100% of the mail, and the organizational
+// domain's subdomain policy isn't none.
+func CheckBIMIDMARCPolicy(info *EnhancedDomainInfo) {
+	if info.BIMI == nil || info.BIMI.Record == nil || info.BIMI.Record.Declined {
+		return
+	}
+
+	if info.DMARCRecord == nil {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      100,
+			Description: "BIMI DMARC requirement",
+			Status:      "fail",
+			Message:     "A BIMI record is published, but the domain has no DMARC record. Receivers only display the logo for domains with a DMARC policy of quarantine or reject.",
+		})
+		return
+	}
+
+	// A policy inherited from the organizational domain applies its sp to this domain
+	policy := info.DMARCPolicy.Policy
+	if info.DMARCRecord.InheritedFrom != "" {
+		policy = info.DMARCPolicy.SubdomainPolicy
+	}
+
+	var problems []string
+	if policy != "quarantine" && policy != "reject" {
+		problems = append(problems, fmt.Sprintf("the policy is %q instead of quarantine or reject", policy))
+	}
+	if info.DMARCPolicy.Percentage < 100 {
+		problems = append(problems, fmt.Sprintf("pct=%d applies the policy to part of the mail only", info.DMARCPolicy.Percentage))
+	}
+	if info.DMARCPolicy.SubdomainPolicy == "none" {
+		problems = append(problems, "the subdomain policy sp=none doesn't enforce")
+	}
+
+	if len(problems) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      100,
+			Description: "BIMI DMARC requirement",
+			Status:      "fail",
+			Message:     fmt.Sprintf("A BIMI record is published, but the DMARC policy doesn't enforce: %s. Receivers don't display the logo until DMARC is at quarantine or reject for all mail.", strings.Join(problems, "; ")),
+		})
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      100,
+		Description: "BIMI DMARC requirement",
+		Status:      "pass",
+		Message:     fmt.Sprintf("The BIMI record is backed by an enforcing DMARC policy (p=%s).", policy),
+	})
+}
+
+// CheckBIMILogo reports why the SVG logo of the BIMI record can't be displayed. Mailbox providers silently
+// skip a logo that can't be fetched or violates the SVG Tiny PS profile.
+func CheckBIMILogo(info *EnhancedDomainInfo) {
+	if info.BIMI == nil || info.BIMI.Logo == nil {
+		return
+	}
+
+	logo := info.BIMI.Logo
+	switch {
+	case logo.Error != "":
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      101,
+			Description: "BIMI logo",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The BIMI logo at %s can't be fetched: %s. Mailbox providers don't display a logo they can't retrieve.", logo.URL, logo.Error),
+		})
+	case len(logo.Violations) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      101,
+			Description: "BIMI logo",
+			Status:      "fail",
+			Message: fmt.Sprintf("The BIMI logo at %s is not a valid SVG Tiny PS document: %s. Mailbox providers silently skip invalid logos; convert it with an SVG Tiny PS converter.",
+				logo.URL, strings.Join(logo.Violations, "; ")),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      101,
+			Description: "BIMI logo",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The BIMI logo at %s is a valid SVG Tiny PS document (%d bytes).", logo.URL, logo.Size),
+		})
+	}
+}
+
+// markCertificateWarningDays is how long before expiry a mark certificate is reported
+const markCertificateWarningDays = 30
+
+// CheckBIMIMarkCertificate validates the mark certificate (VMC or CMC) of the BIMI record: the chain to a
+// known mark certificate authority, the validity period, the BIMI key usage and the domain. Mailbox providers
+// that require a mark certificate don't display the logo when it is invalid.
+func CheckBIMIMarkCertificate(info *EnhancedDomainInfo) {
+	if info.BIMI == nil || info.BIMI.Evidence == nil {
+		return
+	}
+
+	evidence := info.BIMI.Evidence
+	switch {
+	case evidence.Error != "":
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      102,
+			Description: "BIMI mark certificate",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The BIMI evidence document at %s can't be used: %s.", evidence.URL, evidence.Error),
+		})
+	case len(evidence.Problems) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      102,
+			Description: "BIMI mark certificate",
+			Status:      "fail",
+			Message: fmt.Sprintf("The %s at %s is invalid: %s. Mailbox providers don't display the logo without a valid mark certificate.",
+				evidence.Type, evidence.URL, strings.Join(evidence.Problems, "; ")),
+		})
+	case time.Until(evidence.NotAfter) < markCertificateWarningDays*24*time.Hour:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      102,
+			Description: "BIMI mark certificate",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The %s issued by %s expires on %s. Renew it in time, the logo disappears once it expires.", evidence.Type, markIssuer(evidence), evidence.NotAfter.Format("2006-01-02")),
+		})
+	case !evidence.ChainPinned:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      102,
+			Description: "BIMI mark certificate",
+			Status:      "info",
+			Message:     fmt.Sprintf("The %s issued by %s is valid until %s, but its certificate chain was not checked: %s.", evidence.Type, markIssuer(evidence), evidence.NotAfter.Format("2006-01-02"), evidence.ChainError),
+			Confidence:  ConfidenceMedium,
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      102,
+			Description: "BIMI mark certificate",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The %s issued by %s is valid until %s.", evidence.Type, evidence.KnownIssuer, evidence.NotAfter.Format("2006-01-02")),
+		})
+	}
+}
+
+// markIssuer names the issuer of the mark certificate: the pinned root the chain verifies to, or the issuer
+// of the certificate itself when the chain was not checked
+func markIssuer(evidence *bimi.Evidence) string {
+	if evidence.KnownIssuer != "" {
+		return evidence.KnownIssuer
+	}
+	return evidence.Issuer
+}
+
+// CheckBIMICertificateLogo compares the logo embedded in the mark certificate with the published logo.
+// Mailbox providers display the certified logo only when both are identical.
+func CheckBIMICertificateLogo(info *EnhancedDomainInfo) {
+	if info.BIMI == nil || info.BIMI.Evidence == nil || info.BIMI.Evidence.LogoHash == "" {
+		return
+	}
+
+	evidence := info.BIMI.Evidence
+	switch {
+	case info.BIMI.Logo == nil || info.BIMI.Logo.Data == nil:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      103,
+			Description: "BIMI certificate logo",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The mark certificate embeds a logo (SHA-256 %s), but the published logo could not be fetched to compare it.", evidence.LogoHash),
+		})
+	case !evidence.LogoMatches:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      103,
+			Description: "BIMI certificate logo",
+			Status:      "fail",
+			Message: fmt.Sprintf("The logo at %s differs from the logo embedded in the mark certificate (SHA-256 %s). Mailbox providers don't display a logo that doesn't match the certificate; publish the exact SVG that was certified.",
+				info.BIMI.Logo.URL, evidence.LogoHash),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      103,
+			Description: "BIMI certificate logo",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The published logo matches the logo embedded in the mark certificate (SHA-256 %s).", evidence.LogoHash),
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckCAARecords lists the certificate authorities that the CAA records of the domain allow to issue
+// certificates
+func CheckCAARecords(info *EnhancedDomainInfo) {
+	if info.CAA == nil || info.CAA.Error != "" {
+		return
+	}
+
+	if info.CAA.FoundAt == "" {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      76,
+			Description: "CAA records",
+			Status:      "info",
+			Message:     "No CAA records found, so any certificate authority may issue certificates for this domain.",
+		})
+		return
+	}
+
+	location := ""
+	if info.CAA.FoundAt != info.CAA.Name {
+		location = fmt.Sprintf(" (inherited from %s)", info.CAA.FoundAt)
+	}
+	var message string
+	issuers := info.CAA.Issuers("issue")
+	switch {
+	case !info.CAA.Has("issue"):
+		message = fmt.Sprintf("The CAA records%s contain no issue property, so any certificate authority may issue certificates for this domain.", location)
+	case len(issuers) == 0:
+		message = fmt.Sprintf("The CAA records%s forbid every certificate authority to issue certificates for this domain.", location)
+	default:
+		message = fmt.Sprintf("The CAA records%s allow the following certificate authorities to issue certificates: %s.", location, strings.Join(issuers, ", "))
+	}
+	if info.CAA.Has("issuewild") {
+		// issuewild replaces issue for wildcard certificates
+		if wildcardIssuers := info.CAA.Issuers("issuewild"); len(wildcardIssuers) == 0 {
+			message += " Wildcard certificates are forbidden."
+		} else {
+			message += fmt.Sprintf(" Wildcard certificates may be issued by: %s.", strings.Join(wildcardIssuers, ", "))
+		}
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      76,
+		Description: "CAA records",
+		Status:      "info",
+		Message:     message,
+	})
+}
+
+// CheckMXCAA warns when MTA-STS or DANE is deployed but no CAA records constrain who can issue certificates
+// for the MX hosts. Senders then trust the MX certificate, and any certificate authority can issue one.
+func CheckMXCAA(info *EnhancedDomainInfo) {
+	if len(info.MXTransportSecurity) == 0 || len(info.MXCAA) == 0 {
+		// The MX certificates are not authenticated by senders
+		return
+	}
+
+	var unconstrained []string
+	for _, result := range info.MXCAA {
+		// Only issue properties restrict certificates for the MX hostname itself
+		if result.Error == "" && !result.Has("issue") {
+			unconstrained = append(unconstrained, result.Name)
+		}
+	}
+
+	if len(unconstrained) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      77,
+			Description: "CAA for MX hosts",
+			Status:      "warn",
+			Message: fmt.Sprintf("The domain deploys %s, but no CAA records constrain who can issue certificates for the following MX hosts: %s. Publish CAA records naming the certificate authorities used for the MX certificates.",
+				strings.Join(info.MXTransportSecurity, " and "), strings.Join(unconstrained, ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      77,
+			Description: "CAA for MX hosts",
+			Status:      "pass",
+			Message:     "CAA records constrain who can issue certificates for all MX hosts.",
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"check-maildomain/internal/dane"
+)
+
+// CheckDANEExists reports which MX hosts publish TLSA records for SMTP. Senders only use TLSA records
+// they can authenticate with DNSSEC, so records the resolver didn't validate are flagged as well.
+func CheckDANEExists(info *EnhancedDomainInfo) {
+	if len(info.MXTLSA) == 0 {
+		return
+	}
+
+	var with, without, unauthenticated []string
+	for _, tlsa := range info.MXTLSA {
+		switch {
+		case tlsa.Error != "":
+			continue
+		case len(tlsa.Records) == 0:
+			without = append(without, tlsa.Host)
+		default:
+			with = append(with, tlsa.Host)
+			if !tlsa.Authenticated {
+				unauthenticated = append(unauthenticated, tlsa.Host)
+			}
+		}
+	}
+
+	switch {
+	case len(with) == 0:
+		if len(without) == 0 {
+			// Every lookup failed
+			return
+		}
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      95,
+			Description: "DANE TLSA records",
+			Status:      "info",
+			Message:     "None of the MX hosts publish TLSA records at _25._tcp. DANE (RFC 7672) lets senders authenticate the MX certificates through DNSSEC and prevents STARTTLS downgrades.",
+		})
+	case len(unauthenticated) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      95,
+			Description: "DANE TLSA records",
+			Status:      "warn",
+			Message: fmt.Sprintf("The TLSA records of the following MX hosts were not authenticated with DNSSEC by the resolver: %s. Senders ignore TLSA records from unsigned zones; sign the zone of the MX host, or check with a validating resolver as -nameserver.",
+				strings.Join(unauthenticated, ", ")),
+			Confidence: ConfidenceMedium,
+		})
+	case len(without) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      95,
+			Description: "DANE TLSA records",
+			Status:      "warn",
+			Message: fmt.Sprintf("Only some MX hosts publish TLSA records (%s); the following don't: %s. Senders can be steered to the hosts without DANE, publish TLSA records for every MX host.",
+				strings.Join(with, ", "), strings.Join(without, ", ")),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      95,
+			Description: "DANE TLSA records",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All MX hosts publish DNSSEC-authenticated TLSA records (%s).", strings.Join(with, ", ")),
+		})
+	}
+}
+
+// CheckDANEParameters verifies that the TLSA records use parameters SMTP senders support: the DANE-TA(2)
+// and DANE-EE(3) usages with known selectors and matching types and correctly sized digests. A host whose
+// records are all unusable is treated by senders as if it had a broken certificate.
+func CheckDANEParameters(info *EnhancedDomainInfo) {
+	var broken, partly []string
+	checked := false
+	for _, tlsa := range info.MXTLSA {
+		if len(tlsa.Records) == 0 {
+			continue
+		}
+		checked = true
+
+		var problems []string
+		for _, record := range tlsa.Records {
+			if !record.Usable() {
+				problems = append(problems, fmt.Sprintf("%s: %s", record, record.Problem))
+			}
+		}
+		if len(problems) == 0 {
+			continue
+		}
+		detail := fmt.Sprintf("%s (%s)", tlsa.Host, strings.Join(problems, "; "))
+		if len(tlsa.UsableRecords()) == 0 {
+			broken = append(broken, detail)
+		} else {
+			partly = append(partly, detail)
+		}
+	}
+
+	if !checked {
+		return
+	}
+
+	switch {
+	case len(broken) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      96,
+			Description: "DANE TLSA parameters",
+			Status:      "fail",
+			Message: fmt.Sprintf("The following MX hosts only publish TLSA records SMTP senders can't use: %s. DANE senders then fail to authenticate the host and defer the mail; publish DANE-EE(3) SPKI(1) SHA2-256(1) records.",
+				strings.Join(broken, ", ")),
+		})
+	case len(partly) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      96,
+			Description: "DANE TLSA parameters",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following MX hosts publish TLSA records senders ignore next to usable ones: %s.", strings.Join(partly, ", ")),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      96,
+			Description: "DANE TLSA parameters",
+			Status:      "pass",
+			Message:     "All TLSA records use the DANE-TA(2) or DANE-EE(3) usage with valid parameters.",
+		})
+	}
+}
+
+// CheckDANEMatchingTypes warns on TLSA records publishing the full certificate or key, matching type
+// Full(0), which RFC 7672 section 3.1.3 doesn't recommend: the records are large and break on every renewal.
+func CheckDANEMatchingTypes(info *EnhancedDomainInfo) {
+	var full []string
+	checked := false
+	for _, tlsa := range info.MXTLSA {
+		for _, record := range tlsa.Records {
+			if !record.Usable() {
+				// Reported by the parameters rule
+				continue
+			}
+			checked = true
+			if record.MatchingType == dane.MatchingFull {
+				full = append(full, fmt.Sprintf("%s (%s)", tlsa.Host, record))
+			}
+		}
+	}
+
+	if !checked {
+		return
+	}
+
+	if len(full) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      97,
+			Description: "DANE matching types",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following TLSA records publish the full certificate or key instead of a digest: %s. Use the SHA2-256(1) matching type, which keeps the DNS responses small.", strings.Join(full, ", ")),
+		})
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      97,
+		Description: "DANE matching types",
+		Status:      "pass",
+		Message:     "All usable TLSA records publish a SHA-256 or SHA-512 digest.",
+	})
+}
+
+// CheckDANEVerification reports whether the certificates the MX hosts present on port 25 match their TLSA
+// records (only with -smtp-probe). Senders enforcing DANE refuse to deliver to a host whose published
+// TLSA records don't match, so a stale record after a certificate renewal blocks inbound mail.
+func CheckDANEVerification(info *EnhancedDomainInfo) {
+	var mismatched, unverified, verified []string
+	for _, tlsa := range info.MXTLSA {
+		for _, verification := range tlsa.Verifications {
+			target := fmt.Sprintf("%s (%s)", tlsa.Host, verification.IP)
+			switch {
+			case verification.Matched != "":
+				verified = append(verified, target)
+			case verification.Connected:
+				mismatched = append(mismatched, fmt.Sprintf("%s: %s", target, verification.Error))
+			default:
+				unverified = append(unverified, fmt.Sprintf("%s: %s", target, verification.Error))
+			}
+		}
+	}
+
+	if len(mismatched) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      98,
+			Description: "DANE certificate verification",
+			Status:      "fail",
+			Message: fmt.Sprintf("The following MX hosts don't present a certificate matching their TLSA records: %s. Senders enforcing DANE refuse to deliver to them; publish TLSA records for the current certificate, and for the next one before rolling it over.",
+				strings.Join(mismatched, "; ")),
+		})
+		return
+	}
+
+	if len(unverified) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      98,
+			Description: "DANE certificate verification",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The TLSA records of the following MX hosts could not be verified: %s. Outbound port 25 may be blocked from this network.", strings.Join(unverified, "; ")),
+			Confidence:  ConfidenceLow,
+		})
+		return
+	}
+
+	if len(verified) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      98,
+			Description: "DANE certificate verification",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The certificates presented by the MX hosts match their TLSA records: %s.", strings.Join(verified, ", ")),
+		})
+	}
+}
@@ -2,7 +2,10 @@ package rules
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // CheckDKIMExists attempts to verify if DKIM records might exist for the domain
@@ -13,6 +16,7 @@ func CheckDKIMExists(info *EnhancedDomainInfo) {
 			RuleID:      7,
 			Description: "DKIM record existence",
 			Status:      "info",
+			Confidence:  ConfidenceLow,
 			Message:     "DKIM status could not be determined. DKIM uses selectors that vary by email provider. Ensure DKIM is configured with your email service provider.",
 		})
 		return
@@ -21,11 +25,19 @@ func CheckDKIMExists(info *EnhancedDomainInfo) {
 	if info.DKIMInfo.HasDomainKey && info.DKIMInfo.ResponseCode == "NOERROR" {
 		// _domainkey record exists
 		if info.DKIMInfo.HasSelectors {
-			// Found actual DKIM selectors
+			// Found actual DKIM selectors, certainly DKIM when one of them publishes a valid key record
+			confidence := ConfidenceMedium
+			for _, key := range info.DKIMInfo.Keys {
+				if len(key.SyntaxErrors) == 0 {
+					confidence = ConfidenceHigh
+					break
+				}
+			}
 			info.RuleResults = append(info.RuleResults, RuleResult{
 				RuleID:      7,
 				Description: "DKIM record existence",
 				Status:      "pass",
+				Confidence:  confidence,
 				Message:     fmt.Sprintf("DKIM records found for this domain with selectors: %s", strings.Join(info.DKIMInfo.Selectors, ", ")),
 			})
 		} else {
@@ -34,6 +46,7 @@ func CheckDKIMExists(info *EnhancedDomainInfo) {
 				RuleID:      7,
 				Description: "DKIM record existence",
 				Status:      "warn",
+				Confidence:  ConfidenceLow,
 				Message:     "Domain has _domainkey record but no common selectors were found. Ensure DKIM is properly configured with your email provider.",
 			})
 		}
@@ -43,7 +56,463 @@ func CheckDKIMExists(info *EnhancedDomainInfo) {
 			RuleID:      7,
 			Description: "DKIM record existence",
 			Status:      "fail",
+			Confidence:  ConfidenceLow,
 			Message:     "No DKIM _domainkey record was found. DKIM helps prevent email spoofing. Configure DKIM with your email service provider.",
 		})
 	}
 }
+
+// CheckDKIMKeyLength verifies that the RSA keys of the discovered selectors are at least 2048 bits
+func CheckDKIMKeyLength(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	var weak, short, strong []string
+	for _, key := range info.DKIMInfo.Keys {
+		if key.KeyType != "rsa" || key.KeyBits == 0 {
+			// Revoked, non-RSA or undecodable keys are reported by other rules
+			continue
+		}
+		entry := fmt.Sprintf("%s (%d bits)", key.Selector, key.KeyBits)
+		switch {
+		case key.KeyBits < 1024:
+			weak = append(weak, entry)
+		case key.KeyBits < 2048:
+			short = append(short, entry)
+		default:
+			strong = append(strong, entry)
+		}
+	}
+
+	switch {
+	case len(weak) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      42,
+			Description: "DKIM key length",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following selectors publish RSA keys shorter than 1024 bits, which can be factored and are rejected by most receivers: %s. Generate a new key of at least 2048 bits.", strings.Join(weak, ", ")),
+		})
+	case len(short) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      42,
+			Description: "DKIM key length",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following selectors publish RSA keys shorter than 2048 bits: %s. Rotate to a 2048-bit key.", strings.Join(short, ", ")),
+		})
+	case len(strong) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      42,
+			Description: "DKIM key length",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All RSA keys are at least 2048 bits: %s.", strings.Join(strong, ", ")),
+		})
+	}
+}
+
+// CheckDKIMKeyAlgorithms reports Ed25519 keys, which not every receiver can verify yet. Invalid
+// keys are left to the syntax rule, so only the algorithm mix of the valid keys is described
+func CheckDKIMKeyAlgorithms(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	var ed25519Keys, others []string
+	for _, key := range info.DKIMInfo.Keys {
+		if key.PublicKey == "" || key.KeyError != "" || len(key.SyntaxErrors) > 0 {
+			continue
+		}
+		if key.KeyType == "ed25519" {
+			ed25519Keys = append(ed25519Keys, key.Selector)
+		} else {
+			others = append(others, fmt.Sprintf("%s (%s)", key.Selector, key.KeyType))
+		}
+	}
+
+	switch {
+	case len(ed25519Keys) > 0 && len(others) == 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      43,
+			Description: "DKIM key algorithms",
+			Status:      "info",
+			Message:     fmt.Sprintf("Only Ed25519 keys were found (%s). Some receivers can't verify Ed25519 signatures yet; sign with an additional RSA key so those receivers still see a valid signature.", strings.Join(ed25519Keys, ", ")),
+		})
+	case len(ed25519Keys) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      43,
+			Description: "DKIM key algorithms",
+			Status:      "pass",
+			Message:     fmt.Sprintf("Ed25519 keys (%s) are published alongside other keys (%s), so receivers without Ed25519 support can verify the other signature.", strings.Join(ed25519Keys, ", "), strings.Join(others, ", ")),
+		})
+	}
+}
+
+// CheckDKIMRevokedKeys reports selectors that are still published with a revoked (empty) key
+func CheckDKIMRevokedKeys(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	var revoked []string
+	for _, key := range info.DKIMInfo.Keys {
+		if key.Revoked() {
+			revoked = append(revoked, key.Selector)
+		}
+	}
+	if len(revoked) == 0 {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      44,
+		Description: "DKIM revoked keys",
+		Status:      "warn",
+		Message:     fmt.Sprintf("The following selectors publish a revoked key (empty p=): %s. Any sender still signing with these selectors produces failing signatures; make sure no service uses them, then remove the records once old signatures no longer need to verify.", strings.Join(revoked, ", ")),
+	})
+}
+
+// CheckDKIMTestingMode warns about selectors still in testing mode (t=y)
+func CheckDKIMTestingMode(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	var testing []string
+	for _, key := range info.DKIMInfo.Keys {
+		if key.HasFlag("y") && !key.Revoked() {
+			testing = append(testing, key.Selector)
+		}
+	}
+	if len(testing) == 0 {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      45,
+		Description: "DKIM testing mode",
+		Status:      "warn",
+		Message:     fmt.Sprintf("The following selectors are in testing mode (t=y): %s. Some receivers treat signatures made in testing mode as unsigned, so DMARC can't rely on them. Remove the y flag once signing works.", strings.Join(testing, ", ")),
+	})
+}
+
+// CheckDKIMSuppliedSelectors verifies that every selector supplied by the user publishes a key record
+func CheckDKIMSuppliedSelectors(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil || !info.DKIMInfo.CustomSelectors {
+		return
+	}
+
+	var found, missing, unchecked []string
+	for _, result := range info.DKIMInfo.SelectorResults {
+		if !result.Supplied {
+			continue
+		}
+		switch {
+		case result.Error != "":
+			unchecked = append(unchecked, fmt.Sprintf("%s (%s)", result.Selector, result.Error))
+		case result.Found:
+			found = append(found, result.Selector)
+		default:
+			missing = append(missing, fmt.Sprintf("%s (%s)", result.Selector, result.ResponseCode))
+		}
+	}
+
+	if len(missing) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      46,
+			Description: "DKIM supplied selectors",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following selectors have no key record at <selector>._domainkey.%s: %s. Signatures made with these selectors can't be verified.", info.Domain, strings.Join(missing, ", ")),
+		})
+	} else if len(unchecked) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      46,
+			Description: "DKIM supplied selectors",
+			Status:      "info",
+			Message:     fmt.Sprintf("The following selectors could not be checked: %s.", strings.Join(unchecked, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      46,
+			Description: "DKIM supplied selectors",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All supplied selectors publish a key record: %s.", strings.Join(found, ", ")),
+		})
+	}
+}
+
+// CheckDKIMDelegatedSelectors reports selectors delegated through a CNAME to a signing provider, and delegations
+// whose target publishes no key
+func CheckDKIMDelegatedSelectors(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	keys := make(map[string]bool)
+	for _, key := range info.DKIMInfo.Keys {
+		keys[key.Selector] = true
+	}
+
+	var delegated, dangling []string
+	for _, result := range info.DKIMInfo.SelectorResults {
+		if len(result.CNAMEChain) == 0 {
+			continue
+		}
+		target := result.CNAMEChain[len(result.CNAMEChain)-1]
+		entry := fmt.Sprintf("%s -> %s", result.Selector, target)
+		if result.Provider != "" {
+			entry += fmt.Sprintf(" (%s)", result.Provider)
+		}
+		if keys[result.Selector] {
+			delegated = append(delegated, entry)
+		} else {
+			dangling = append(dangling, entry)
+		}
+	}
+
+	if len(dangling) > 0 {
+		message := fmt.Sprintf("The following selectors are delegated through a CNAME, but the target publishes no key: %s. Finish the setup at the provider or remove the CNAME; a dangling delegation can be taken over by whoever registers the target.", strings.Join(dangling, "; "))
+		if len(delegated) > 0 {
+			message += fmt.Sprintf(" Working delegations: %s.", strings.Join(delegated, "; "))
+		}
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      47,
+			Description: "DKIM delegated selectors",
+			Status:      "warn",
+			Message:     message,
+		})
+	} else if len(delegated) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      47,
+			Description: "DKIM delegated selectors",
+			Status:      "info",
+			Message:     fmt.Sprintf("The following selectors are delegated through a CNAME, so the provider manages and rotates the key: %s.", strings.Join(delegated, "; ")),
+		})
+	}
+}
+
+// CheckDKIMSharedKeys warns when multiple selectors publish the same public key, which defeats key rotation
+func CheckDKIMSharedKeys(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	var order []string
+	selectors := make(map[string][]string)
+	for _, key := range info.DKIMInfo.Keys {
+		if key.PublicKey == "" {
+			continue
+		}
+		if _, ok := selectors[key.PublicKey]; !ok {
+			order = append(order, key.PublicKey)
+		}
+		selectors[key.PublicKey] = append(selectors[key.PublicKey], key.Selector)
+	}
+
+	var shared []string
+	for _, publicKey := range order {
+		if len(selectors[publicKey]) > 1 {
+			shared = append(shared, strings.Join(selectors[publicKey], ", "))
+		}
+	}
+	if len(shared) == 0 {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      48,
+		Description: "DKIM shared keys",
+		Status:      "warn",
+		Message:     fmt.Sprintf("The following selectors publish identical public keys: %s. Rotating to another selector doesn't replace the key, and a compromise of one key affects all of them. Generate a separate key pair per selector.", strings.Join(shared, "; ")),
+	})
+}
+
+// CheckDKIMSyntax reports the syntax problems of every selector record separately
+func CheckDKIMSyntax(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil || len(info.DKIMInfo.Keys) == 0 {
+		return
+	}
+
+	var valid []string
+	for _, key := range info.DKIMInfo.Keys {
+		switch {
+		case len(key.SyntaxErrors) > 0:
+			info.RuleResults = append(info.RuleResults, RuleResult{
+				RuleID:      49,
+				Description: "DKIM record syntax",
+				Status:      "fail",
+				Message:     fmt.Sprintf("The key record of selector %s is invalid: %s. Receivers may fail to verify signatures made with this selector.", key.Selector, strings.Join(key.SyntaxErrors, "; ")),
+			})
+		case len(key.SyntaxWarnings) > 0:
+			info.RuleResults = append(info.RuleResults, RuleResult{
+				RuleID:      49,
+				Description: "DKIM record syntax",
+				Status:      "warn",
+				Message:     fmt.Sprintf("The key record of selector %s contains values that verifiers ignore: %s. Remove them, or check for a typo.", key.Selector, strings.Join(key.SyntaxWarnings, "; ")),
+			})
+		default:
+			valid = append(valid, key.Selector)
+		}
+	}
+
+	if len(valid) == len(info.DKIMInfo.Keys) {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      49,
+			Description: "DKIM record syntax",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All DKIM key records are valid: %s.", strings.Join(valid, ", ")),
+		})
+	}
+}
+
+// CheckDKIMHashAlgorithms flags selectors that allow or require the weak sha1 hash algorithm (h=sha1)
+func CheckDKIMHashAlgorithms(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	var sha1Only, sha1Listed []string
+	for _, key := range info.DKIMInfo.Keys {
+		if key.Revoked() || len(key.HashAlgorithms) == 0 {
+			// Without h= all hash algorithms are acceptable and the signer chooses
+			continue
+		}
+		hasSHA1, hasOther := false, false
+		for _, hash := range key.HashAlgorithms {
+			if strings.EqualFold(hash, "sha1") {
+				hasSHA1 = true
+			} else {
+				hasOther = true
+			}
+		}
+		if hasSHA1 && !hasOther {
+			sha1Only = append(sha1Only, key.Selector)
+		} else if hasSHA1 {
+			sha1Listed = append(sha1Listed, key.Selector)
+		}
+	}
+
+	if len(sha1Only) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      50,
+			Description: "DKIM hash algorithms",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following selectors restrict signatures to sha1 (h=sha1): %s. Several receivers refuse RSA-SHA1 signatures; set h=sha256 or remove the h tag and sign with rsa-sha256.", strings.Join(sha1Only, ", ")),
+		})
+	} else if len(sha1Listed) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      50,
+			Description: "DKIM hash algorithms",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following selectors still accept sha1 signatures: %s. Remove sha1 from the h tag and make sure the signer uses rsa-sha256.", strings.Join(sha1Listed, ", ")),
+		})
+	}
+}
+
+// CheckDKIMTCPOnly warns about key records that are only retrievable over TCP, which some receivers don't fall back to
+func CheckDKIMTCPOnly(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil {
+		return
+	}
+
+	var tcpOnly []string
+	for _, result := range info.DKIMInfo.SelectorResults {
+		if result.Found && result.TCPOnly {
+			tcpOnly = append(tcpOnly, result.Selector)
+		}
+	}
+	if len(tcpOnly) == 0 {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      51,
+		Description: "DKIM key retrievable over UDP",
+		Status:      "warn",
+		Message:     fmt.Sprintf("The key records of the following selectors were truncated over UDP, even with a larger EDNS buffer, and could only be retrieved over TCP: %s. Receivers or firewalls that don't retry over TCP can't verify signatures; reduce the response size (e.g. remove other TXT records at the name or use a 2048-bit or Ed25519 key).", strings.Join(tcpOnly, ", ")),
+	})
+}
+
+// minSelectorYear is the oldest year a date stamp in a selector name can plausibly be, the year DomainKeys,
+// the predecessor of DKIM, was published
+const minSelectorYear = 2004
+
+var (
+	// selectorMonth matches a year and month stamp in a selector name such as s202301, dkim-2023-06 or 20230615
+	selectorMonth = regexp.MustCompile(`(?:^|[^0-9])((?:19|20)[0-9]{2})[-_]?(0[1-9]|1[0-2])(?:[-_]?(?:0[1-9]|[12][0-9]|3[01]))?(?:[^0-9]|$)`)
+	// selectorYear matches a year stamp set apart by separators, such as 2019 or dkim-2019, but not the key
+	// size in rsa2048
+	selectorYear = regexp.MustCompile(`(?:^|[-_.])((?:19|20)[0-9]{2})(?:[-_.]|$)`)
+)
+
+// parseSelectorDate returns the end of the period (month, or year without a month) stamped in a selector name, if
+// any. Years before DKIM existed and periods starting after now are not date stamps.
+func parseSelectorDate(selector string, now time.Time) (time.Time, string, bool) {
+	for _, match := range selectorMonth.FindAllStringSubmatch(selector, -1) {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		if year >= minSelectorYear && !start.After(now) {
+			return start.AddDate(0, 1, 0), start.Format("January 2006"), true
+		}
+	}
+	for _, match := range selectorYear.FindAllStringSubmatch(selector, -1) {
+		year, _ := strconv.Atoi(match[1])
+		if year >= minSelectorYear && year <= now.Year() {
+			return time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC), match[1], true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// CheckDKIMSelectorRotation suggests key rotation when the selector names indicate the keys are old:
+// the newest date-stamped selector is older than the configured age, or only a "default" selector exists
+func CheckDKIMSelectorRotation(info *EnhancedDomainInfo) {
+	if info.DKIMInfo == nil || len(info.DKIMInfo.Selectors) == 0 {
+		return
+	}
+
+	months := info.Settings.DKIMRotationMonths
+	if months <= 0 {
+		// Rotation checking is disabled
+		return
+	}
+
+	var newest, newestPeriod string
+	var newestEnd time.Time
+	for _, selector := range info.DKIMInfo.Selectors {
+		if end, period, ok := parseSelectorDate(selector, info.QueryTime); ok && end.After(newestEnd) {
+			newest, newestPeriod, newestEnd = selector, period, end
+		}
+	}
+
+	if newest != "" {
+		if newestEnd.After(info.QueryTime.AddDate(0, -months, 0)) {
+			info.RuleResults = append(info.RuleResults, RuleResult{
+				RuleID:      52,
+				Description: "DKIM selector rotation",
+				Status:      "pass",
+				Message:     fmt.Sprintf("The newest date-stamped selector %s is less than %d months old.", newest, months),
+				Confidence:  ConfidenceMedium,
+			})
+			return
+		}
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      52,
+			Description: "DKIM selector rotation",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The newest date-stamped selector %s dates from %s, more than %d months ago. Rotate the key by publishing a new selector, switching the signer to it and revoking the old key (p=) after a grace period.", newest, newestPeriod, months),
+			Confidence:  ConfidenceMedium,
+		})
+		return
+	}
+
+	if len(info.DKIMInfo.Selectors) == 1 && strings.EqualFold(info.DKIMInfo.Selectors[0], "default") {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      52,
+			Description: "DKIM selector rotation",
+			Status:      "info",
+			Message:     "The only selector found is \"default\", which usually means the key was generated when DKIM was set up and never rotated. Consider rotating to a new date-stamped selector (e.g. s" + info.QueryTime.Format("200601") + ") periodically.",
+			Confidence:  ConfidenceLow,
+		})
+	}
+}
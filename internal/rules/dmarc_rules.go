@@ -1,5 +1,15 @@
 package rules
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"check-maildomain/internal/dmarc"
+	"check-maildomain/internal/spf"
+)
+
 // CheckDMARCPolicy verifies that DMARC policy is set to reject or quarantine
 func CheckDMARCPolicy(info *EnhancedDomainInfo) {
 	if info.DMARCRecord == nil {
@@ -8,6 +18,10 @@ func CheckDMARCPolicy(info *EnhancedDomainInfo) {
 
 	// The policy is already parsed and available in info.DMARCPolicy
 	policyValue := info.DMARCPolicy.Policy
+	if info.DMARCRecord.InheritedFrom != "" {
+		// Subdomains inheriting the organizational record get the subdomain policy
+		policyValue = info.DMARCPolicy.SubdomainPolicy
+	}
 
 	switch policyValue {
 	case "reject":
@@ -60,3 +74,524 @@ func CheckDMARCExists(info *EnhancedDomainInfo) {
 		})
 	}
 }
+
+// CheckDMARCSyntax verifies that every DMARC tag value is valid according to RFC 7489
+func CheckDMARCSyntax(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil {
+		return
+	}
+
+	if len(info.DMARCRecord.ValidationErrors) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      19,
+			Description: "DMARC record syntax",
+			Status:      "fail",
+			Message:     fmt.Sprintf("DMARC record is invalid: %s. Receivers may ignore invalid tags or the whole record.", strings.Join(info.DMARCRecord.ValidationErrors, "; ")),
+		})
+	}
+	// Ignored values are reported on their own, so they aren't lost behind a failure
+	if len(info.DMARCRecord.TagWarnings) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      19,
+			Description: "DMARC record syntax",
+			Status:      "warn",
+			Message:     fmt.Sprintf("DMARC record contains tag values that receivers will ignore: %s.", strings.Join(sortedValues(info.DMARCRecord.TagWarnings), "; ")),
+		})
+	}
+	if len(info.DMARCRecord.ValidationErrors) == 0 && len(info.DMARCRecord.TagWarnings) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      19,
+			Description: "DMARC record syntax",
+			Status:      "pass",
+			Message:     "The DMARC record is valid.",
+		})
+	}
+}
+
+// CheckDMARCStrictSyntax reports every deviation from the RFC 7489 grammar as a separate result when strict parsing is enabled
+func CheckDMARCStrictSyntax(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || !info.DMARCRecord.Strict {
+		return
+	}
+
+	if len(info.DMARCRecord.StrictViolations) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      41,
+			Description: "DMARC strict syntax",
+			Status:      "pass",
+			Message:     "The DMARC record follows the RFC 7489 grammar.",
+		})
+		return
+	}
+
+	for _, violation := range info.DMARCRecord.StrictViolations {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      41,
+			Description: "DMARC strict syntax",
+			Status:      "fail",
+			Message:     fmt.Sprintf("Strict parsing rejects the record: %s.", violation),
+		})
+	}
+}
+
+// sortedValues returns the values of a map ordered by key
+func sortedValues(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values []string
+	for _, key := range keys {
+		values = append(values, m[key])
+	}
+	return values
+}
+
+// CheckDMARCUnknownTags reports tags that are not defined by RFC 7489
+func CheckDMARCUnknownTags(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || len(info.DMARCRecord.UnknownTags) == 0 {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      20,
+		Description: "DMARC unknown tags",
+		Status:      "warn",
+		Message:     fmt.Sprintf("DMARC record contains unknown tags: %s. Unknown tags are ignored by receivers and are often typos of valid tags.", strings.Join(info.DMARCRecord.UnknownTags, ", ")),
+	})
+}
+
+// CheckDMARCReportURIs verifies that the rua and ruf destinations are valid mailto: URIs
+func CheckDMARCReportURIs(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil {
+		return
+	}
+
+	uris := append(dmarc.ParseReportURIs(info.DMARCPolicy.AggregateReportURI), dmarc.ParseReportURIs(info.DMARCPolicy.ForensicReportURI)...)
+	if len(uris) == 0 {
+		return
+	}
+
+	var problems []string
+	for _, uri := range uris {
+		if !uri.Valid {
+			problems = append(problems, fmt.Sprintf("%s (%s)", uri.Raw, uri.Error))
+		}
+	}
+
+	if len(problems) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      23,
+			Description: "DMARC report URIs",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following rua/ruf destinations are invalid and will be ignored by receivers: %s. Use the form mailto:reports@example.com, optionally followed by a size limit such as !10m.", strings.Join(problems, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      23,
+			Description: "DMARC report URIs",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All %d rua/ruf destinations are valid mailto: URIs.", len(uris)),
+		})
+	}
+}
+
+// CheckDMARCReportVendors reports which DMARC analytics vendors receive the aggregate reports
+func CheckDMARCReportVendors(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || len(info.DMARCPolicy.AggregateReportURI) == 0 {
+		return
+	}
+
+	message := "No well-known DMARC analytics vendor was identified in the rua destinations; reports are processed in-house or by an unrecognized platform."
+	if len(info.DMARCVendors) > 0 {
+		message = fmt.Sprintf("Aggregate reports are processed by the following DMARC analytics vendors: %s.", strings.Join(info.DMARCVendors, ", "))
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      39,
+		Description: "DMARC report analytics vendor",
+		Status:      "info",
+		Message:     message,
+	})
+}
+
+// CheckDMARCExternalReportAuthorization verifies that external rua/ruf destinations have authorized receiving reports
+func CheckDMARCExternalReportAuthorization(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || len(info.DMARCReportAuthorizations) == 0 {
+		// No external report destinations
+		return
+	}
+
+	var unauthorized, unchecked []string
+	for _, auth := range info.DMARCReportAuthorizations {
+		if auth.Error != "" {
+			unchecked = append(unchecked, fmt.Sprintf("%s (%s)", auth.URI, auth.Error))
+		} else if !auth.Authorized {
+			unauthorized = append(unauthorized, fmt.Sprintf("%s (no record at %s)", auth.URI, auth.Query))
+		}
+	}
+
+	if len(unauthorized) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      27,
+			Description: "DMARC external report authorization",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following external report destinations have not authorized receiving reports for this domain: %s. Receivers will not send reports to them; ask the report provider to publish \"v=DMARC1\" at the listed name.", strings.Join(unauthorized, "; ")),
+		})
+	} else if len(unchecked) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      27,
+			Description: "DMARC external report authorization",
+			Status:      "info",
+			Message:     fmt.Sprintf("The authorization of the following external report destinations could not be verified: %s.", strings.Join(unchecked, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      27,
+			Description: "DMARC external report authorization",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All %d external report destinations have authorized receiving reports for this domain.", len(info.DMARCReportAuthorizations)),
+		})
+	}
+}
+
+// CheckDMARCReportDestinations verifies that the rua/ruf mailbox domains can accept mail
+func CheckDMARCReportDestinations(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || len(info.DMARCReportDestinations) == 0 {
+		return
+	}
+
+	var broken, unchecked []string
+	for _, dest := range info.DMARCReportDestinations {
+		switch {
+		case dest.CanReceive:
+		case dest.NullMX:
+			broken = append(broken, fmt.Sprintf("%s (%s publishes a null MX)", dest.URI, dest.Domain))
+		case dest.Error == "domain does not exist":
+			broken = append(broken, fmt.Sprintf("%s (%s does not exist)", dest.URI, dest.Domain))
+		case dest.Error != "":
+			unchecked = append(unchecked, fmt.Sprintf("%s (%s)", dest.URI, dest.Error))
+		case len(dest.MXHosts) > 0:
+			broken = append(broken, fmt.Sprintf("%s (MX hosts %s do not resolve)", dest.URI, strings.Join(dest.MXHosts, ", ")))
+		default:
+			broken = append(broken, fmt.Sprintf("%s (%s has no MX or address records)", dest.URI, dest.Domain))
+		}
+	}
+
+	if len(broken) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      34,
+			Description: "DMARC report destinations",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following report destinations cannot receive mail, so no reports will arrive: %s.", strings.Join(broken, "; ")),
+		})
+	} else if len(unchecked) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      34,
+			Description: "DMARC report destinations",
+			Status:      "info",
+			Message:     fmt.Sprintf("The following report destinations could not be verified: %s.", strings.Join(unchecked, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      34,
+			Description: "DMARC report destinations",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All %d report destinations can receive mail.", len(info.DMARCReportDestinations)),
+		})
+	}
+}
+
+// CheckDMARCPercentage verifies that the DMARC policy is applied to all mail (pct=100)
+func CheckDMARCPercentage(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil {
+		return
+	}
+
+	if _, ok := info.DMARCRecord.Tags["pct"]; !ok {
+		// pct defaults to 100
+		return
+	}
+
+	if _, invalid := info.DMARCRecord.InvalidTags["pct"]; invalid {
+		// Invalid values are reported by CheckDMARCSyntax
+		return
+	}
+
+	pct := info.DMARCPolicy.Percentage
+	switch {
+	case info.DMARCPolicy.Policy == "none":
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      28,
+			Description: "DMARC policy percentage",
+			Status:      "info",
+			Message:     fmt.Sprintf("pct=%d is set, but pct is ignored with p=none because no policy is enforced.", pct),
+		})
+	case pct <= 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      28,
+			Description: "DMARC policy percentage",
+			Status:      "fail",
+			Message:     fmt.Sprintf("pct=0 means the '%s' policy is applied to no mail at all, so spoofed mail is effectively treated as with p=none.", info.DMARCPolicy.Policy),
+		})
+	case pct < 100:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      28,
+			Description: "DMARC policy percentage",
+			Status:      "warn",
+			Message:     fmt.Sprintf("pct=%d means the '%s' policy is only applied to %d%% of failing mail; the rest of the spoofed mail is still let through. Increase pct to 100 once legitimate mail passes authentication.", pct, info.DMARCPolicy.Policy, pct),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      28,
+			Description: "DMARC policy percentage",
+			Status:      "pass",
+			Message:     "The DMARC policy is applied to all failing mail (pct=100).",
+		})
+	}
+}
+
+// CheckDMARCInheritance explains which policy applies when the DMARC record is inherited from the organizational domain
+func CheckDMARCInheritance(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || info.DMARCRecord.InheritedFrom == "" {
+		return
+	}
+
+	source := "p"
+	if _, ok := info.DMARCRecord.Tags["sp"]; ok {
+		source = "sp"
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      29,
+		Description: "DMARC policy inheritance",
+		Status:      "info",
+		Message: fmt.Sprintf("%s has no DMARC record of its own. The record of the organizational domain %s (%s) applies, so the effective policy is '%s' from its %s tag. Publish _dmarc.%s to override it.",
+			info.Domain, info.DMARCRecord.InheritedFrom, info.DMARCRecord.Location, info.DMARCPolicy.SubdomainPolicy, source, info.Domain),
+	})
+}
+
+// CheckDMARCHosted reports when the _dmarc name is delegated through a CNAME, usually to a DMARC monitoring vendor
+func CheckDMARCHosted(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || len(info.DMARCRecord.CNAMEChain) == 0 {
+		return
+	}
+
+	chain := strings.Join(info.DMARCRecord.CNAMEChain, " -> ")
+	message := fmt.Sprintf("The DMARC record at %s is delegated through a CNAME (%s) and the hosting provider is not recognized.", info.DMARCRecord.Location, chain)
+	if info.DMARCRecord.HostedBy != "" {
+		message = fmt.Sprintf("The DMARC record at %s is hosted by %s through a CNAME (%s). Policy changes are made in the provider's portal; the record published by the provider has been validated.", info.DMARCRecord.Location, info.DMARCRecord.HostedBy, chain)
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      35,
+		Description: "DMARC hosted record",
+		Status:      "info",
+		Message:     message,
+	})
+}
+
+// CheckDMARCAlignmentFeasibility warns when strict alignment is likely to break mail from third-party senders
+func CheckDMARCAlignmentFeasibility(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil {
+		return
+	}
+
+	strictSPF := strings.ToLower(info.DMARCPolicy.ASPF) == "s"
+	strictDKIM := strings.ToLower(info.DMARCPolicy.ADKIM) == "s"
+	if !strictSPF && !strictDKIM {
+		// Relaxed alignment is compatible with most sending setups
+		return
+	}
+
+	// Senders authorized through includes in another organizational domain are third parties
+	orgDomain := dmarc.OrganizationalDomain(info.Domain)
+	var thirdParties []string
+	if info.SPFRecord != nil {
+		for _, term := range info.SPFRecord.Mechanisms("include") {
+			if dmarc.OrganizationalDomain(term.Value) == orgDomain {
+				continue
+			}
+			name := term.Value
+			if provider, ok := spf.LookupProvider(term.Value); ok {
+				name = fmt.Sprintf("%s (%s)", provider, term.Value)
+			}
+			thirdParties = append(thirdParties, name)
+		}
+	}
+
+	// Selectors delegated through a CNAME to another organizational domain are signed by a third party
+	if strictDKIM && info.DKIMInfo != nil {
+		for _, result := range info.DKIMInfo.SelectorResults {
+			if len(result.CNAMEChain) == 0 {
+				continue
+			}
+			target := result.CNAMEChain[len(result.CNAMEChain)-1]
+			if dmarc.OrganizationalDomain(target) == orgDomain {
+				continue
+			}
+			name := fmt.Sprintf("DKIM selector %s delegated to %s", result.Selector, target)
+			if result.Provider != "" {
+				name = fmt.Sprintf("%s (DKIM selector %s delegated to %s)", result.Provider, result.Selector, target)
+			}
+			thirdParties = append(thirdParties, name)
+		}
+	}
+
+	var strictModes []string
+	if strictSPF {
+		strictModes = append(strictModes, "aspf=s")
+	}
+	if strictDKIM {
+		strictModes = append(strictModes, "adkim=s")
+	}
+
+	if len(thirdParties) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      30,
+			Description: "DMARC alignment feasibility",
+			Status:      "warn",
+			Confidence:  ConfidenceMedium,
+			Message: fmt.Sprintf("Strict alignment (%s) is configured, but third-party senders are authorized: %s. Third-party senders usually use their own or a subdomain envelope sender and often sign with their own DKIM domain, which fails strict alignment. Verify these senders use exactly %s, or use relaxed alignment.",
+				strings.Join(strictModes, ", "), strings.Join(thirdParties, ", "), info.Domain),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      30,
+			Description: "DMARC alignment feasibility",
+			Status:      "pass",
+			Confidence:  ConfidenceMedium,
+			Message:     fmt.Sprintf("Strict alignment (%s) is configured and no third-party senders were found that are likely to break it.", strings.Join(strictModes, ", ")),
+		})
+	}
+}
+
+// CheckDMARCFailureOptions notes that the fo tag has no effect without a ruf destination
+func CheckDMARCFailureOptions(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil {
+		return
+	}
+
+	if _, ok := info.DMARCRecord.Tags["fo"]; !ok || len(info.DMARCPolicy.ForensicReportURI) > 0 {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      32,
+		Description: "DMARC failure reporting options",
+		Status:      "info",
+		Message:     fmt.Sprintf("fo=%s is set, but there is no ruf destination to send failure reports to, so fo has no effect. Remove fo or add a ruf destination.", info.DMARCPolicy.FailureReportingOption),
+	})
+}
+
+// CheckDMARCFailureReportPrivacy warns that failure reports (ruf) may carry message content and personal data
+func CheckDMARCFailureReportPrivacy(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil || len(info.DMARCPolicy.ForensicReportURI) == 0 {
+		return
+	}
+
+	// Reports sent outside the organization hand the data to a third party
+	orgDomain := dmarc.OrganizationalDomain(info.Domain)
+	var external []string
+	for _, uri := range dmarc.ParseReportURIs(info.DMARCPolicy.ForensicReportURI) {
+		if uri.Valid && dmarc.OrganizationalDomain(uri.Domain) != orgDomain {
+			external = append(external, uri.Raw)
+		}
+	}
+
+	message := "ruf is configured. Failure reports can contain message headers, content and recipient addresses (personal data), and many large receivers don't send them at all. Confirm the destination mailbox is allowed to store this data and can handle the volume."
+	status := "info"
+	if len(external) > 0 {
+		status = "warn"
+		message = fmt.Sprintf("ruf sends failure reports to a mailbox outside the organization (%s). Failure reports can contain message headers, content and recipient addresses (personal data); make sure a data processing agreement covers this destination. Many large receivers don't send failure reports at all.", strings.Join(external, ", "))
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      37,
+		Description: "DMARC failure report privacy",
+		Status:      status,
+		Message:     message,
+	})
+}
+
+// dmarcDefaults are the values receivers assume when a tag is omitted (RFC 7489 section 6.3)
+var dmarcDefaults = map[string]string{
+	"adkim": "r",
+	"aspf":  "r",
+	"fo":    "0",
+	"pct":   "100",
+	"rf":    "afrf",
+	"ri":    "86400",
+}
+
+// CheckDMARCRedundantTags reports tags that repeat the policy or their default value, so the record can be simplified
+func CheckDMARCRedundantTags(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil {
+		return
+	}
+
+	var redundant []string
+	if sp, ok := info.DMARCRecord.Tags["sp"]; ok && strings.EqualFold(sp, info.DMARCRecord.Tags["p"]) {
+		redundant = append(redundant, fmt.Sprintf("sp=%s repeats p, subdomains already get the p policy when sp is omitted", sp))
+	}
+
+	var keys []string
+	for key := range dmarcDefaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := info.DMARCRecord.Tags[key]; ok && strings.EqualFold(value, dmarcDefaults[key]) {
+			redundant = append(redundant, fmt.Sprintf("%s=%s is the default", key, value))
+		}
+	}
+
+	if len(redundant) == 0 {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      38,
+		Description: "DMARC redundant tags",
+		Status:      "info",
+		Message:     fmt.Sprintf("The DMARC record can be simplified: %s.", strings.Join(redundant, "; ")),
+	})
+}
+
+// CheckDMARCReportInterval evaluates the requested aggregate report interval (ri)
+func CheckDMARCReportInterval(info *EnhancedDomainInfo) {
+	if info.DMARCRecord == nil {
+		return
+	}
+
+	raw, ok := info.DMARCRecord.Tags["ri"]
+	if !ok {
+		// ri defaults to 86400 seconds
+		return
+	}
+
+	interval, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		// The syntax rule already reports that receivers ignore the value
+		return
+	}
+
+	switch {
+	case interval < 3600:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      33,
+			Description: "DMARC report interval",
+			Status:      "warn",
+			Message:     fmt.Sprintf("ri=%d requests reports more often than hourly. Most receivers ignore sub-hour intervals and send daily reports anyway.", interval),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      33,
+			Description: "DMARC report interval",
+			Status:      "pass",
+			Message:     fmt.Sprintf("ri=%d is a reasonable report interval.", interval),
+		})
+	}
+}
@@ -1,6 +1,16 @@
 package rules
 
-// CheckDNSSECEnabled verifies if DNSSEC is enabled for the domain
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"check-maildomain/internal/dnssec"
+)
+
+// CheckDNSSECEnabled verifies if DNSSEC is enabled for the domain and doesn't fail validation
 func CheckDNSSECEnabled(info *EnhancedDomainInfo) {
 	if info.DNSSECInfo == nil {
 		// No DNSSEC info available
@@ -13,14 +23,22 @@ func CheckDNSSECEnabled(info *EnhancedDomainInfo) {
 		return
 	}
 
-	if info.DNSSECInfo.Enabled {
+	switch {
+	case info.DNSSECInfo.Enabled && info.DNSSECInfo.Validation == dnssec.ValidationBogus:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      8,
+			Description: "DNSSEC enabled",
+			Status:      "fail",
+			Message:     "DNSSEC is enabled for this domain, but validation fails, so validating resolvers can't resolve it. See the DNSSEC validation result for details.",
+		})
+	case info.DNSSECInfo.Enabled:
 		info.RuleResults = append(info.RuleResults, RuleResult{
 			RuleID:      8,
 			Description: "DNSSEC enabled",
 			Status:      "pass",
 			Message:     "DNSSEC is enabled for this domain, providing additional security for DNS lookups.",
 		})
-	} else {
+	default:
 		info.RuleResults = append(info.RuleResults, RuleResult{
 			RuleID:      8,
 			Description: "DNSSEC enabled",
@@ -29,3 +47,569 @@ func CheckDNSSECEnabled(info *EnhancedDomainInfo) {
 		})
 	}
 }
+
+// CheckDNSSECDSMatchesDNSKEY verifies that the DS records at the parent refer to published DNSKEY records.
+// When no DS matches, validating resolvers treat the zone as bogus and the domain becomes unreachable.
+func CheckDNSSECDSMatchesDNSKEY(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || len(info.DNSSECInfo.DSRecords) == 0 {
+		return
+	}
+
+	var matching, stale []string
+	for _, ds := range info.DNSSECInfo.DSRecords {
+		description := fmt.Sprintf("key tag %d, algorithm %d, digest type %d", ds.KeyTag, ds.Algorithm, ds.DigestType)
+		if ds.Matches {
+			matching = append(matching, description)
+		} else {
+			stale = append(stale, fmt.Sprintf("%s (%s)", description, ds.Problem))
+		}
+	}
+
+	switch {
+	case len(matching) == 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      53,
+			Description: "DS records match DNSKEY",
+			Status:      "fail",
+			Message:     fmt.Sprintf("None of the DS records at the parent match a published DNSKEY: %s. Validating resolvers treat the zone as bogus and fail every lookup, including MX and SPF. Update the DS records at the registrar to the current key signing key, or publish the key they refer to again.", strings.Join(stale, "; ")),
+		})
+	case len(stale) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      53,
+			Description: "DS records match DNSKEY",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following DS records don't match a published DNSKEY: %s. Validation still works through %s, but remove stale DS records at the registrar unless they are pre-published for a key rollover.", strings.Join(stale, "; "), strings.Join(matching, "; ")),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      53,
+			Description: "DS records match DNSKEY",
+			Status:      "pass",
+			Message:     fmt.Sprintf("Every DS record at the parent matches a published DNSKEY (%s).", strings.Join(matching, "; ")),
+		})
+	}
+}
+
+// dnssecAlgorithmStrength classifies DNSSEC algorithms (RFC 8624): deprecated algorithms fail, SHA-1 based ones warn
+var dnssecAlgorithmStrength = map[int]string{
+	1:  "fail", // RSAMD5
+	3:  "fail", // DSA
+	6:  "fail", // DSA-NSEC3-SHA1
+	5:  "warn", // RSASHA1
+	7:  "warn", // RSASHA1-NSEC3-SHA1
+	8:  "pass", // RSASHA256
+	10: "pass", // RSASHA512
+	13: "pass", // ECDSAP256SHA256
+	14: "pass", // ECDSAP384SHA384
+	15: "pass", // ED25519
+	16: "pass", // ED448
+}
+
+// CheckDNSSECAlgorithmStrength evaluates the algorithms of the DNSKEY records
+func CheckDNSSECAlgorithmStrength(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || len(info.DNSSECInfo.Keys) == 0 {
+		return
+	}
+
+	var deprecated, weak, strong []string
+	seen := make(map[int]bool)
+	for _, key := range info.DNSSECInfo.Keys {
+		if seen[key.Algorithm] {
+			continue
+		}
+		seen[key.Algorithm] = true
+
+		switch dnssecAlgorithmStrength[key.Algorithm] {
+		case "fail":
+			deprecated = append(deprecated, key.AlgorithmName)
+		case "warn":
+			weak = append(weak, key.AlgorithmName)
+		case "pass":
+			strong = append(strong, key.AlgorithmName)
+		}
+	}
+
+	switch {
+	case len(deprecated) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      54,
+			Description: "DNSSEC algorithm strength",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The zone is signed with deprecated algorithms: %s. Validators must not use them and treat the zone as insecure; perform an algorithm rollover to ECDSAP256SHA256 or ED25519.", strings.Join(deprecated, ", ")),
+		})
+	case len(weak) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      54,
+			Description: "DNSSEC algorithm strength",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The zone is signed with SHA-1 based algorithms: %s. Validators are phasing out SHA-1 signatures; perform an algorithm rollover to ECDSAP256SHA256 or ED25519.", strings.Join(weak, ", ")),
+		})
+	case len(strong) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      54,
+			Description: "DNSSEC algorithm strength",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The zone is signed with recommended algorithms: %s.", strings.Join(strong, ", ")),
+		})
+	}
+}
+
+// Minimum RSA key sizes for DNSSEC keys
+const (
+	minKSKBits = 2048
+	minZSKBits = 1280
+)
+
+// CheckDNSSECKeySize warns about RSA DNSKEYs that are too short to resist factoring
+func CheckDNSSECKeySize(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil {
+		return
+	}
+
+	var short, sizes []string
+	for _, key := range info.DNSSECInfo.Keys {
+		if !strings.HasPrefix(key.AlgorithmName, "RSA") || key.KeySize == 0 {
+			// Elliptic curve keys have a fixed size
+			continue
+		}
+		description := fmt.Sprintf("%s %d (%d bits)", key.Role, key.KeyTag, key.KeySize)
+		sizes = append(sizes, description)
+
+		minimum := minZSKBits
+		if key.Role == "KSK" {
+			minimum = minKSKBits
+		}
+		if key.KeySize < minimum {
+			short = append(short, fmt.Sprintf("%s, minimum %d", description, minimum))
+		}
+	}
+	if len(sizes) == 0 {
+		return
+	}
+
+	if len(short) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      55,
+			Description: "DNSSEC key size",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following RSA keys are shorter than recommended: %s. Roll them over to longer keys, or switch to ECDSAP256SHA256 which is both stronger and smaller.", strings.Join(short, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      55,
+			Description: "DNSSEC key size",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All RSA keys have a sufficient size: %s.", strings.Join(sizes, "; ")),
+		})
+	}
+}
+
+// signatureWarningFraction is the part of its validity period at the end of which a signature is reported:
+// signers re-sign well before that, short-lived signatures included
+const signatureWarningFraction = 5
+
+// signatureWarningWindow returns how long before its expiration a signature is reported: the last fifth of
+// its validity period, at most the configured number of days. A fixed window would report every signature of
+// a signer that keeps them valid for only a few days.
+func signatureWarningWindow(sig dnssec.SignatureDetail, days int) time.Duration {
+	window := time.Duration(days) * 24 * time.Hour
+	if lifetime := sig.Expiration.Sub(sig.Inception); lifetime > 0 && lifetime/signatureWarningFraction < window {
+		window = lifetime / signatureWarningFraction
+	}
+	return window
+}
+
+// CheckDNSSECSignatureExpiration fails when signatures over the DNSKEY, MX or TXT RRsets have expired and
+// warns when they are in the last fifth of their validity period, which usually means the signer stopped
+// re-signing
+func CheckDNSSECSignatureExpiration(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || len(info.DNSSECInfo.Signatures) == 0 {
+		return
+	}
+
+	var expired, expiring []string
+	var earliest time.Time
+	for _, sig := range info.DNSSECInfo.Signatures {
+		description := fmt.Sprintf("%s (key tag %d, expires %s)", sig.TypeCovered, sig.KeyTag, sig.Expiration.Format("2006-01-02 15:04 MST"))
+		switch {
+		case sig.Expiration.Before(info.QueryTime):
+			expired = append(expired, description)
+		case sig.Expiration.Before(info.QueryTime.Add(signatureWarningWindow(sig, info.Settings.SignatureWarningDays))):
+			expiring = append(expiring, description)
+		}
+		if earliest.IsZero() || sig.Expiration.Before(earliest) {
+			earliest = sig.Expiration
+		}
+	}
+
+	switch {
+	case len(expired) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      56,
+			Description: "DNSSEC signature expiration",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following signatures have expired: %s. Validating resolvers reject these RRsets, so mail to and from the domain fails. Check that the signer is running and re-signs the zone.", strings.Join(expired, "; ")),
+		})
+	case len(expiring) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      56,
+			Description: "DNSSEC signature expiration",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following signatures are in the last fifth of their validity period or expire within %d days: %s. Make sure the signer re-signs the zone before they expire.", info.Settings.SignatureWarningDays, strings.Join(expiring, "; ")),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      56,
+			Description: "DNSSEC signature expiration",
+			Status:      "pass",
+			Message:     fmt.Sprintf("No signature is close to its expiration, the earliest expires %s.", earliest.Format("2006-01-02 15:04 MST")),
+		})
+	}
+}
+
+// NSEC3 parameter limits following RFC 9276
+const (
+	maxNSEC3Iterations = 10
+	maxNSEC3SaltBytes  = 8
+)
+
+// CheckDNSSECNSEC3Parameters warns about NSEC3 iteration counts and salts that validators penalize
+func CheckDNSSECNSEC3Parameters(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || info.DNSSECInfo.NSEC3 == nil {
+		return
+	}
+
+	params := info.DNSSECInfo.NSEC3
+	saltBytes := len(params.Salt) / 2
+	var problems []string
+	if params.Iterations > maxNSEC3Iterations {
+		problems = append(problems, fmt.Sprintf("%d iterations (more than %d)", params.Iterations, maxNSEC3Iterations))
+	}
+	if saltBytes > maxNSEC3SaltBytes {
+		problems = append(problems, fmt.Sprintf("a %d byte salt (more than %d)", saltBytes, maxNSEC3SaltBytes))
+	}
+
+	if len(problems) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      57,
+			Description: "NSEC3 parameters",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The zone uses NSEC3 with %s. Validators limit the iterations they accept and treat zones above the limit as insecure, while extra iterations and salt add no protection against zone enumeration; use 0 iterations and no salt (RFC 9276).", strings.Join(problems, " and ")),
+		})
+		return
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      57,
+		Description: "NSEC3 parameters",
+		Status:      "pass",
+		Message:     fmt.Sprintf("The zone uses NSEC3 with %d iterations and a %d byte salt.", params.Iterations, saltBytes),
+	})
+}
+
+// CheckDNSSECMailRRsets verifies that the MX, SPF, DMARC and DKIM records of a signed zone carry valid signatures
+func CheckDNSSECMailRRsets(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || len(info.DNSSECInfo.MailRRsets) == 0 {
+		return
+	}
+
+	var signed, unsigned, invalid []string
+	for _, rrset := range info.DNSSECInfo.MailRRsets {
+		description := fmt.Sprintf("%s %s", rrset.Name, rrset.Type)
+		switch rrset.Status {
+		case dnssec.RRsetSigned:
+			signed = append(signed, description)
+		case dnssec.RRsetUnsigned:
+			unsigned = append(unsigned, description)
+		default:
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", description, rrset.Error))
+		}
+	}
+
+	switch {
+	case len(invalid) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      58,
+			Description: "DNSSEC signatures over mail records",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The signatures over the following mail records don't validate: %s. Validating resolvers reject these records, which breaks mail delivery or authentication.", strings.Join(invalid, "; ")),
+		})
+	case len(unsigned) > 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      58,
+			Description: "DNSSEC signatures over mail records",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The zone is signed, but the following mail records carry no signature: %s. They are served from an unsigned zone (e.g. a CNAME target at a provider) or the signer skips them, so they can be spoofed despite DNSSEC.", strings.Join(unsigned, "; ")),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      58,
+			Description: "DNSSEC signatures over mail records",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All mail records carry valid signatures: %s.", strings.Join(signed, "; ")),
+		})
+	}
+}
+
+// CheckDNSSECMXZones reports per MX target in another zone whether that zone is DNSSEC-signed
+func CheckDNSSECMXZones(info *EnhancedDomainInfo) {
+	if len(info.MXZones) == 0 {
+		return
+	}
+
+	var signed, unsigned, unknown []string
+	for _, zone := range info.MXZones {
+		switch {
+		case zone.Error != "":
+			unknown = append(unknown, fmt.Sprintf("%s (%s)", zone.Host, zone.Error))
+		case zone.Signed:
+			signed = append(signed, fmt.Sprintf("%s (zone %s)", zone.Host, zone.Zone))
+		default:
+			unsigned = append(unsigned, fmt.Sprintf("%s (zone %s)", zone.Host, zone.Zone))
+		}
+	}
+
+	var details []string
+	if len(signed) > 0 {
+		details = append(details, fmt.Sprintf("Signed: %s.", strings.Join(signed, ", ")))
+	}
+	if len(unknown) > 0 {
+		details = append(details, fmt.Sprintf("Could not be determined: %s.", strings.Join(unknown, ", ")))
+	}
+
+	if len(unsigned) == 0 {
+		if len(signed) == 0 {
+			return
+		}
+		summary := "The zones of all MX hosts are DNSSEC-signed."
+		if len(unknown) > 0 {
+			summary = "The zones of all MX hosts that could be checked are DNSSEC-signed."
+		}
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      59,
+			Description: "DNSSEC of MX host zones",
+			Status:      "pass",
+			Message:     strings.Join(append([]string{summary}, details...), " "),
+		})
+		return
+	}
+
+	// Unsigned MX zones only undermine the domain's own DNSSEC when it is signed
+	status := "info"
+	if info.DNSSECInfo != nil && info.DNSSECInfo.Enabled {
+		status = "warn"
+	}
+	message := fmt.Sprintf("The zones of the following MX hosts are not DNSSEC-signed: %s. Their addresses can be spoofed and DANE can't be used for them, regardless of the domain's own DNSSEC.", strings.Join(unsigned, ", "))
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      59,
+		Description: "DNSSEC of MX host zones",
+		Status:      status,
+		Message:     strings.Join(append([]string{message}, details...), " "),
+	})
+}
+
+// CheckDNSSECKeyRollover detects key rollovers in progress from the published keys, DS records and signatures
+func CheckDNSSECKeyRollover(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || len(info.DNSSECInfo.Keys) == 0 {
+		return
+	}
+
+	var ksks, zsks []string
+	algorithms := make(map[string]bool)
+	for _, key := range info.DNSSECInfo.Keys {
+		if key.Role == "KSK" {
+			ksks = append(ksks, fmt.Sprint(key.KeyTag))
+		} else {
+			zsks = append(zsks, fmt.Sprint(key.KeyTag))
+		}
+		algorithms[key.AlgorithmName] = true
+	}
+
+	// Several digest types for one key are not a rollover, count the keys the DS records refer to
+	var dsTags []string
+	seen := make(map[uint16]bool)
+	for _, ds := range info.DNSSECInfo.DSRecords {
+		if !seen[ds.KeyTag] {
+			seen[ds.KeyTag] = true
+			dsTags = append(dsTags, fmt.Sprint(ds.KeyTag))
+		}
+	}
+	signing := make(map[string]bool)
+	for _, sig := range info.DNSSECInfo.Signatures {
+		if sig.TypeCovered != "DNSKEY" {
+			signing[fmt.Sprint(sig.KeyTag)] = true
+		}
+	}
+
+	var phases []string
+	if len(algorithms) > 1 {
+		var names []string
+		for name := range algorithms {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		phases = append(phases, fmt.Sprintf("algorithm rollover: keys with %s are published", strings.Join(names, " and ")))
+	}
+	if len(ksks) > 1 {
+		phases = append(phases, fmt.Sprintf("KSK rollover (double-KSK): %d key signing keys are published (%s)", len(ksks), strings.Join(ksks, ", ")))
+	}
+	if len(dsTags) > 1 {
+		phases = append(phases, fmt.Sprintf("double-DS: the parent publishes DS records for %d keys (%s)", len(dsTags), strings.Join(dsTags, ", ")))
+	}
+	if len(zsks) > 1 {
+		var active []string
+		for _, tag := range zsks {
+			if signing[tag] {
+				active = append(active, tag)
+			}
+		}
+		phase := fmt.Sprintf("ZSK rollover (pre-publish): %d zone signing keys are published (%s)", len(zsks), strings.Join(zsks, ", "))
+		if len(active) > 0 {
+			phase += fmt.Sprintf(", signing with %s", strings.Join(active, ", "))
+		}
+		phases = append(phases, phase)
+	}
+
+	if len(phases) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      60,
+			Description: "DNSSEC key rollover",
+			Status:      "info",
+			Message:     fmt.Sprintf("No key rollover is in progress (%d KSK, %d ZSK).", len(ksks), len(zsks)),
+		})
+		return
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      60,
+		Description: "DNSSEC key rollover",
+		Status:      "info",
+		Message:     fmt.Sprintf("A key rollover appears to be in progress: %s. Complete it by removing the old keys and DS records once their TTLs have expired.", strings.Join(phases, "; ")),
+		Confidence:  ConfidenceMedium,
+	})
+}
+
+// dsDigestNames maps DS digest types to their names
+var dsDigestNames = map[uint8]string{
+	1: "SHA-1",
+	2: "SHA-256",
+	3: "GOST R 34.11-94",
+	4: "SHA-384",
+}
+
+// CheckDNSSECDSDigests warns when the parent only publishes SHA-1 DS digests
+func CheckDNSSECDSDigests(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || len(info.DNSSECInfo.DSRecords) == 0 {
+		return
+	}
+
+	var digests []string
+	seen := make(map[uint8]bool)
+	onlySHA1 := true
+	for _, ds := range info.DNSSECInfo.DSRecords {
+		if !seen[ds.DigestType] {
+			seen[ds.DigestType] = true
+			name, ok := dsDigestNames[ds.DigestType]
+			if !ok {
+				name = fmt.Sprintf("digest type %d", ds.DigestType)
+			}
+			digests = append(digests, name)
+		}
+		if ds.DigestType != 1 {
+			onlySHA1 = false
+		}
+	}
+
+	if onlySHA1 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      61,
+			Description: "DS digest types",
+			Status:      "warn",
+			Message:     "The parent only publishes SHA-1 (digest type 1) DS records. Registries and validators are deprecating SHA-1 digests; add a SHA-256 (digest type 2) DS record at the registrar and remove the SHA-1 one.",
+		})
+		return
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      61,
+		Description: "DS digest types",
+		Status:      "pass",
+		Message:     fmt.Sprintf("The DS records use %s digests.", strings.Join(digests, " and ")),
+	})
+}
+
+// validatorSupportedAlgorithms lists the DNSSEC algorithms that major validating resolvers implement;
+// GOST (12), the private algorithms (253, 254) and unassigned numbers are missing on purpose
+var validatorSupportedAlgorithms = map[int]bool{
+	1: true, 3: true, 5: true, 6: true, 7: true, 8: true, 10: true, 13: true, 14: true, 15: true, 16: true,
+}
+
+// CheckDNSSECUnsupportedAlgorithms warns when the zone is only signed with algorithms that validators don't
+// implement, which makes them treat the zone as insecure despite the published keys
+func CheckDNSSECUnsupportedAlgorithms(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil || len(info.DNSSECInfo.Keys) == 0 {
+		return
+	}
+
+	var unsupported, supported []string
+	for _, key := range info.DNSSECInfo.Keys {
+		list := &supported
+		if !validatorSupportedAlgorithms[key.Algorithm] {
+			list = &unsupported
+		}
+		if !slices.Contains(*list, key.AlgorithmName) {
+			*list = append(*list, key.AlgorithmName)
+		}
+	}
+	if len(unsupported) == 0 {
+		return
+	}
+
+	if len(supported) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      62,
+			Description: "DNSSEC algorithm support",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The zone is only signed with algorithms that major validators don't support: %s. Validators treat the zone as insecure, so it gets no DNSSEC protection in practice; roll over to ECDSAP256SHA256.", strings.Join(unsupported, ", ")),
+		})
+		return
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      62,
+		Description: "DNSSEC algorithm support",
+		Status:      "info",
+		Message:     fmt.Sprintf("Some keys use algorithms that major validators don't support (%s), validators rely on the keys using %s.", strings.Join(unsupported, ", "), strings.Join(supported, ", ")),
+	})
+}
+
+// CheckDNSSECValidation reports the outcome of validating the zone at a validating resolver. A bogus zone is
+// worse than an unsigned one: validating resolvers refuse its answers, so mail to the domain can't be delivered.
+func CheckDNSSECValidation(info *EnhancedDomainInfo) {
+	if info.DNSSECInfo == nil {
+		return
+	}
+
+	switch info.DNSSECInfo.Validation {
+	case dnssec.ValidationBogus:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      63,
+			Description: "DNSSEC validation",
+			Status:      "fail",
+			Message:     "DNSSEC validation fails for this domain: the resolver returns SERVFAIL, but answers with checking disabled. Validating resolvers (and the senders using them) can't resolve the domain, so mail to it bounces or is deferred. Fix the signatures or the DS records at the parent, or remove the DS records to go insecure.",
+		})
+	case dnssec.ValidationSecure:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      63,
+			Description: "DNSSEC validation",
+			Status:      "pass",
+			Message:     "The resolver validated the answers for this domain (AD bit set).",
+		})
+	case dnssec.ValidationIndeterminate:
+		if !info.DNSSECInfo.Enabled {
+			return
+		}
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      63,
+			Description: "DNSSEC validation",
+			Status:      "info",
+			Message:     "The validation status could not be determined; the resolver does not appear to validate DNSSEC. Use a validating resolver with -nameserver to distinguish secure from bogus.",
+		})
+	}
+}
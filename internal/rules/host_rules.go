@@ -0,0 +1,142 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckHostFCrDNS verifies that every address of the host has forward-confirmed reverse DNS
+func CheckHostFCrDNS(info *EnhancedDomainInfo) {
+	if info.HostInfo == nil || len(info.HostInfo.PTR) == 0 {
+		return
+	}
+
+	var missing, unconfirmed, unchecked []string
+	for _, ptr := range info.HostInfo.PTR {
+		if ptr.Error != "" {
+			// The lookup failed, the PTR state is unknown
+			unchecked = append(unchecked, fmt.Sprintf("%s (%s)", ptr.IP, ptr.Error))
+			continue
+		}
+		if len(ptr.Names) == 0 {
+			missing = append(missing, ptr.IP)
+		} else if !ptr.ForwardConfirmed {
+			unconfirmed = append(unconfirmed, fmt.Sprintf("%s (%s)", ptr.IP, strings.Join(ptr.Names, ", ")))
+		}
+	}
+
+	if len(missing) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      24,
+			Description: "Reverse DNS (FCrDNS)",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The following addresses have no PTR record: %s. Many receivers reject mail from servers without reverse DNS.", strings.Join(missing, ", ")),
+		})
+	} else if len(unconfirmed) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      24,
+			Description: "Reverse DNS (FCrDNS)",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The PTR names of the following addresses don't resolve back to the address: %s. Forward-confirmed reverse DNS is expected by many receivers.", strings.Join(unconfirmed, "; ")),
+		})
+	} else if len(unchecked) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      24,
+			Description: "Reverse DNS (FCrDNS)",
+			Status:      "info",
+			Message:     fmt.Sprintf("The reverse DNS of the following addresses could not be checked: %s.", strings.Join(unchecked, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      24,
+			Description: "Reverse DNS (FCrDNS)",
+			Status:      "pass",
+			Message:     "All addresses have forward-confirmed reverse DNS.",
+		})
+	}
+}
+
+// CheckHostBlocklists verifies that none of the host's addresses are listed on DNS blocklists
+func CheckHostBlocklists(info *EnhancedDomainInfo) {
+	if info.HostInfo == nil || len(info.HostInfo.Blocklists) == 0 {
+		return
+	}
+
+	var listed, refused []string
+	for _, result := range info.HostInfo.Blocklists {
+		if result.Listed {
+			listed = append(listed, fmt.Sprintf("%s on %s (%s)", result.IP, result.Zone, result.Response))
+		} else if result.Error != "" {
+			refused = append(refused, result.Zone)
+		}
+	}
+
+	unchecked := ""
+	confidence := ConfidenceHigh
+	if len(refused) > 0 {
+		confidence = ConfidenceMedium
+		unchecked = fmt.Sprintf(" The following blocklists could not be checked: %s.", strings.Join(refused, ", "))
+	}
+
+	if len(listed) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      25,
+			Description: "DNS blocklists",
+			Status:      "fail",
+			Message:     fmt.Sprintf("Listed on DNS blocklists: %s. Mail from listed addresses is likely to be rejected.%s", strings.Join(listed, "; "), unchecked),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      25,
+			Description: "DNS blocklists",
+			Status:      "pass",
+			Confidence:  confidence,
+			Message:     "None of the addresses are listed on the checked DNS blocklists." + unchecked,
+		})
+	}
+}
+
+// CheckHostSMTPTLS verifies that the host accepts SMTP connections and offers STARTTLS with a valid certificate
+func CheckHostSMTPTLS(info *EnhancedDomainInfo) {
+	if info.HostInfo == nil || len(info.HostInfo.SMTP) == 0 {
+		// SMTP probing was not requested
+		return
+	}
+
+	var failures, warnings []string
+	for _, result := range info.HostInfo.SMTP {
+		switch {
+		case result.Error != "" && result.Banner == "":
+			failures = append(failures, fmt.Sprintf("%s: %s", result.IP, result.Error))
+		case !result.STARTTLS:
+			failures = append(failures, fmt.Sprintf("%s: STARTTLS not offered", result.IP))
+		case result.Error != "":
+			failures = append(failures, fmt.Sprintf("%s: %s", result.IP, result.Error))
+		case !result.CertificateValid:
+			warnings = append(warnings, fmt.Sprintf("%s: certificate does not verify for %s (%s)", result.IP, result.ServerName, result.CertificateError))
+		}
+	}
+
+	if len(failures) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      26,
+			Description: "SMTP STARTTLS",
+			Status:      "fail",
+			Message:     fmt.Sprintf("SMTP/TLS problems found: %s.", strings.Join(append(failures, warnings...), "; ")),
+		})
+	} else if len(warnings) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      26,
+			Description: "SMTP STARTTLS",
+			Status:      "warn",
+			Message:     fmt.Sprintf("STARTTLS is offered, but: %s. Senders enforcing MTA-STS or DANE will not deliver to an invalid certificate.", strings.Join(warnings, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      26,
+			Description: "SMTP STARTTLS",
+			Status:      "pass",
+			Message:     "All probed addresses offer STARTTLS with a valid certificate.",
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"check-maildomain/internal/idn"
+)
+
+// CheckMixedScripts reports labels of an internationalized domain that mix letters of several scripts.
+// Mixing e.g. Latin and Cyrillic letters is how homograph domains imitate well-known names, so receivers
+// and users may treat mail from such a domain with suspicion.
+func CheckMixedScripts(info *EnhancedDomainInfo) {
+	if info.UnicodeDomain == "" {
+		// Not an internationalized domain
+		return
+	}
+
+	mixed := idn.MixedScriptLabels(info.UnicodeDomain)
+	if len(mixed) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      89,
+			Description: "Internationalized domain scripts",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The internationalized domain %s (%s) uses a single script per label.", info.UnicodeDomain, info.Domain),
+		})
+		return
+	}
+
+	var labels []string
+	for label, scripts := range mixed {
+		labels = append(labels, fmt.Sprintf("%s (%s)", label, strings.Join(scripts, ", ")))
+	}
+	sort.Strings(labels)
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      89,
+		Description: "Internationalized domain scripts",
+		Status:      "info",
+		Message: fmt.Sprintf("The internationalized domain %s (%s) mixes scripts within a label: %s. Mixed scripts are typical for homograph domains imitating another name; receivers and users may distrust mail from it.",
+			info.UnicodeDomain, info.Domain, strings.Join(labels, "; ")),
+		Confidence: ConfidenceMedium,
+	})
+}
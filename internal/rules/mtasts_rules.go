@@ -0,0 +1,218 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"check-maildomain/internal/mtasts"
+)
+
+const (
+	minMTASTSMaxAge         = 86400     // Below a day senders refetch the policy constantly
+	recommendedMTASTSMaxAge = 7 * 86400 // RFC 8461 recommends weeks or more for a stable policy
+)
+
+// CheckMTASTSExists reports whether the domain publishes an MTA-STS record and whether its policy can be
+// fetched. Without a policy, senders deliver over unauthenticated or even plaintext connections.
+func CheckMTASTSExists(info *EnhancedDomainInfo) {
+	if info.MTASTS == nil || len(info.MXRecords) == 0 {
+		// The lookup failed, or the domain receives no mail
+		return
+	}
+
+	sts := info.MTASTS
+	switch {
+	case len(sts.Records) == 0:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      90,
+			Description: "MTA-STS existence",
+			Status:      "info",
+			Message:     fmt.Sprintf("No MTA-STS record found at _mta-sts.%s. MTA-STS (RFC 8461) makes senders require TLS with a valid certificate when delivering to the MX hosts, protecting inbound mail against downgrade and interception.", info.Domain),
+		})
+	case sts.Policy == nil:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      90,
+			Description: "MTA-STS existence",
+			Status:      "fail",
+			Message:     fmt.Sprintf("An MTA-STS record is published, but the policy at %s can't be fetched: %s. Senders can't apply the policy; serve it over HTTPS with a certificate valid for mta-sts.%s.", sts.PolicyURL, sts.Error, info.Domain),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      90,
+			Description: "MTA-STS existence",
+			Status:      "pass",
+			Message:     fmt.Sprintf("An MTA-STS record is published and the policy is served at %s.", sts.PolicyURL),
+		})
+	}
+}
+
+// CheckMTASTSSyntax validates the MTA-STS TXT record and policy. Senders ignore a record or policy with
+// syntax errors, which silently disables MTA-STS.
+func CheckMTASTSSyntax(info *EnhancedDomainInfo) {
+	if info.MTASTS == nil || len(info.MTASTS.Records) == 0 {
+		return
+	}
+
+	sts := info.MTASTS
+	var problems []string
+	if len(sts.Records) > 1 {
+		problems = append(problems, fmt.Sprintf("%d MTA-STS records are published, senders ignore all of them", len(sts.Records)))
+	}
+	if sts.Record != nil {
+		for _, problem := range sts.Record.Errors {
+			problems = append(problems, "TXT record: "+problem)
+		}
+	}
+	if sts.Policy != nil {
+		for _, problem := range sts.Policy.Errors {
+			problems = append(problems, "policy: "+problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      91,
+			Description: "MTA-STS syntax",
+			Status:      "fail",
+			Message:     fmt.Sprintf("The MTA-STS configuration is invalid: %s. Senders ignore an invalid record or policy.", strings.Join(problems, "; ")),
+		})
+		return
+	}
+
+	if sts.Policy == nil {
+		// The TXT record is valid, the missing policy is reported by the existence rule
+		return
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      91,
+		Description: "MTA-STS syntax",
+		Status:      "pass",
+		Message:     fmt.Sprintf("The MTA-STS record (id %s) and policy (mode %s) are valid.", sts.Record.ID, sts.Policy.Mode),
+	})
+}
+
+// CheckMTASTSMXCoverage verifies that every MX host matches an mx pattern of the MTA-STS policy. Senders
+// honoring an enforced policy refuse to deliver to MX hosts the policy doesn't list.
+func CheckMTASTSMXCoverage(info *EnhancedDomainInfo) {
+	if info.MTASTS == nil || info.MTASTS.Policy == nil || len(info.MXRecords) == 0 {
+		return
+	}
+
+	policy := info.MTASTS.Policy
+	if policy.Mode == mtasts.ModeNone || len(policy.MX) == 0 {
+		// A withdrawn policy or one without patterns is reported by the mode and syntax rules
+		return
+	}
+
+	var unmatched []string
+	for _, record := range info.MXRecords {
+		if record.Host == "" {
+			// Null MX
+			continue
+		}
+		if !policy.Matches(record.Host) {
+			unmatched = append(unmatched, record.Host)
+		}
+	}
+
+	if len(unmatched) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      92,
+			Description: "MTA-STS MX coverage",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All MX hosts match the mx patterns of the MTA-STS policy (%s).", strings.Join(policy.MX, ", ")),
+		})
+		return
+	}
+
+	if policy.Mode == mtasts.ModeEnforce {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      92,
+			Description: "MTA-STS MX coverage",
+			Status:      "fail",
+			Message: fmt.Sprintf("The following MX hosts don't match any mx pattern of the enforced MTA-STS policy: %s. Senders honoring MTA-STS refuse to deliver to them; add them to the policy (%s) and update the id in the TXT record.",
+				strings.Join(unmatched, ", "), strings.Join(policy.MX, ", ")),
+		})
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      92,
+		Description: "MTA-STS MX coverage",
+		Status:      "warn",
+		Message: fmt.Sprintf("The following MX hosts don't match any mx pattern of the MTA-STS policy in testing mode: %s. Senders report the failures, and delivery to these hosts will fail once the policy is enforced.",
+			strings.Join(unmatched, ", ")),
+	})
+}
+
+// CheckMTASTSMode reports the mode of a valid MTA-STS policy. Only mode enforce protects inbound mail,
+// testing merely asks senders to report failures.
+func CheckMTASTSMode(info *EnhancedDomainInfo) {
+	if info.MTASTS == nil || info.MTASTS.Policy == nil || !info.MTASTS.Policy.Valid() {
+		return
+	}
+
+	switch info.MTASTS.Policy.Mode {
+	case mtasts.ModeEnforce:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      93,
+			Description: "MTA-STS mode",
+			Status:      "pass",
+			Message:     "The MTA-STS policy is enforced, senders refuse to deliver over unauthenticated connections.",
+		})
+	case mtasts.ModeTesting:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      93,
+			Description: "MTA-STS mode",
+			Status:      "warn",
+			Message:     "The MTA-STS policy is in testing mode, senders deliver over unauthenticated connections and only report failures. Publish a TLS-RPT record to receive these reports, and switch to mode enforce once they show no failures.",
+		})
+	case mtasts.ModeNone:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      93,
+			Description: "MTA-STS mode",
+			Status:      "info",
+			Message:     "The MTA-STS policy is in mode none, which withdraws the policy from senders that cached it. Remove the TXT record once the previous max_age has passed.",
+		})
+	}
+}
+
+// CheckMTASTSMaxAge evaluates how long senders cache the MTA-STS policy. The policy only protects against
+// attackers blocking the policy fetch while it is cached, so RFC 8461 recommends weeks or more.
+func CheckMTASTSMaxAge(info *EnhancedDomainInfo) {
+	if info.MTASTS == nil || info.MTASTS.Policy == nil || info.MTASTS.Policy.MaxAge < 0 {
+		return
+	}
+
+	policy := info.MTASTS.Policy
+	if policy.Mode == mtasts.ModeNone {
+		// A withdrawn policy only needs to be cached long enough to replace the previous one
+		return
+	}
+
+	switch {
+	case policy.MaxAge < minMTASTSMaxAge:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      94,
+			Description: "MTA-STS max_age",
+			Status:      "warn",
+			Message: fmt.Sprintf("The MTA-STS policy is cached for only %s. Senders refetch it constantly, and an attacker blocking the fetch can downgrade delivery once the cache expires; raise max_age to %s or more.",
+				formatDuration(uint32(policy.MaxAge)), formatDuration(recommendedMTASTSMaxAge)),
+		})
+	case policy.MaxAge < recommendedMTASTSMaxAge:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      94,
+			Description: "MTA-STS max_age",
+			Status:      "info",
+			Message: fmt.Sprintf("The MTA-STS policy is cached for %s. Once the policy is stable, raise max_age to %s or more, as RFC 8461 recommends.",
+				formatDuration(uint32(policy.MaxAge)), formatDuration(recommendedMTASTSMaxAge)),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      94,
+			Description: "MTA-STS max_age",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The MTA-STS policy is cached for %s.", formatDuration(uint32(policy.MaxAge))),
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net"
 	"strings"
+
+	"check-maildomain/internal/mx"
 )
 
 // CheckMXExists verifies that MX records exist for the domain
@@ -26,6 +28,32 @@ func CheckMXExists(info *EnhancedDomainInfo) {
 	}
 }
 
+// CheckImplicitMX reports where mail goes when the domain has no MX records: senders fall back to the
+// address records of the domain itself (RFC 5321 section 5.1), usually a web server that doesn't accept mail
+func CheckImplicitMX(info *EnhancedDomainInfo) {
+	if len(info.MXRecords) > 0 {
+		// MX records are used, the implicit MX rule doesn't apply
+		return
+	}
+
+	if len(info.ApexAddresses) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      68,
+			Description: "Implicit MX",
+			Status:      "warn",
+			Message: fmt.Sprintf("The domain has no MX records, so senders fall back to the implicit MX rule and try to deliver mail to the domain's own addresses: %s. Publish MX records if the domain receives mail, or a Null MX record \"0 .\" (RFC 7505) if it doesn't.",
+				strings.Join(info.ApexAddresses, ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      68,
+			Description: "Implicit MX",
+			Status:      "info",
+			Message:     "The domain has neither MX nor address records, so it can't receive mail. Publish a Null MX record \"0 .\" (RFC 7505) to make senders reject mail to it immediately.",
+		})
+	}
+}
+
 // CheckMXHasIPs verifies that each MX record has at least one IP address
 func CheckMXHasIPs(info *EnhancedDomainInfo) {
 	if len(info.MXRecords) == 0 {
@@ -283,6 +311,355 @@ func CheckMXPrivateIPs(info *EnhancedDomainInfo) {
 	}
 }
 
+// CheckMXIPLiteral verifies that MX records name a host instead of an IP address. The exchange of an MX record
+// must be a hostname (RFC 1035 section 3.3.9, RFC 5321 section 5.1); many senders treat an address literal as a name and
+// fail to deliver.
+func CheckMXIPLiteral(info *EnhancedDomainInfo) {
+	if len(info.MXRecords) == 0 {
+		// No MX records to check
+		return
+	}
+
+	var literals []string
+	for _, mx := range info.MXRecords {
+		if net.ParseIP(strings.Trim(mx.Host, "[]")) != nil {
+			literals = append(literals, mx.Host)
+		}
+	}
+
+	if len(literals) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      64,
+			Description: "MX IP literal check",
+			Status:      "fail",
+			Message: fmt.Sprintf("Found %d MX records pointing to an IP address instead of a hostname: %s. The MX exchange must be a hostname with A/AAAA records; many senders can't deliver to an address literal.",
+				len(literals), strings.Join(literals, ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      64,
+			Description: "MX IP literal check",
+			Status:      "pass",
+			Message:     "All MX records point to hostnames.",
+		})
+	}
+}
+
+// CheckMXDuplicates verifies that no host is listed more than once in the MX records. A duplicated host adds
+// no redundancy and, at different priorities, makes senders retry the same server.
+func CheckMXDuplicates(info *EnhancedDomainInfo) {
+	if len(info.MXRecords) < 2 {
+		// Nothing to compare
+		return
+	}
+
+	seen := make(map[string]int)
+	var duplicates []string
+	for _, mx := range info.MXRecords {
+		host := strings.ToLower(strings.TrimSuffix(mx.Host, "."))
+		seen[host]++
+		if seen[host] == 2 {
+			duplicates = append(duplicates, host)
+		}
+	}
+
+	if len(duplicates) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      65,
+			Description: "MX duplicate hosts",
+			Status:      "warn",
+			Message: fmt.Sprintf("The following hosts are listed in more than one MX record: %s. Duplicates add no redundancy; remove them or list a different backup server.",
+				strings.Join(duplicates, ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      65,
+			Description: "MX duplicate hosts",
+			Status:      "pass",
+			Message:     "Every MX record points to a different host.",
+		})
+	}
+}
+
+// CheckMXPriorities reports how the MX priorities are laid out: all servers sharing one priority means there
+// is no backup order, and consecutive priorities leave no room to add a server in between. Both are
+// conventions rather than errors, so this rule is informational.
+func CheckMXPriorities(info *EnhancedDomainInfo) {
+	if len(info.MXRecords) < 2 {
+		// Nothing to compare
+		return
+	}
+
+	// MXRecords are sorted by priority
+	priorities := []uint16{info.MXRecords[0].Priority}
+	for _, mx := range info.MXRecords[1:] {
+		if mx.Priority != priorities[len(priorities)-1] {
+			priorities = append(priorities, mx.Priority)
+		}
+	}
+
+	if len(priorities) == 1 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      66,
+			Description: "MX priorities",
+			Status:      "info",
+			Message: fmt.Sprintf("All %d MX records share priority %d, so senders spread the mail over them without a backup order. This is fine for load balanced servers; use a higher value for backup servers.",
+				len(info.MXRecords), priorities[0]),
+		})
+		return
+	}
+
+	for i := 1; i < len(priorities); i++ {
+		if priorities[i]-priorities[i-1] == 1 {
+			info.RuleResults = append(info.RuleResults, RuleResult{
+				RuleID:      66,
+				Description: "MX priorities",
+				Status:      "info",
+				Message: fmt.Sprintf("The MX priorities %d and %d are consecutive, which leaves no room to add a server in between. Spacing priorities by 10 (10, 20, ...) is the common convention.",
+					priorities[i-1], priorities[i]),
+			})
+			return
+		}
+	}
+}
+
+// CheckMXReverseDNS verifies that the addresses of the MX hosts have forward-confirmed reverse DNS. MX hosts
+// usually send mail as well, and receivers reject or penalize servers without FCrDNS.
+func CheckMXReverseDNS(info *EnhancedDomainInfo) {
+	if len(info.MXReverseDNS) == 0 {
+		// No MX addresses to check
+		return
+	}
+
+	var missing, unconfirmed []string
+	for _, mx := range info.MXRecords {
+		for _, ptr := range info.MXReverseDNS[mx.Host] {
+			if ptr.Error != "" {
+				// The lookup failed, the PTR state is unknown
+				continue
+			}
+			if len(ptr.Names) == 0 {
+				missing = append(missing, fmt.Sprintf("%s (%s)", ptr.IP, mx.Host))
+			} else if !ptr.ForwardConfirmed {
+				unconfirmed = append(unconfirmed, fmt.Sprintf("%s of %s (PTR %s)", ptr.IP, mx.Host, strings.Join(ptr.Names, ", ")))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      69,
+			Description: "MX reverse DNS (FCrDNS)",
+			Status:      "fail",
+			Message: fmt.Sprintf("The following MX addresses have no PTR record: %s. Mail sent from these servers is rejected by many receivers; ask the network provider to publish PTR records naming the MX host.",
+				strings.Join(missing, ", ")),
+		})
+	} else if len(unconfirmed) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      69,
+			Description: "MX reverse DNS (FCrDNS)",
+			Status:      "warn",
+			Message: fmt.Sprintf("The PTR names of the following MX addresses don't resolve back to the address: %s. Forward-confirmed reverse DNS is expected by many receivers.",
+				strings.Join(unconfirmed, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      69,
+			Description: "MX reverse DNS (FCrDNS)",
+			Status:      "pass",
+			Message:     "All MX addresses have forward-confirmed reverse DNS.",
+		})
+	}
+}
+
+// CheckMXNetworkDiversity verifies that the MX addresses are spread over more than one network. When every
+// address falls in one /24 (IPv4) and one /48 (IPv6), a single network outage removes all inbound capacity,
+// however many MX records there are.
+func CheckMXNetworkDiversity(info *EnhancedDomainInfo) {
+	networks := make(map[string]bool)
+	addresses := make(map[string]bool)
+	var v4, v6 []string
+	for _, mx := range info.MXRecords {
+		for _, record := range mx.Records {
+			ip := net.ParseIP(record.Value)
+			if ip == nil || (record.Type != "A" && record.Type != "AAAA") || addresses[ip.String()] {
+				continue
+			}
+			addresses[ip.String()] = true
+
+			var network string
+			if ip4 := ip.To4(); ip4 != nil {
+				network = (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+				if !networks[network] {
+					v4 = append(v4, network)
+				}
+			} else {
+				network = (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+				if !networks[network] {
+					v6 = append(v6, network)
+				}
+			}
+			networks[network] = true
+		}
+	}
+
+	if len(addresses) < 2 {
+		// A single address is covered by the redundancy rule
+		return
+	}
+
+	if len(v4) <= 1 && len(v6) <= 1 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      70,
+			Description: "MX network diversity",
+			Status:      "warn",
+			Message: fmt.Sprintf("All MX addresses are in the same network (%s), so a single network outage removes all inbound mail capacity. Place a backup MX in a different network or location.",
+				strings.Join(append(v4, v6...), ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      70,
+			Description: "MX network diversity",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The MX addresses are spread over %d networks: %s.", len(v4)+len(v6), strings.Join(append(v4, v6...), ", ")),
+		})
+	}
+}
+
+// CheckMXHostnameSyntax verifies that the MX hosts are valid hostnames: letters, digits and hyphens only, no
+// label starting or ending with a hyphen, labels of at most 63 and names of at most 253 characters. DNS
+// accepts any bytes, but SMTP clients reject names outside the hostname syntax (RFC 5321 section 4.1.2).
+func CheckMXHostnameSyntax(info *EnhancedDomainInfo) {
+	if len(info.MXRecords) == 0 {
+		// No MX records to check
+		return
+	}
+
+	var invalid []string
+	for _, mx := range info.MXRecords {
+		host := strings.TrimSuffix(mx.Host, ".")
+		if host == "" || net.ParseIP(strings.Trim(host, "[]")) != nil {
+			// Null MX, and IP literals are reported by their own rule
+			continue
+		}
+		if problem := hostnameProblem(host); problem != "" {
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", host, problem))
+		}
+	}
+
+	if len(invalid) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      71,
+			Description: "MX hostname syntax",
+			Status:      "fail",
+			Message: fmt.Sprintf("The following MX hosts are not valid hostnames: %s. Resolvers may accept them, but SMTP clients refuse to deliver to them.",
+				strings.Join(invalid, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      71,
+			Description: "MX hostname syntax",
+			Status:      "pass",
+			Message:     "All MX hosts are valid hostnames.",
+		})
+	}
+}
+
+// hostnameProblem describes why the name is not a valid hostname, or returns an empty string when it is
+func hostnameProblem(name string) string {
+	if len(name) > 253 {
+		return fmt.Sprintf("name is %d characters, the maximum is 253", len(name))
+	}
+	for _, label := range strings.Split(name, ".") {
+		switch {
+		case label == "":
+			return "empty label"
+		case len(label) > 63:
+			return fmt.Sprintf("label %q is %d characters, the maximum is 63", label, len(label))
+		case strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-"):
+			return fmt.Sprintf("label %q starts or ends with a hyphen", label)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Sprintf("label %q contains the illegal character %q", label, c)
+			}
+		}
+	}
+	return ""
+}
+
+// CheckMXMixedProviders warns when the MX hosts belong to more than one inbound provider. This usually
+// indicates an unfinished migration: senders still deliver to the old server, bypassing the filtering and
+// policies of the new provider.
+func CheckMXMixedProviders(info *EnhancedDomainInfo) {
+	if len(info.InboundProviders) < 2 {
+		return
+	}
+
+	hosts := make(map[string][]string)
+	for _, record := range info.MXRecords {
+		if name, ok := mx.LookupProvider(record.Host, info.Domain); ok {
+			hosts[name] = append(hosts[name], fmt.Sprintf("%s (priority %d)", record.Host, record.Priority))
+		}
+	}
+
+	var details []string
+	for _, name := range info.InboundProviders {
+		details = append(details, fmt.Sprintf("%s: %s", name, strings.Join(hosts[name], ", ")))
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      72,
+		Description: "Mixed inbound providers",
+		Status:      "warn",
+		Message: fmt.Sprintf("The MX records point to %d different providers. %s. This often indicates an unfinished migration; mail delivered to the forgotten server bypasses the filtering of the other provider. Remove the MX records that are no longer in use.",
+			len(info.InboundProviders), strings.Join(details, "; ")),
+	})
+}
+
+// CheckMXWebHosting warns when MX hosts resolve to the same addresses as the apex or www website. Hosting
+// panels point the MX at the web server by default, which usually means there is no working mail service.
+func CheckMXWebHosting(info *EnhancedDomainInfo) {
+	if len(info.MXRecords) == 0 || len(info.ApexAddresses)+len(info.WWWAddresses) == 0 {
+		// Nothing to compare
+		return
+	}
+
+	web := make(map[string]bool)
+	for _, address := range append(append([]string{}, info.ApexAddresses...), info.WWWAddresses...) {
+		if ip := net.ParseIP(address); ip != nil {
+			web[ip.String()] = true
+		}
+	}
+
+	var shared []string
+	for _, mx := range info.MXRecords {
+		for _, record := range mx.Records {
+			ip := net.ParseIP(record.Value)
+			if ip != nil && (record.Type == "A" || record.Type == "AAAA") && web[ip.String()] {
+				shared = append(shared, fmt.Sprintf("%s (%s)", mx.Host, record.Value))
+			}
+		}
+	}
+
+	if len(shared) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      73,
+			Description: "MX on web hosting",
+			Status:      "warn",
+			Message: fmt.Sprintf("The following MX hosts resolve to the address of the website: %s. Hosting panels set this up by default; unless the web server really runs the mail service, point the MX records at the mail provider or publish a Null MX record \"0 .\".",
+				strings.Join(shared, ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      73,
+			Description: "MX on web hosting",
+			Status:      "pass",
+			Message:     "The MX hosts don't share addresses with the website.",
+		})
+	}
+}
+
 // isPrivateIP checks if an IP address is in a private range
 func isPrivateIP(ip net.IP) bool {
 	// Define private IP ranges
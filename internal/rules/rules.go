@@ -1,57 +1,394 @@
 package rules
 
 import (
+	"fmt"
+
 	"check-maildomain/internal/dns"
 )
 
+// Category groups related rules in the output
+type Category string
+
+const (
+	CategoryAuthentication    Category = "authentication"
+	CategoryTransport         Category = "transport"
+	CategoryDNSInfrastructure Category = "dns-infrastructure"
+	CategoryReputation        Category = "reputation"
+	CategoryHygiene           Category = "hygiene"
+)
+
+// Categories lists all rule categories in display order
+var Categories = []Category{
+	CategoryAuthentication,
+	CategoryTransport,
+	CategoryDNSInfrastructure,
+	CategoryReputation,
+	CategoryHygiene,
+}
+
+// Confidence levels describe how strong the evidence behind a rule result is
+const (
+	ConfidenceHigh   = "high"   // Based on records that were fetched and parsed
+	ConfidenceMedium = "medium" // Based on partial data or a heuristic
+	ConfidenceLow    = "low"    // Inferred from indirect signals only
+)
+
+// ruleCategories maps every rule ID to its category
+var ruleCategories = map[int]Category{
+	1:   CategoryHygiene,           // SPF ptr mechanism
+	2:   CategoryAuthentication,    // SPF include limit
+	3:   CategoryAuthentication,    // SPF all mechanism
+	4:   CategoryAuthentication,    // DMARC policy
+	5:   CategoryAuthentication,    // DMARC existence
+	6:   CategoryAuthentication,    // SPF existence
+	7:   CategoryAuthentication,    // DKIM existence
+	8:   CategoryDNSInfrastructure, // DNSSEC enabled
+	9:   CategoryTransport,         // MX existence
+	10:  CategoryTransport,         // MX has IPs
+	11:  CategoryTransport,         // MX has IPv6
+	12:  CategoryTransport,         // MX redundancy
+	13:  CategoryHygiene,           // MX count
+	14:  CategoryTransport,         // MX localhost
+	15:  CategoryTransport,         // MX private IPs
+	16:  CategoryAuthentication,    // SPF providers
+	17:  CategoryAuthentication,    // MX covered by SPF
+	18:  CategoryAuthentication,    // Subdomain SPF coverage
+	19:  CategoryAuthentication,    // DMARC syntax
+	20:  CategoryHygiene,           // DMARC unknown tags
+	21:  CategoryHygiene,           // Domain web presence
+	22:  CategoryAuthentication,    // Parked domain lockdown
+	23:  CategoryAuthentication,    // DMARC report URIs
+	24:  CategoryReputation,        // Host FCrDNS
+	25:  CategoryReputation,        // Host DNS blocklists
+	26:  CategoryTransport,         // Host SMTP STARTTLS
+	27:  CategoryAuthentication,    // DMARC external report authorization
+	28:  CategoryAuthentication,    // DMARC pct
+	29:  CategoryAuthentication,    // DMARC inheritance
+	30:  CategoryAuthentication,    // DMARC alignment feasibility
+	31:  CategoryHygiene,           // Check completed
+	32:  CategoryHygiene,           // DMARC fo without ruf
+	33:  CategoryHygiene,           // DMARC report interval
+	34:  CategoryAuthentication,    // DMARC report destinations can receive mail
+	35:  CategoryAuthentication,    // DMARC record hosted through a CNAME
+	36:  CategoryAuthentication,    // Subdomain DMARC overrides
+	37:  CategoryHygiene,           // DMARC ruf privacy
+	38:  CategoryHygiene,           // DMARC redundant tags
+	39:  CategoryAuthentication,    // DMARC report analytics vendor
+	40:  CategoryDNSInfrastructure, // Policy record TTLs
+	41:  CategoryAuthentication,    // DMARC strict syntax
+	42:  CategoryAuthentication,    // DKIM key length
+	43:  CategoryAuthentication,    // DKIM key algorithms
+	44:  CategoryHygiene,           // DKIM revoked keys
+	45:  CategoryAuthentication,    // DKIM testing mode
+	46:  CategoryAuthentication,    // DKIM supplied selectors
+	47:  CategoryAuthentication,    // DKIM delegated selectors
+	48:  CategoryHygiene,           // DKIM shared keys
+	49:  CategoryAuthentication,    // DKIM record syntax
+	50:  CategoryAuthentication,    // DKIM hash algorithms
+	51:  CategoryDNSInfrastructure, // DKIM key only retrievable over TCP
+	52:  CategoryHygiene,           // DKIM selector rotation
+	53:  CategoryDNSInfrastructure, // DNSSEC DS matches DNSKEY
+	54:  CategoryDNSInfrastructure, // DNSSEC algorithm strength
+	55:  CategoryDNSInfrastructure, // DNSSEC key size
+	56:  CategoryDNSInfrastructure, // DNSSEC signature expiration
+	57:  CategoryDNSInfrastructure, // NSEC3 parameters
+	58:  CategoryDNSInfrastructure, // DNSSEC signatures over mail RRsets
+	59:  CategoryDNSInfrastructure, // DNSSEC of MX host zones
+	60:  CategoryDNSInfrastructure, // DNSSEC key rollover state
+	61:  CategoryDNSInfrastructure, // DS digest types
+	62:  CategoryDNSInfrastructure, // DNSSEC algorithm support
+	63:  CategoryDNSInfrastructure, // DNSSEC validation
+	64:  CategoryTransport,         // MX IP literal
+	65:  CategoryHygiene,           // MX duplicate hosts
+	66:  CategoryHygiene,           // MX priorities
+	67:  CategoryTransport,         // MX record TTL
+	68:  CategoryTransport,         // Implicit MX
+	69:  CategoryReputation,        // MX reverse DNS (FCrDNS)
+	70:  CategoryTransport,         // MX network diversity
+	71:  CategoryTransport,         // MX hostname syntax
+	72:  CategoryTransport,         // Mixed inbound providers
+	73:  CategoryTransport,         // MX on web hosting
+	74:  CategoryDNSInfrastructure, // Wildcard DNS
+	75:  CategoryDNSInfrastructure, // TXT record size
+	76:  CategoryDNSInfrastructure, // CAA records
+	77:  CategoryTransport,         // CAA for MX hosts
+	78:  CategoryDNSInfrastructure, // SOA timers
+	79:  CategoryDNSInfrastructure, // SOA serial
+	80:  CategoryDNSInfrastructure, // SOA MNAME
+	81:  CategoryDNSInfrastructure, // NS consistency
+	82:  CategoryDNSInfrastructure, // Mail records across nameservers
+	83:  CategoryDNSInfrastructure, // Lame delegation
+	84:  CategoryDNSInfrastructure, // Zone transfer
+	85:  CategoryDNSInfrastructure, // Nameserver redundancy
+	86:  CategoryDNSInfrastructure, // Nameserver diversity
+	87:  CategoryDNSInfrastructure, // Nameserver IPv6
+	88:  CategoryDNSInfrastructure, // Negative caching TTL
+	89:  CategoryHygiene,           // Mixed scripts in an internationalized domain
+	90:  CategoryTransport,         // MTA-STS existence
+	91:  CategoryTransport,         // MTA-STS syntax
+	92:  CategoryTransport,         // MTA-STS MX coverage
+	93:  CategoryTransport,         // MTA-STS mode
+	94:  CategoryTransport,         // MTA-STS max_age
+	95:  CategoryTransport,         // DANE TLSA records
+	96:  CategoryTransport,         // DANE TLSA parameters
+	97:  CategoryTransport,         // DANE matching types
+	98:  CategoryTransport,         // DANE certificate verification
+	99:  CategoryReputation,        // BIMI record
+	100: CategoryReputation,        // BIMI DMARC requirement
+	101: CategoryReputation,        // BIMI logo
+	102: CategoryReputation,        // BIMI mark certificate
+	103: CategoryReputation,        // BIMI certificate logo
+}
+
 // RuleResult represents the outcome of a rule check
 type RuleResult struct {
-	RuleID      int    `json:"rule_id"`
-	Description string `json:"description"`
-	Status      string `json:"status"` // "warning", "error", "info", "pass"
-	Message     string `json:"message"`
+	RuleID      int      `json:"rule_id"`
+	Category    Category `json:"category"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"` // "warning", "error", "info", "pass"
+	Message     string   `json:"message"`
+	Confidence  string   `json:"confidence"`            // high, medium or low; defaults to high
+	Remediation string   `json:"remediation,omitempty"` // Suggested corrected record, if one can be derived
+}
+
+// CategoryResults groups the rule results belonging to a single category
+type CategoryResults struct {
+	Category Category     `json:"category"`
+	Results  []RuleResult `json:"results"`
+}
+
+// Settings contains the thresholds of configurable rules
+type Settings struct {
+	DKIMRotationMonths   int // Age in months after which a date-stamped DKIM selector should have been rotated
+	SignatureWarningDays int // Most days before an RRSIG expires that the expiration is reported
+}
+
+// DefaultSettings returns the settings used when none are configured
+func DefaultSettings() Settings {
+	return Settings{
+		DKIMRotationMonths:   12,
+		SignatureWarningDays: 7,
+	}
 }
 
 // EnhancedDomainInfo wraps DomainInfo with additional rule check results
 type EnhancedDomainInfo struct {
 	*dns.DomainInfo
-	RuleResults []RuleResult `json:"rule_results,omitempty"`
+	Settings       Settings          `json:"-"`
+	RuleResults    []RuleResult      `json:"-"`
+	RuleCategories []CategoryResults `json:"rule_categories,omitempty"`
 }
 
-// NewEnhancedDomainInfo creates a new EnhancedDomainInfo from a DomainInfo
+// NewEnhancedDomainInfo creates a new EnhancedDomainInfo from a DomainInfo using the default settings
 func NewEnhancedDomainInfo(info *dns.DomainInfo) *EnhancedDomainInfo {
+	return NewEnhancedDomainInfoWithSettings(info, DefaultSettings())
+}
+
+// NewEnhancedDomainInfoWithSettings creates a new EnhancedDomainInfo whose rules use the given settings
+func NewEnhancedDomainInfoWithSettings(info *dns.DomainInfo, settings Settings) *EnhancedDomainInfo {
 	return &EnhancedDomainInfo{
 		DomainInfo:  info,
+		Settings:    settings,
 		RuleResults: []RuleResult{},
 	}
 }
 
 // ApplyAllRules runs all available rules against the domain info
 func ApplyAllRules(info *EnhancedDomainInfo) {
+	// Hostnames and bare IPs only get the reduced host rule set
+	if info.HostInfo != nil {
+		ApplyHostRules(info)
+		return
+	}
+
 	// Apply SPF rules
 	CheckSPFPtrUsage(info)
 	CheckSPFIncludeLimit(info)
 	CheckSPFAllMechanism(info)
 	CheckSPFExists(info)
+	CheckSPFProviders(info)
+	CheckSPFCoversMX(info)
 
 	// Apply DMARC rules
-	CheckDMARCPolicy(info)
-	CheckDMARCExists(info)
+	checkDMARCRules(info)
 
 	// Apply DKIM rules
 	CheckDKIMExists(info)
+	CheckDKIMKeyLength(info)
+	CheckDKIMKeyAlgorithms(info)
+	CheckDKIMRevokedKeys(info)
+	CheckDKIMTestingMode(info)
+	CheckDKIMSuppliedSelectors(info)
+	CheckDKIMDelegatedSelectors(info)
+	CheckDKIMSharedKeys(info)
+	CheckDKIMSyntax(info)
+	CheckDKIMHashAlgorithms(info)
+	CheckDKIMTCPOnly(info)
+	CheckDKIMSelectorRotation(info)
 
 	// Apply DNSSEC rules
 	CheckDNSSECEnabled(info)
+	CheckDNSSECDSMatchesDNSKEY(info)
+	CheckDNSSECAlgorithmStrength(info)
+	CheckDNSSECKeySize(info)
+	CheckDNSSECSignatureExpiration(info)
+	CheckDNSSECNSEC3Parameters(info)
+	CheckDNSSECMailRRsets(info)
+	CheckDNSSECMXZones(info)
+	CheckDNSSECKeyRollover(info)
+	CheckDNSSECDSDigests(info)
+	CheckDNSSECUnsupportedAlgorithms(info)
+	CheckDNSSECValidation(info)
+
+	// Apply TTL rules
+	CheckPolicyRecordTTLs(info)
+	CheckMXTTL(info)
+
+	// Apply TXT rules
+	CheckTXTRecordSize(info)
+
+	// Apply zone rules
+	CheckSOATimers(info)
+	CheckSOASerial(info)
+	CheckSOAMName(info)
+	CheckNegativeCachingTTL(info)
+	CheckNSConsistency(info)
+	CheckLameDelegation(info)
+	CheckZoneTransfer(info)
+	CheckNSRedundancy(info)
+	CheckNSDiversity(info)
+	CheckNSIPv6(info)
+	CheckNameserverRecordConsistency(info)
+
+	// Apply MTA-STS rules
+	CheckMTASTSExists(info)
+	CheckMTASTSSyntax(info)
+	CheckMTASTSMXCoverage(info)
+	CheckMTASTSMode(info)
+	CheckMTASTSMaxAge(info)
+
+	// Apply BIMI rules
+	CheckBIMIRecord(info)
+	CheckBIMIDMARCPolicy(info)
+	CheckBIMILogo(info)
+	CheckBIMIMarkCertificate(info)
+	CheckBIMICertificateLogo(info)
+
+	// Apply DANE rules
+	CheckDANEExists(info)
+	CheckDANEParameters(info)
+	CheckDANEMatchingTypes(info)
+	CheckDANEVerification(info)
+
+	// Apply CAA rules
+	CheckCAARecords(info)
+	CheckMXCAA(info)
 
 	// Apply MX rules
 	CheckMXExists(info)
+	CheckImplicitMX(info)
 	CheckMXHasIPs(info)
 	CheckMXHasIPv6(info)
 	CheckMXRedundancy(info)
 	CheckMXTooMany(info)
 	CheckMXLocalhost(info)
 	CheckMXPrivateIPs(info)
+	CheckMXIPLiteral(info)
+	CheckMXHostnameSyntax(info)
+	CheckMXDuplicates(info)
+	CheckMXPriorities(info)
+	CheckMXReverseDNS(info)
+	CheckMXNetworkDiversity(info)
+	CheckMXMixedProviders(info)
+	CheckMXWebHosting(info)
+
+	// Apply subdomain rules
+	CheckSubdomainSPFCoverage(info)
+	CheckSubdomainDMARCOverrides(info)
+	CheckWildcardDNS(info)
+
+	// Apply internationalized domain rules
+	CheckMixedScripts(info)
+
+	// Apply website rules
+	CheckWebPresence(info)
+	CheckParkedDomainLockdown(info)
 
 	// etc.
+
+	categorizeResults(info)
+}
+
+// ApplyDMARCRules runs only the DMARC rules, e.g. against a record that was not looked up in DNS
+func ApplyDMARCRules(info *EnhancedDomainInfo) {
+	checkDMARCRules(info)
+	categorizeResults(info)
+}
+
+// checkDMARCRules runs all DMARC rules without categorizing the results
+func checkDMARCRules(info *EnhancedDomainInfo) {
+	CheckDMARCPolicy(info)
+	CheckDMARCExists(info)
+	CheckDMARCSyntax(info)
+	CheckDMARCStrictSyntax(info)
+	CheckDMARCUnknownTags(info)
+	CheckDMARCReportURIs(info)
+	CheckDMARCReportVendors(info)
+	CheckDMARCExternalReportAuthorization(info)
+	CheckDMARCReportDestinations(info)
+	CheckDMARCPercentage(info)
+	CheckDMARCInheritance(info)
+	CheckDMARCHosted(info)
+	CheckDMARCAlignmentFeasibility(info)
+	CheckDMARCFailureOptions(info)
+	CheckDMARCFailureReportPrivacy(info)
+	CheckDMARCRedundantTags(info)
+	CheckDMARCReportInterval(info)
+}
+
+// ApplyHostRules runs the reduced rule set for a mail server hostname or bare IP
+func ApplyHostRules(info *EnhancedDomainInfo) {
+	CheckHostFCrDNS(info)
+	CheckHostBlocklists(info)
+	CheckHostSMTPTLS(info)
+
+	categorizeResults(info)
+}
+
+// ApplyErrorRules records that the check of a domain aborted with an internal error
+func ApplyErrorRules(info *EnhancedDomainInfo, message string) {
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      31,
+		Description: "Check completed",
+		Status:      "fail",
+		Message:     fmt.Sprintf("The check of %s aborted with an internal error: %s. The results for this domain are incomplete.", info.Domain, message),
+	})
+
+	categorizeResults(info)
+}
+
+// categorizeResults attaches a category (and default confidence) to every rule result and groups them per category
+func categorizeResults(info *EnhancedDomainInfo) {
+	grouped := make(map[Category][]RuleResult)
+	for i := range info.RuleResults {
+		category, ok := ruleCategories[info.RuleResults[i].RuleID]
+		if !ok {
+			category = CategoryHygiene
+		}
+		info.RuleResults[i].Category = category
+		if info.RuleResults[i].Confidence == "" {
+			info.RuleResults[i].Confidence = ConfidenceHigh
+		}
+		grouped[category] = append(grouped[category], info.RuleResults[i])
+	}
+
+	info.RuleCategories = []CategoryResults{}
+	for _, category := range Categories {
+		if results, ok := grouped[category]; ok {
+			info.RuleCategories = append(info.RuleCategories, CategoryResults{
+				Category: category,
+				Results:  results,
+			})
+		}
+	}
 }
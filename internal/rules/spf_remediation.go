@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"strings"
+
+	"check-maildomain/internal/spf"
+)
+
+// maxSPFLookups is the RFC 7208 limit on DNS-querying terms in an SPF evaluation
+const maxSPFLookups = 10
+
+// remediateSPFMissing suggests a starting SPF record for a domain without one
+func remediateSPFMissing(info *EnhancedDomainInfo) string {
+	if len(info.MXRecords) > 0 {
+		return "v=spf1 mx ~all"
+	}
+	return "v=spf1 -all"
+}
+
+// remediateSPFAll returns the record with +all and ?all replaced by ~all, or ~all appended when no all mechanism
+// exists. Nothing is suggested for a record without all that redirects, since an all mechanism would make
+// receivers ignore the redirect (RFC 7208 section 6.1).
+func remediateSPFAll(record *spf.SPFRecord) string {
+	var terms []spf.Term
+	hasAll, hasRedirect := false, false
+	for _, term := range record.Terms {
+		if term.IsMechanism("all") {
+			hasAll = true
+			if term.Qualifier == "+" || term.Qualifier == "?" {
+				term = spf.ParseTerm("~all")
+			}
+		}
+		if term.Modifier && term.Mechanism == "redirect" {
+			hasRedirect = true
+		}
+		terms = append(terms, term)
+	}
+
+	if !hasAll {
+		if hasRedirect {
+			return ""
+		}
+		terms = appendBeforeModifiers(terms, spf.ParseTerm("~all"))
+	}
+
+	return spf.BuildRecord(terms)
+}
+
+// remediateSPFPtr returns the record with all ptr mechanisms removed
+func remediateSPFPtr(record *spf.SPFRecord) string {
+	var terms []spf.Term
+	for _, term := range record.Terms {
+		if !term.IsMechanism("ptr") {
+			terms = append(terms, term)
+		}
+	}
+	return spf.BuildRecord(terms)
+}
+
+// remediateSPFIncludes keeps as many include mechanisms as the lookup limit allows, charging each its
+// recursive lookup count, and flattens the remaining ones into the ip4/ip6 networks they currently resolve
+// to. Includes that match more than their networks are kept as they are. No record is suggested when the
+// expansion is incomplete or the kept terms alone exceed the limit.
+func remediateSPFIncludes(record *spf.SPFRecord, expansion *spf.Expansion) string {
+	if expansion == nil || expansion.Incomplete {
+		return ""
+	}
+
+	flattenable := func(term spf.Term) bool {
+		return term.Qualifier == "+" && !expansion.UnflattenableVia[term.Raw]
+	}
+
+	// Charge everything that can't be flattened first
+	budget := maxSPFLookups
+	for _, term := range record.Terms {
+		if term.IsMechanism("include") && flattenable(term) {
+			continue
+		}
+		budget -= expansion.LookupsVia[term.Raw]
+	}
+	if budget < 0 {
+		return ""
+	}
+
+	var terms []spf.Term
+	for _, term := range record.Terms {
+		if !term.IsMechanism("include") || !flattenable(term) {
+			terms = append(terms, term)
+			continue
+		}
+
+		if cost := expansion.LookupsVia[term.Raw]; cost <= budget {
+			budget -= cost
+			terms = append(terms, term)
+			continue
+		}
+
+		// Flatten this include into its resolved networks
+		for _, network := range expansion.NetworksVia(term.Raw) {
+			mechanism := "ip4:"
+			if strings.Contains(network.CIDR, ":") {
+				mechanism = "ip6:"
+			}
+			terms = append(terms, spf.ParseTerm(mechanism+strings.TrimSuffix(strings.TrimSuffix(network.CIDR, "/32"), "/128")))
+		}
+	}
+
+	return spf.BuildRecord(terms)
+}
+
+// appendBeforeModifiers appends a mechanism after the last mechanism but before any modifiers
+func appendBeforeModifiers(terms []spf.Term, term spf.Term) []spf.Term {
+	for i, t := range terms {
+		if t.Modifier {
+			result := append([]spf.Term{}, terms[:i]...)
+			result = append(result, term)
+			return append(result, terms[i:]...)
+		}
+	}
+	return append(terms, term)
+}
@@ -0,0 +1,28 @@
+package rules
+
+import (
+	"testing"
+
+	"check-maildomain/internal/spf"
+)
+
+func TestRemediateSPFAll(t *testing.T) {
+	tests := []struct {
+		record string
+		want   string
+	}{
+		{"v=spf1 mx ?all", "v=spf1 mx ~all"},
+		{"v=spf1 mx +all", "v=spf1 mx ~all"},
+		{"v=spf1 mx all", "v=spf1 mx ~all"},
+		{"v=spf1 mx", "v=spf1 mx ~all"},
+		{"v=spf1 mx exp=explain._spf.example.com", "v=spf1 mx ~all exp=explain._spf.example.com"},
+		// An all mechanism would disable the redirect
+		{"v=spf1 redirect=_spf.example.com", ""},
+		{"v=spf1 mx redirect=_spf.example.com", ""},
+	}
+	for _, test := range tests {
+		if got := remediateSPFAll(spf.ParseSPF(test.record)); got != test.want {
+			t.Errorf("remediateSPFAll(%q) = %q, want %q", test.record, got, test.want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package rules
 
 import (
 	"fmt"
+	"net"
 	"strings"
+
+	"check-maildomain/internal/spf"
 )
 
 // CheckSPFPtrUsage checks if SPF record uses the deprecated ptr: mechanism
@@ -13,12 +16,13 @@ func CheckSPFPtrUsage(info *EnhancedDomainInfo) {
 	}
 
 	for _, term := range info.SPFRecord.Terms {
-		if strings.HasPrefix(term, "ptr") || term == "ptr" {
+		if term.IsMechanism("ptr") {
 			info.RuleResults = append(info.RuleResults, RuleResult{
 				RuleID:      1,
 				Description: "SPF record uses deprecated ptr: mechanism",
 				Status:      "warning",
 				Message:     "The ptr: mechanism in SPF records is deprecated due to performance issues and should be avoided",
+				Remediation: remediateSPFPtr(info.SPFRecord),
 			})
 			return
 		}
@@ -40,12 +44,7 @@ func CheckSPFIncludeLimit(info *EnhancedDomainInfo) {
 		return
 	}
 
-	includeCount := 0
-	for _, term := range info.SPFRecord.Terms {
-		if strings.HasPrefix(term, "include:") {
-			includeCount++
-		}
-	}
+	includeCount := len(info.SPFRecord.Mechanisms("include"))
 
 	if includeCount > 10 {
 		info.RuleResults = append(info.RuleResults, RuleResult{
@@ -53,6 +52,7 @@ func CheckSPFIncludeLimit(info *EnhancedDomainInfo) {
 			Description: "SPF record has too many include mechanisms",
 			Status:      "fail",
 			Message:     "SPF record contains more than 10 include mechanisms. Consider using SPF flattening to reduce lookup complexity.",
+			Remediation: remediateSPFIncludes(info.SPFRecord, info.SPFExpansion),
 		})
 	} else {
 		info.RuleResults = append(info.RuleResults, RuleResult{
@@ -75,12 +75,11 @@ func CheckSPFAllMechanism(info *EnhancedDomainInfo) {
 	hasProperAll := false
 	hasPositiveAll := false
 
-	for _, term := range info.SPFRecord.Terms {
-		term = strings.TrimSpace(term)
-		if term == "-all" || term == "~all" {
+	for _, term := range info.SPFRecord.Mechanisms("all") {
+		if term.Qualifier == "-" || term.Qualifier == "~" {
 			hasProperAll = true
 			break
-		} else if term == "+all" || term == "all" {
+		} else if term.Qualifier == "+" {
 			hasPositiveAll = true
 			break
 		}
@@ -92,6 +91,7 @@ func CheckSPFAllMechanism(info *EnhancedDomainInfo) {
 			Description: "SPF record uses +all",
 			Status:      "fail",
 			Message:     "SPF record uses +all which allows any server to send mail for your domain. Use -all or ~all instead.",
+			Remediation: remediateSPFAll(info.SPFRecord),
 		})
 	} else if hasProperAll {
 		info.RuleResults = append(info.RuleResults, RuleResult{
@@ -106,6 +106,7 @@ func CheckSPFAllMechanism(info *EnhancedDomainInfo) {
 			Description: "SPF record missing all mechanism",
 			Status:      "fail",
 			Message:     "SPF record doesn't have an 'all' mechanism. Add -all or ~all at the end of your SPF record.",
+			Remediation: remediateSPFAll(info.SPFRecord),
 		})
 	}
 }
@@ -119,6 +120,7 @@ func CheckSPFExists(info *EnhancedDomainInfo) {
 			Description: "SPF record existence",
 			Status:      "fail",
 			Message:     "No SPF record was found for this domain. SPF is important for preventing email spoofing. Add an SPF record to specify which servers are authorized to send email for your domain.",
+			Remediation: remediateSPFMissing(info),
 		})
 	} else {
 		info.RuleResults = append(info.RuleResults, RuleResult{
@@ -129,3 +131,97 @@ func CheckSPFExists(info *EnhancedDomainInfo) {
 		})
 	}
 }
+
+// CheckSPFProviders reports which well-known email providers are authorized by the SPF record
+func CheckSPFProviders(info *EnhancedDomainInfo) {
+	if info.SPFRecord == nil {
+		// No SPF record to check
+		return
+	}
+
+	var unknown []string
+	for _, term := range info.SPFRecord.Mechanisms("include") {
+		if _, ok := spf.LookupProvider(term.Value); !ok {
+			unknown = append(unknown, term.Value)
+		}
+	}
+
+	message := "No well-known email providers were identified in the SPF includes."
+	if len(info.Providers) > 0 {
+		message = fmt.Sprintf("SPF record authorizes the following email providers: %s.", strings.Join(info.Providers, ", "))
+	}
+	if len(unknown) > 0 {
+		message += fmt.Sprintf(" Unrecognized includes: %s.", strings.Join(unknown, ", "))
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      16,
+		Description: "SPF authorized providers",
+		Status:      "info",
+		Message:     message,
+	})
+}
+
+// CheckSPFCoversMX verifies that the domain's own MX hosts are permitted to send mail by its SPF record
+func CheckSPFCoversMX(info *EnhancedDomainInfo) {
+	if info.SPFRecord == nil || info.SPFExpansion == nil || len(info.MXRecords) == 0 {
+		// Nothing to cross-check
+		return
+	}
+
+	if info.SPFExpansion.PassAll {
+		// +all is already reported by CheckSPFAllMechanism
+		return
+	}
+
+	checked := 0
+	var uncovered []string
+	for _, mx := range info.MXRecords {
+		for _, record := range mx.Records {
+			if record.Type != "A" && record.Type != "AAAA" {
+				continue
+			}
+
+			ip := net.ParseIP(record.Value)
+			if ip == nil {
+				continue
+			}
+
+			checked++
+			if _, ok := info.SPFExpansion.Contains(ip); !ok {
+				uncovered = append(uncovered, fmt.Sprintf("%s (%s)", mx.Host, record.Value))
+			}
+		}
+	}
+
+	if checked == 0 {
+		// No resolved MX addresses to check
+		return
+	}
+
+	incomplete := ""
+	confidence := ConfidenceHigh
+	if len(info.SPFExpansion.Unresolved) > 0 {
+		confidence = ConfidenceMedium
+		incomplete = fmt.Sprintf(" Note: some SPF terms could not be evaluated (%s), so this result may be incomplete.", strings.Join(info.SPFExpansion.Unresolved, ", "))
+	}
+
+	if len(uncovered) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      17,
+			Description: "MX hosts permitted by SPF",
+			Status:      "warn",
+			Confidence:  confidence,
+			Message: fmt.Sprintf("The following MX addresses are not permitted to send mail by the SPF record: %s. Mail sent or forwarded by your own inbound servers (bounces, forwards) may fail SPF.%s",
+				strings.Join(uncovered, ", "), incomplete),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      17,
+			Description: "MX hosts permitted by SPF",
+			Status:      "pass",
+			Confidence:  confidence,
+			Message:     fmt.Sprintf("All %d MX addresses are permitted to send mail by the SPF record.%s", checked, incomplete),
+		})
+	}
+}
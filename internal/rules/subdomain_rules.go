@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckSubdomainSPFCoverage reports scanned subdomains that can be used for mail but have no SPF record
+func CheckSubdomainSPFCoverage(info *EnhancedDomainInfo) {
+	if info.Subdomains == nil {
+		// Subdomain scan was not requested
+		return
+	}
+
+	var existing, unprotected []string
+	for _, sub := range info.Subdomains {
+		if !sub.Exists {
+			continue
+		}
+		existing = append(existing, sub.Domain)
+
+		if sub.CanSendMail() && sub.SPFRecord == nil {
+			unprotected = append(unprotected, sub.Domain)
+		}
+	}
+
+	if len(existing) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      18,
+			Description: "Subdomain SPF coverage",
+			Status:      "info",
+			Message:     fmt.Sprintf("None of the %d scanned subdomains exist.", len(info.Subdomains)),
+		})
+		return
+	}
+
+	if len(unprotected) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      18,
+			Description: "Subdomain SPF coverage",
+			Status:      "warn",
+			Message: fmt.Sprintf("The following subdomains have MX or address records but no SPF record: %s. Attackers can spoof mail from unprotected subdomains; publish \"v=spf1 -all\" on subdomains that don't send mail and make sure the DMARC sp tag covers them.",
+				strings.Join(unprotected, ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      18,
+			Description: "Subdomain SPF coverage",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All existing subdomains that can be used for mail have an SPF record (%s).", strings.Join(existing, ", ")),
+		})
+	}
+}
+
+// policyStrength orders the DMARC policies from weakest to strongest
+var policyStrength = map[string]int{
+	"none":       0,
+	"quarantine": 1,
+	"reject":     2,
+}
+
+// CheckSubdomainDMARCOverrides reports scanned subdomains with an explicit DMARC record and flags
+// those that weaken the policy the organizational domain applies to its subdomains
+func CheckSubdomainDMARCOverrides(info *EnhancedDomainInfo) {
+	if info.Subdomains == nil {
+		// Subdomain scan was not requested
+		return
+	}
+
+	var overrides, weaker []string
+	for _, sub := range info.Subdomains {
+		if sub.DMARCRecord == nil {
+			continue
+		}
+		policy := strings.ToLower(sub.DMARCRecord.Tags["p"])
+		overrides = append(overrides, fmt.Sprintf("%s (p=%s)", sub.Domain, policy))
+
+		if info.DMARCRecord == nil {
+			continue
+		}
+		parentPolicy := strings.ToLower(info.DMARCPolicy.SubdomainPolicy)
+		subStrength, ok := policyStrength[policy]
+		if !ok {
+			// An invalid policy makes the record unusable, receivers fall back to no policy
+			subStrength = -1
+		}
+		if parentStrength, ok := policyStrength[parentPolicy]; ok && subStrength < parentStrength {
+			weaker = append(weaker, fmt.Sprintf("%s (p=%s, organizational sp=%s)", sub.Domain, policy, parentPolicy))
+		}
+	}
+
+	if len(overrides) == 0 {
+		return
+	}
+
+	if len(weaker) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      36,
+			Description: "Subdomain DMARC overrides",
+			Status:      "warn",
+			Message:     fmt.Sprintf("The following subdomains publish their own DMARC record with a weaker policy than the organizational domain applies to subdomains: %s. Their explicit record overrides the parent policy, so spoofed mail from them is treated more leniently.", strings.Join(weaker, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      36,
+			Description: "Subdomain DMARC overrides",
+			Status:      "info",
+			Message:     fmt.Sprintf("The following subdomains publish their own DMARC record, overriding the organizational policy without weakening it: %s.", strings.Join(overrides, "; ")),
+		})
+	}
+}
+
+// CheckWildcardDNS reports a wildcard under the domain. Wildcards answer for any name, which makes DKIM
+// selector discovery, the subdomain scan and checks for dangling records report names that don't exist.
+func CheckWildcardDNS(info *EnhancedDomainInfo) {
+	if info.Wildcard == nil {
+		return
+	}
+
+	var answers []string
+	if len(info.Wildcard.Types) > 0 {
+		answers = append(answers, fmt.Sprintf("%s records for %s", strings.Join(info.Wildcard.Types, ", "), info.Wildcard.Name))
+	}
+	if info.Wildcard.DKIM {
+		answers = append(answers, "TXT records below _domainkey, so every guessed DKIM selector appears to exist")
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      74,
+		Description: "Wildcard DNS",
+		Status:      "warn",
+		Message: fmt.Sprintf("The domain has a wildcard record: a random non-existent name returns %s. Wildcards make DKIM selector discovery, the subdomain scan and MX checks unreliable, and a wildcard MX or TXT record applies to every subdomain. Replace the wildcard with explicit records where possible.",
+			strings.Join(answers, "; ")),
+	})
+}
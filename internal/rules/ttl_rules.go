@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	minPolicyTTL = 300       // Below 5 minutes the record is queried needlessly often
+	maxPolicyTTL = 7 * 86400 // Above 7 days changes take too long to reach receivers
+	minMXTTL     = 300       // Below 5 minutes an outage of the authoritative servers quickly breaks delivery
+	maxMXTTL     = 2 * 86400 // Above 2 days moving mail to another server during an incident takes too long
+)
+
+// CheckPolicyRecordTTLs warns on extreme TTLs of the SPF, DMARC and DKIM TXT records, which complicate
+// staged rollouts and incident response
+func CheckPolicyRecordTTLs(info *EnhancedDomainInfo) {
+	ttls := make(map[string]uint32)
+	if info.SPFRecord != nil && info.SPFRecord.TTLKnown {
+		ttls["SPF ("+info.Domain+")"] = info.SPFRecord.TTL
+	}
+	if info.DMARCRecord != nil && info.DMARCRecord.TTLKnown {
+		ttls["DMARC ("+info.DMARCRecord.Location+")"] = info.DMARCRecord.TTL
+	}
+	if info.DKIMInfo != nil {
+		for selector, ttl := range info.DKIMInfo.SelectorTTLs {
+			ttls["DKIM ("+selector+"._domainkey."+info.Domain+")"] = ttl
+		}
+	}
+
+	if len(ttls) == 0 {
+		// No authoritative nameserver could be queried for the TTLs
+		return
+	}
+
+	var names []string
+	for name := range ttls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tooLow, tooHigh, all []string
+	for _, name := range names {
+		ttl := ttls[name]
+		all = append(all, fmt.Sprintf("%s %ds", name, ttl))
+		if ttl < minPolicyTTL {
+			tooLow = append(tooLow, fmt.Sprintf("%s %ds", name, ttl))
+		} else if ttl > maxPolicyTTL {
+			tooHigh = append(tooHigh, fmt.Sprintf("%s %ds", name, ttl))
+		}
+	}
+
+	if len(tooLow) == 0 && len(tooHigh) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      40,
+			Description: "Policy record TTLs",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All policy record TTLs are between 5 minutes and 7 days: %s.", strings.Join(all, ", ")),
+		})
+		return
+	}
+
+	var problems []string
+	if len(tooLow) > 0 {
+		problems = append(problems, fmt.Sprintf("TTLs below 5 minutes cause needless query load and make lookups fragile: %s", strings.Join(tooLow, ", ")))
+	}
+	if len(tooHigh) > 0 {
+		problems = append(problems, fmt.Sprintf("TTLs above 7 days delay policy changes and key rotations: %s", strings.Join(tooHigh, ", ")))
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      40,
+		Description: "Policy record TTLs",
+		Status:      "warn",
+		Message:     strings.Join(problems, ". ") + ". A TTL of one hour to one day is a good balance; lower it temporarily before planned changes.",
+	})
+}
+
+// CheckMXTTL warns on very low or very high TTLs of the MX records, which affect failover during incidents
+func CheckMXTTL(info *EnhancedDomainInfo) {
+	var tooLow, tooHigh, all []string
+	for _, mx := range info.MXRecords {
+		if !mx.TTLKnown {
+			// No authoritative nameserver could be queried, or the system resolver fallback was used
+			continue
+		}
+		description := fmt.Sprintf("%s %ds", mx.Host, mx.TTL)
+		all = append(all, description)
+		if mx.TTL < minMXTTL {
+			tooLow = append(tooLow, description)
+		} else if mx.TTL > maxMXTTL {
+			tooHigh = append(tooHigh, description)
+		}
+	}
+
+	if len(all) == 0 {
+		return
+	}
+
+	if len(tooLow) == 0 && len(tooHigh) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      67,
+			Description: "MX record TTL",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All MX record TTLs are between 5 minutes and 2 days: %s.", strings.Join(all, ", ")),
+		})
+		return
+	}
+
+	var problems []string
+	if len(tooLow) > 0 {
+		problems = append(problems, fmt.Sprintf("TTLs below 5 minutes make delivery depend on the nameservers answering at all times: %s", strings.Join(tooLow, ", ")))
+	}
+	if len(tooHigh) > 0 {
+		problems = append(problems, fmt.Sprintf("TTLs above 2 days keep senders on the old servers long after a change: %s", strings.Join(tooHigh, ", ")))
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      67,
+		Description: "MX record TTL",
+		Status:      "warn",
+		Message:     strings.Join(problems, ". ") + ". A TTL of one hour to one day keeps failover to other servers quick without depending on constant lookups.",
+	})
+}
@@ -0,0 +1,48 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"check-maildomain/internal/txt"
+)
+
+// CheckTXTRecordSize warns when the TXT records at the apex are too large for a single UDP response. Every
+// SPF and DMARC evaluation then needs a retry over TCP, which fails behind firewalls that block DNS over TCP.
+func CheckTXTRecordSize(info *EnhancedDomainInfo) {
+	if info.TXTRecords == nil || len(info.TXTRecords.Records) == 0 {
+		return
+	}
+
+	if !info.TXTRecords.Truncated && info.TXTRecords.ResponseSize <= txt.SafeUDPSize {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      75,
+			Description: "TXT record size",
+			Status:      "pass",
+			Message: fmt.Sprintf("The %d TXT records at the apex total %d bytes, the response of %d bytes fits in a single UDP packet.",
+				len(info.TXTRecords.Records), info.TXTRecords.Size, info.TXTRecords.ResponseSize),
+		})
+		return
+	}
+
+	seen := make(map[string]bool)
+	var verification []string
+	for _, record := range info.TXTRecords.Records {
+		if record.Category == txt.CategoryVerification && !seen[record.Service] {
+			seen[record.Service] = true
+			verification = append(verification, record.Service)
+		}
+	}
+	advice := "Remove records that are no longer needed."
+	if len(verification) > 0 {
+		advice = fmt.Sprintf("Remove records that are no longer needed, such as the site verification tokens (%s) of services that have verified the domain already.", strings.Join(verification, ", "))
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      75,
+		Description: "TXT record size",
+		Status:      "warn",
+		Message: fmt.Sprintf("The %d TXT records at the apex total %d bytes and the response of %d bytes exceeds %d bytes, so it is truncated over UDP and resolvers must retry over TCP. SPF lookups fail where DNS over TCP is blocked. %s",
+			len(info.TXTRecords.Records), info.TXTRecords.Size, info.TXTRecords.ResponseSize, txt.SafeUDPSize, advice),
+	})
+}
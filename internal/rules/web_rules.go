@@ -0,0 +1,78 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"check-maildomain/internal/web"
+)
+
+// CheckWebPresence reports whether the domain hosts an active website, a parking page or nothing at all
+func CheckWebPresence(info *EnhancedDomainInfo) {
+	if info.WebInfo == nil {
+		// Website probe was not requested
+		return
+	}
+
+	var message string
+	switch info.WebInfo.Status {
+	case web.StatusActive:
+		message = fmt.Sprintf("The domain hosts an active website at %s (HTTP %d).", info.WebInfo.FinalURL, info.WebInfo.StatusCode)
+	case web.StatusParked:
+		message = fmt.Sprintf("The domain appears to be parked: %s matched the parking indicator %q.", info.WebInfo.FinalURL, info.WebInfo.ParkingIndicator)
+	default:
+		message = "No website answered on the apex or www host. The domain may be unused; if it doesn't send mail, lock it down against spoofing."
+		if len(info.ApexAddresses) == 0 && len(info.WWWAddresses) == 0 {
+			message = "Neither the apex nor the www host has address records, so the domain hosts no website. The domain may be unused; if it doesn't send mail, lock it down against spoofing."
+		}
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      21,
+		Description: "Domain web presence",
+		Status:      "info",
+		Confidence:  ConfidenceMedium,
+		Message:     message,
+	})
+}
+
+// CheckParkedDomainLockdown verifies that a parked domain rejects all mail sent in its name
+func CheckParkedDomainLockdown(info *EnhancedDomainInfo) {
+	if info.WebInfo == nil || info.WebInfo.Status != web.StatusParked {
+		return
+	}
+
+	var recommendations []string
+
+	// A parked domain should authorize no senders at all
+	if info.SPFRecord == nil || len(info.SPFRecord.Terms) != 1 || !info.SPFRecord.Terms[0].IsMechanism("all") || info.SPFRecord.Terms[0].Qualifier != "-" {
+		recommendations = append(recommendations, "publish the SPF record \"v=spf1 -all\"")
+	}
+
+	if info.DMARCRecord == nil || info.DMARCPolicy.Policy != "reject" {
+		recommendations = append(recommendations, "publish the DMARC record \"v=DMARC1; p=reject;\" at _dmarc")
+	}
+
+	if len(info.MXRecords) > 0 && !(len(info.MXRecords) == 1 && info.MXRecords[0].Host == "") {
+		recommendations = append(recommendations, "replace the MX records with a Null MX record \"0 .\" (RFC 7505)")
+	}
+
+	if len(recommendations) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      22,
+			Description: "Parked domain lockdown",
+			Status:      "fail",
+			Confidence:  ConfidenceMedium,
+			Message: fmt.Sprintf("This domain appears to be parked but is not locked down against spoofing. To lock down the parked domain: %s. Optionally also publish an empty DKIM key \"v=DKIM1; p=\" at *._domainkey.",
+				strings.Join(recommendations, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      22,
+			Description: "Parked domain lockdown",
+			Status:      "pass",
+			Confidence:  ConfidenceMedium,
+			Message:     "This parked domain is locked down: SPF authorizes no senders, DMARC rejects all mail and no mail is accepted.",
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package rules
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minNegativeTTL = 300      // Below 5 minutes lookups of missing records hammer the nameservers
+	maxNegativeTTL = 3 * 3600 // Above 3 hours a fixed record stays invisible for too long (RFC 2308 recommends 1 to 3 hours)
+)
+
+// CheckSOATimers verifies that the refresh, retry and expire values of the SOA record are within the ranges
+// recommended by RFC 1912 and RIPE-203. The minimum is evaluated as negative caching TTL by its own rule.
+func CheckSOATimers(info *EnhancedDomainInfo) {
+	if info.SOA == nil {
+		return
+	}
+
+	soa := info.SOA
+	var problems []string
+	if soa.Refresh < 1200 || soa.Refresh > 86400 {
+		problems = append(problems, fmt.Sprintf("refresh %ds is outside 20 minutes to 1 day", soa.Refresh))
+	}
+	if soa.Retry < 120 || soa.Retry >= soa.Refresh {
+		problems = append(problems, fmt.Sprintf("retry %ds should be at least 2 minutes and less than the refresh", soa.Retry))
+	}
+	if soa.Expire < 604800 || soa.Expire > 3628800 {
+		problems = append(problems, fmt.Sprintf("expire %ds is outside 1 to 6 weeks", soa.Expire))
+	} else if soa.Expire < soa.Refresh+soa.Retry {
+		problems = append(problems, fmt.Sprintf("expire %ds is shorter than refresh plus retry", soa.Expire))
+	}
+
+	if len(problems) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      78,
+			Description: "SOA timers",
+			Status:      "warn",
+			Message: fmt.Sprintf("The SOA record of %s has unusual timer values: %s. Secondaries may serve stale mail records or stop answering during an outage of the primary.",
+				soa.Zone, strings.Join(problems, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      78,
+			Description: "SOA timers",
+			Status:      "pass",
+			Message: fmt.Sprintf("The SOA timers of %s are within the recommended ranges (refresh %ds, retry %ds, expire %ds).",
+				soa.Zone, soa.Refresh, soa.Retry, soa.Expire),
+		})
+	}
+}
+
+// CheckNegativeCachingTTL evaluates how long resolvers cache the absence of a record: the lower of the SOA
+// TTL and the SOA minimum (RFC 2308 section 5). A high value keeps a fixed DMARC or MX record invisible for
+// hours, a very low one makes resolvers repeat the queries for missing records constantly. The SOA TTL is
+// only known when the SOA was read from an authoritative nameserver, otherwise the minimum is used alone.
+func CheckNegativeCachingTTL(info *EnhancedDomainInfo) {
+	if info.SOA == nil {
+		return
+	}
+
+	ttl := info.SOA.Minimum
+	confidence := ConfidenceHigh
+	if !info.SOA.Authoritative {
+		confidence = ConfidenceMedium
+	} else if info.SOA.TTL < ttl {
+		ttl = info.SOA.TTL
+	}
+
+	switch {
+	case ttl > maxNegativeTTL:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      88,
+			Description: "Negative caching TTL",
+			Status:      "warn",
+			Confidence:  confidence,
+			Message: fmt.Sprintf("Resolvers cache missing records of %s for %s (%ds). After adding a missing DMARC, SPF or MX record, senders keep seeing it as absent that long. Lower the SOA minimum to one hour or less.",
+				info.SOA.Zone, formatDuration(ttl), ttl),
+		})
+	case ttl < minNegativeTTL:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      88,
+			Description: "Negative caching TTL",
+			Status:      "warn",
+			Confidence:  confidence,
+			Message: fmt.Sprintf("Resolvers cache missing records of %s for only %ds, so lookups of absent records such as unused DKIM selectors reach the nameservers constantly. Raise the SOA minimum to at least 5 minutes.",
+				info.SOA.Zone, ttl),
+		})
+	default:
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      88,
+			Description: "Negative caching TTL",
+			Status:      "pass",
+			Confidence:  confidence,
+			Message:     fmt.Sprintf("Resolvers cache missing records of %s for %s (%ds).", info.SOA.Zone, formatDuration(ttl), ttl),
+		})
+	}
+}
+
+// formatDuration formats a number of seconds as hours and minutes
+func formatDuration(seconds uint32) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// CheckSOASerial verifies that a serial number in the common YYYYMMDDnn format is a valid date that is not in
+// the future. A future serial can't be lowered again without serial number arithmetic (RFC 1982).
+func CheckSOASerial(info *EnhancedDomainInfo) {
+	if info.SOA == nil {
+		return
+	}
+
+	serial := strconv.FormatUint(uint64(info.SOA.Serial), 10)
+	if len(serial) != 10 || (!strings.HasPrefix(serial, "19") && !strings.HasPrefix(serial, "20")) {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      79,
+			Description: "SOA serial",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The SOA serial of %s is %s.", info.SOA.Zone, serial),
+		})
+		return
+	}
+
+	date, err := time.Parse("20060102", serial[:8])
+	if err != nil {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      79,
+			Description: "SOA serial",
+			Status:      "warn",
+			Confidence:  ConfidenceMedium,
+			Message:     fmt.Sprintf("The SOA serial %s of %s looks like the YYYYMMDDnn format, but %s is not a valid date. Check the tooling that updates the serial.", serial, info.SOA.Zone, serial[:8]),
+		})
+		return
+	}
+	if date.After(time.Now().AddDate(0, 0, 1)) {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      79,
+			Description: "SOA serial",
+			Status:      "warn",
+			Confidence:  ConfidenceMedium,
+			Message:     fmt.Sprintf("The SOA serial %s of %s is dated in the future (%s). Date based serials can only go up, so the zone keeps this serial until that date or needs a serial number wrap (RFC 1982) to fix.", serial, info.SOA.Zone, date.Format("2006-01-02")),
+		})
+		return
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      79,
+		Description: "SOA serial",
+		Status:      "pass",
+		Message:     fmt.Sprintf("The SOA serial %s of %s is a valid date based serial (%s).", serial, info.SOA.Zone, date.Format("2006-01-02")),
+	})
+}
+
+// CheckSOAMName verifies that the primary nameserver in the SOA record is one of the nameservers of the zone.
+// Hidden primary setups deliberately omit it, so a mismatch is not an error on its own.
+func CheckSOAMName(info *EnhancedDomainInfo) {
+	if info.SOA == nil || len(info.Nameservers) == 0 {
+		return
+	}
+
+	for _, ns := range info.Nameservers {
+		if strings.EqualFold(ns, info.SOA.MName) {
+			info.RuleResults = append(info.RuleResults, RuleResult{
+				RuleID:      80,
+				Description: "SOA MNAME",
+				Status:      "pass",
+				Message:     fmt.Sprintf("The primary nameserver %s in the SOA record is one of the nameservers of %s.", info.SOA.MName, info.SOA.Zone),
+			})
+			return
+		}
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      80,
+		Description: "SOA MNAME",
+		Status:      "warn",
+		Confidence:  ConfidenceMedium,
+		Message: fmt.Sprintf("The primary nameserver %s in the SOA record is not one of the nameservers of %s (%s). This is fine for a hidden primary, otherwise NOTIFY messages and dynamic updates go to the wrong server.",
+			info.SOA.MName, info.SOA.Zone, strings.Join(info.Nameservers, ", ")),
+	})
+}
+
+// CheckNSConsistency verifies that the delegation in the parent zone and the NS records published by every
+// authoritative nameserver name the same nameservers. Mismatches cause intermittent resolution failures,
+// depending on which server a resolver happens to ask.
+func CheckNSConsistency(info *EnhancedDomainInfo) {
+	if info.Delegation == nil || len(info.Delegation.Parent) == 0 {
+		// The delegation could not be retrieved, there is nothing to compare against
+		return
+	}
+
+	parent := strings.Join(info.Delegation.Parent, ", ")
+	var mismatches []string
+	compared := 0
+	for _, server := range info.Delegation.Servers {
+		if server.Error != "" {
+			continue
+		}
+		compared++
+		if strings.Join(server.NS, ", ") != parent {
+			mismatches = append(mismatches, fmt.Sprintf("%s publishes %s", server.Nameserver, strings.Join(server.NS, ", ")))
+		}
+	}
+
+	if compared == 0 {
+		return
+	}
+
+	if len(mismatches) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      81,
+			Description: "NS consistency",
+			Status:      "warn",
+			Message: fmt.Sprintf("The NS records of %s differ between the parent delegation (%s) and the zone: %s. Resolvers use either set, so lookups of the mail records fail intermittently when a server in one set is broken. Update the delegation at the registrar or the NS records in the zone so they match.",
+				info.Delegation.Zone, parent, strings.Join(mismatches, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      81,
+			Description: "NS consistency",
+			Status:      "pass",
+			Message:     fmt.Sprintf("The parent delegation and all %d authoritative nameservers agree on the NS records of %s: %s.", compared, info.Delegation.Zone, parent),
+		})
+	}
+}
+
+// CheckLameDelegation verifies that every nameserver of the zone answers authoritatively for it. Resolvers
+// that ask a lame or unreachable server time out or retry, which degrades the resolution of every mail record.
+func CheckLameDelegation(info *EnhancedDomainInfo) {
+	if info.Delegation == nil || len(info.Delegation.Servers) == 0 {
+		return
+	}
+
+	var lame, unreachable []string
+	for _, server := range info.Delegation.Servers {
+		switch {
+		case server.Lame:
+			lame = append(lame, server.Nameserver)
+		case !server.Reachable:
+			unreachable = append(unreachable, fmt.Sprintf("%s (%s)", server.Nameserver, server.Error))
+		}
+	}
+
+	if len(lame) == 0 && len(unreachable) == 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      83,
+			Description: "Lame delegation",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All %d nameservers answer authoritatively for %s.", len(info.Delegation.Servers), info.Delegation.Zone),
+		})
+		return
+	}
+
+	var problems []string
+	if len(lame) > 0 {
+		problems = append(problems, fmt.Sprintf("not authoritative (lame): %s", strings.Join(lame, ", ")))
+	}
+	if len(unreachable) > 0 {
+		problems = append(problems, fmt.Sprintf("unreachable: %s", strings.Join(unreachable, "; ")))
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      83,
+		Description: "Lame delegation",
+		Status:      "fail",
+		Message: fmt.Sprintf("Not every nameserver of %s serves the zone. %s. Resolvers asking these servers time out or retry, delaying or failing the lookups of the mail records. Fix the servers or remove them from the delegation and the NS records.",
+			info.Delegation.Zone, strings.Join(problems, ". ")),
+	})
+}
+
+// CheckZoneTransfer verifies that the nameservers refuse zone transfers to arbitrary clients. A transferred
+// zone exposes internal mail hosts and DKIM selectors.
+func CheckZoneTransfer(info *EnhancedDomainInfo) {
+	if len(info.ZoneTransfers) == 0 {
+		// Zone transfers were not attempted
+		return
+	}
+
+	var allowed []string
+	for _, transfer := range info.ZoneTransfers {
+		if transfer.Allowed {
+			allowed = append(allowed, fmt.Sprintf("%s (%d records)", transfer.Nameserver, transfer.Records))
+		}
+	}
+
+	if len(allowed) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      84,
+			Description: "Zone transfer",
+			Status:      "fail",
+			Message: fmt.Sprintf("The following nameservers allow anyone to transfer the zone: %s. The zone exposes every host, including internal mail servers and DKIM selectors. Restrict AXFR to the secondaries by IP address or TSIG key.",
+				strings.Join(allowed, ", ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      84,
+			Description: "Zone transfer",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All %d nameservers refused the zone transfer.", len(info.ZoneTransfers)),
+		})
+	}
+}
+
+// CheckNSRedundancy verifies that the zone has at least two nameservers, as required by RFC 1034 section 4.1
+func CheckNSRedundancy(info *EnhancedDomainInfo) {
+	if info.Delegation == nil || len(info.Delegation.Servers) == 0 {
+		return
+	}
+
+	if len(info.Delegation.Servers) < 2 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      85,
+			Description: "Nameserver redundancy",
+			Status:      "fail",
+			Message:     fmt.Sprintf("%s has a single nameserver (%s). When it is unavailable, senders can't look up the MX, SPF or DMARC records; add at least one more nameserver.", info.Delegation.Zone, info.Delegation.Servers[0].Nameserver),
+		})
+		return
+	}
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      85,
+		Description: "Nameserver redundancy",
+		Status:      "pass",
+		Message:     fmt.Sprintf("%s has %d nameservers.", info.Delegation.Zone, len(info.Delegation.Servers)),
+	})
+}
+
+// CheckNSDiversity warns when all nameservers share one /24 (IPv4) and one /48 (IPv6) network, or one
+// autonomous system. A single network outage or routing problem then makes the zone unresolvable.
+func CheckNSDiversity(info *EnhancedDomainInfo) {
+	if info.Delegation == nil || len(info.Delegation.Servers) < 2 {
+		// Redundancy is reported by its own rule
+		return
+	}
+
+	networks := make(map[string]bool)
+	var v4, v6 []string
+	asns := make(map[string]bool)
+	resolved := 0
+	for _, server := range info.Delegation.Servers {
+		if len(server.Addresses) == 0 {
+			continue
+		}
+		resolved++
+		for _, address := range server.Addresses {
+			ip := net.ParseIP(address)
+			if ip == nil {
+				continue
+			}
+			var network string
+			if ip4 := ip.To4(); ip4 != nil {
+				network = (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+				if !networks[network] {
+					v4 = append(v4, network)
+				}
+			} else {
+				network = (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+				if !networks[network] {
+					v6 = append(v6, network)
+				}
+			}
+			networks[network] = true
+		}
+		for _, asn := range server.ASNs {
+			asns[asn] = true
+		}
+	}
+
+	if resolved < 2 {
+		return
+	}
+
+	var problems []string
+	if len(networks) > 0 && len(v4) <= 1 && len(v6) <= 1 {
+		// Dual-stack nameservers in one IPv4 and one IPv6 network still share a single location
+		problems = append(problems, fmt.Sprintf("all nameserver addresses are in %s", strings.Join(append(v4, v6...), " and ")))
+	}
+	if len(asns) == 1 {
+		for asn := range asns {
+			problems = append(problems, fmt.Sprintf("all nameservers are announced by %s", asn))
+		}
+	}
+
+	if len(problems) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      86,
+			Description: "Nameserver diversity",
+			Status:      "warn",
+			Message: fmt.Sprintf("The nameservers of %s are not diverse: %s. A single network outage makes the zone, and with it the mail records, unresolvable. Add a nameserver in a different network, for example a secondary DNS service.",
+				info.Delegation.Zone, strings.Join(problems, "; ")),
+		})
+	} else {
+		message := fmt.Sprintf("The nameservers of %s are spread over %d networks", info.Delegation.Zone, len(v4)+len(v6))
+		if len(asns) > 0 {
+			message += fmt.Sprintf(" and %d autonomous systems", len(asns))
+		}
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      86,
+			Description: "Nameserver diversity",
+			Status:      "pass",
+			Message:     message + ".",
+		})
+	}
+}
+
+// CheckNSIPv6 warns when none of the nameservers has an IPv6 address, so IPv6-only resolvers can't resolve
+// the zone
+func CheckNSIPv6(info *EnhancedDomainInfo) {
+	if info.Delegation == nil || len(info.Delegation.Servers) == 0 {
+		return
+	}
+
+	resolved := false
+	for _, server := range info.Delegation.Servers {
+		for _, address := range server.Addresses {
+			resolved = true
+			if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+				info.RuleResults = append(info.RuleResults, RuleResult{
+					RuleID:      87,
+					Description: "Nameserver IPv6",
+					Status:      "pass",
+					Message:     fmt.Sprintf("At least one nameserver of %s is reachable over IPv6 (%s).", info.Delegation.Zone, server.Nameserver),
+				})
+				return
+			}
+		}
+	}
+	if !resolved {
+		return
+	}
+
+	info.RuleResults = append(info.RuleResults, RuleResult{
+		RuleID:      87,
+		Description: "Nameserver IPv6",
+		Status:      "warn",
+		Message:     fmt.Sprintf("None of the nameservers of %s has an IPv6 address, so IPv6-only resolvers can't look up the mail records. Add AAAA records for the nameservers or a nameserver with IPv6 connectivity.", info.Delegation.Zone),
+	})
+}
+
+// CheckNameserverRecordConsistency verifies that every authoritative nameserver serves the same mail records.
+// Stale secondaries are a frequent cause of problems that only some senders experience.
+func CheckNameserverRecordConsistency(info *EnhancedDomainInfo) {
+	if len(info.RecordComparison) == 0 {
+		// The comparison was not requested
+		return
+	}
+
+	var differences, unanswered []string
+	for _, comparison := range info.RecordComparison {
+		for server, problem := range comparison.Errors {
+			unanswered = append(unanswered, fmt.Sprintf("%s at %s (%s)", comparison.Label, server, problem))
+		}
+		if comparison.Consistent {
+			continue
+		}
+		var servers []string
+		for server := range comparison.Answers {
+			servers = append(servers, server)
+		}
+		sort.Strings(servers)
+
+		var answers []string
+		for _, server := range servers {
+			answer := strings.Join(comparison.Answers[server], " | ")
+			if answer == "" {
+				answer = "no record"
+			}
+			answers = append(answers, fmt.Sprintf("%s: %s", server, answer))
+		}
+		differences = append(differences, fmt.Sprintf("%s (%s %s) differs: %s", comparison.Label, comparison.Name, comparison.Type, strings.Join(answers, "; ")))
+	}
+
+	if len(differences) > 0 {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      82,
+			Description: "Mail records across nameservers",
+			Status:      "fail",
+			Message: fmt.Sprintf("The authoritative nameservers serve different mail records. %s. Senders see either version depending on the server their resolver asks; check zone transfers to the secondaries.",
+				strings.Join(differences, ". ")),
+		})
+	} else if len(unanswered) > 0 {
+		sort.Strings(unanswered)
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      82,
+			Description: "Mail records across nameservers",
+			Status:      "info",
+			Message:     fmt.Sprintf("The nameservers that answered serve the same mail records, but the following could not be compared: %s.", strings.Join(unanswered, "; ")),
+		})
+	} else {
+		info.RuleResults = append(info.RuleResults, RuleResult{
+			RuleID:      82,
+			Description: "Mail records across nameservers",
+			Status:      "pass",
+			Message:     fmt.Sprintf("All authoritative nameservers serve the same %d mail records.", len(info.RecordComparison)),
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package spf
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// maxExpandDepth limits include/redirect recursion to protect against loops
+const maxExpandDepth = 10
+
+// Network is an IP network authorized by an SPF record
+type Network struct {
+	CIDR   string // Network in CIDR notation
+	Source string // Domain whose SPF record contributed the network
+	Term   string // The term that produced the network
+	Via    string // Top-level term of the evaluated record through which the network was reached
+}
+
+// Expansion contains the networks authorized by an SPF record after recursively resolving
+// include, redirect, a and mx terms. Only pass ("+") qualified mechanisms contribute networks.
+type Expansion struct {
+	Networks         []Network       // Authorized networks
+	LookupCount      int             // Number of DNS-querying terms evaluated (RFC 7208 limit is 10)
+	LookupsVia       map[string]int  // DNS-querying terms evaluated through each top-level term, the term itself included
+	PassAll          bool            // Whether a +all mechanism was reached, authorizing every host
+	Unresolved       []string        // Terms that could not be expanded (exists, ptr, failed lookups)
+	UnflattenableVia map[string]bool // Top-level terms that match more than the networks reached through them
+	Incomplete       bool            // Whether an included or redirected record could not be expanded, making LookupCount a lower bound
+
+	matchers []matcher // Every mechanism in evaluation order, whatever its qualifier
+	scopes   int       // Number of includes expanded, to number their scopes
+}
+
+// matcher is a resolved mechanism in evaluation order, used to evaluate the record for an address
+type matcher struct {
+	network   *net.IPNet // Network the mechanism matches, nil for all
+	qualifier string     // Qualifier of the mechanism
+	scopes    []scope    // Includes through which the mechanism was reached, outermost first
+	result    Network    // What a match is reported as
+}
+
+// scope is an include mechanism whose record is being evaluated
+type scope struct {
+	id        int    // Unique number of the include
+	qualifier string // Qualifier of the include mechanism
+}
+
+// Expand recursively resolves the SPF record of a domain into the networks it authorizes
+func Expand(record *SPFRecord, domain string, nameserver string) *Expansion {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	expansion := &Expansion{
+		Networks:         []Network{},
+		LookupsVia:       make(map[string]int),
+		Unresolved:       []string{},
+		UnflattenableVia: make(map[string]bool),
+	}
+	visited := map[string]bool{strings.ToLower(domain): true}
+	expansion.expandRecord(record, domain, nameserver, 0, "", nil, visited)
+	return expansion
+}
+
+// NetworksVia returns the networks reached through the given top-level term
+func (e *Expansion) NetworksVia(term string) []Network {
+	var networks []Network
+	for _, network := range e.Networks {
+		if network.Via == term {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// Contains evaluates the record for the IP address the way a receiver does (RFC 7208 section 4.6): the
+// first matching mechanism decides, and an include only matches when its record passes the address. It
+// returns the matching network and whether the result is pass; the network of an all mechanism has no CIDR.
+func (e *Expansion) Contains(ip net.IP) (Network, bool) {
+	failed := make(map[int]bool)
+	for _, m := range e.matchers {
+		if m.network != nil && !m.network.Contains(ip) {
+			continue
+		}
+		if slices.ContainsFunc(m.scopes, func(s scope) bool { return failed[s.id] }) {
+			// The record of an enclosing include already decided without a pass
+			continue
+		}
+
+		// Walk outwards: an include matches with its own qualifier when its record passes
+		qualifier, matched := m.qualifier, true
+		for i := len(m.scopes) - 1; i >= 0; i-- {
+			if qualifier != "+" {
+				failed[m.scopes[i].id] = true
+				matched = false
+				break
+			}
+			qualifier = m.scopes[i].qualifier
+		}
+		if matched {
+			return m.result, qualifier == "+"
+		}
+	}
+	return Network{}, false
+}
+
+// lookup counts a DNS-querying term reached through the top-level term via
+func (e *Expansion) lookup(via string) {
+	e.LookupCount++
+	e.LookupsVia[via]++
+}
+
+// unresolved records a term reached through the top-level term via that could not be expanded
+func (e *Expansion) unresolved(term string, via string) {
+	e.Unresolved = append(e.Unresolved, term)
+	e.UnflattenableVia[via] = true
+}
+
+// add records a network matched by a mechanism, which is authorized when the mechanism and every include
+// leading to it have the pass qualifier
+func (e *Expansion) add(ipNet *net.IPNet, term Term, source string, via string, scopes []scope) {
+	network := Network{Source: source, Term: term.Raw, Via: via}
+	if ipNet != nil {
+		network.CIDR = ipNet.String()
+	}
+	e.matchers = append(e.matchers, matcher{network: ipNet, qualifier: term.Qualifier, scopes: scopes, result: network})
+
+	if term.Qualifier != "+" || slices.ContainsFunc(scopes, func(s scope) bool { return s.qualifier != "+" }) {
+		return
+	}
+	if ipNet == nil {
+		e.PassAll = true
+		return
+	}
+	e.Networks = append(e.Networks, network)
+}
+
+// expandRecord walks the terms of a single record and collects its networks. scopes are the includes
+// leading to the record; records reached through an include without the pass qualifier are walked as well,
+// since they count towards the lookup limit and decide whether that include matches.
+func (e *Expansion) expandRecord(record *SPFRecord, domain string, nameserver string, depth int, via string, scopes []scope, visited map[string]bool) {
+	if depth > maxExpandDepth {
+		e.unresolved(fmt.Sprintf("%s (maximum recursion depth reached)", domain), via)
+		e.Incomplete = true
+		return
+	}
+
+	for _, term := range record.Terms {
+		if term.Modifier {
+			continue
+		}
+
+		target := term.Value
+		if target == "" {
+			target = domain
+		}
+
+		termVia := via
+		if depth == 0 {
+			termVia = term.Raw
+		}
+
+		// Below the top level only networks that pass can be flattened, an exception or a +all in an
+		// included record makes it match differently than its networks
+		if depth > 0 {
+			switch {
+			case term.Mechanism == "all" && term.Qualifier == "+", term.Mechanism != "all" && term.Qualifier != "+":
+				e.UnflattenableVia[termVia] = true
+			}
+		}
+
+		switch term.Mechanism {
+		case "all":
+			e.add(nil, term, domain, termVia, scopes)
+		case "ip4", "ip6":
+			cidr := term.Value
+			if term.CIDR != "" {
+				cidr += "/" + term.CIDR
+			}
+			if network, ok := normalizeCIDR(cidr); ok {
+				_, ipNet, _ := net.ParseCIDR(network)
+				e.add(ipNet, term, domain, termVia, scopes)
+			} else {
+				e.unresolved(term.Raw, termVia)
+			}
+		case "a":
+			e.lookup(termVia)
+			e.addHostNetworks(target, term, domain, termVia, scopes, nameserver)
+		case "mx":
+			e.lookup(termVia)
+			hosts, err := lookupMXHosts(target, nameserver)
+			if err != nil {
+				e.unresolved(term.Raw, termVia)
+				continue
+			}
+			for _, host := range hosts {
+				e.addHostNetworks(host, term, domain, termVia, scopes, nameserver)
+			}
+		case "include":
+			e.lookup(termVia)
+			e.scopes++
+			included := append(slices.Clip(scopes), scope{id: e.scopes, qualifier: term.Qualifier})
+			e.expandDomain(target, term, nameserver, depth, termVia, included, visited)
+		case "exists", "ptr":
+			e.lookup(termVia)
+			e.unresolved(term.Raw, termVia)
+		}
+	}
+
+	// A redirect only applies when the record has no all mechanism, its record replaces this one
+	if redirect, ok := record.Modifier("redirect"); ok && len(record.Mechanisms("all")) == 0 {
+		redirectTerm := Term{Raw: "redirect=" + redirect}
+		redirectVia := via
+		if depth == 0 {
+			redirectVia = redirectTerm.Raw
+		}
+		e.lookup(redirectVia)
+		e.expandDomain(redirect, redirectTerm, nameserver, depth, redirectVia, scopes, visited)
+	}
+}
+
+// expandDomain looks up the SPF record of an included or redirected domain and expands it. visited holds
+// the domains on the path from the top-level record, so a domain included twice is expanded twice, as
+// receivers do, while a domain including itself is a loop.
+func (e *Expansion) expandDomain(target string, term Term, nameserver string, depth int, via string, scopes []scope, visited map[string]bool) {
+	key := strings.ToLower(target)
+	if visited[key] {
+		e.unresolved(fmt.Sprintf("%s (loop detected)", term.Raw), via)
+		return
+	}
+
+	record, err := LookupSPF(target, nameserver)
+	if err != nil {
+		e.unresolved(term.Raw, via)
+		e.Incomplete = true
+		return
+	}
+
+	visited[key] = true
+	e.expandRecord(record, target, nameserver, depth+1, via, scopes, visited)
+	delete(visited, key)
+}
+
+// addHostNetworks adds the addresses of a host, widened by the term's CIDR lengths
+func (e *Expansion) addHostNetworks(host string, term Term, source string, via string, scopes []scope, nameserver string) {
+	ips, err := lookupAddresses(host, nameserver)
+	if err != nil {
+		e.unresolved(term.Raw, via)
+		return
+	}
+
+	cidr4, cidr6 := dualCIDR(term.CIDR)
+	for _, ip := range ips {
+		length := cidr6
+		if ip.To4() != nil {
+			length = cidr4
+		}
+		if network, ok := normalizeCIDR(fmt.Sprintf("%s/%d", ip.String(), length)); ok {
+			_, ipNet, _ := net.ParseCIDR(network)
+			e.add(ipNet, term, source, via, scopes)
+		}
+	}
+}
+
+// dualCIDR parses an a/mx CIDR suffix ("24", "24//64" or "/64") into IPv4 and IPv6 prefix lengths
+func dualCIDR(suffix string) (int, int) {
+	cidr4, cidr6 := 32, 128
+	if suffix == "" {
+		return cidr4, cidr6
+	}
+
+	parts := strings.SplitN(suffix, "//", 2)
+	if v, err := strconv.Atoi(parts[0]); err == nil {
+		cidr4 = v
+	}
+	if len(parts) == 2 {
+		if v, err := strconv.Atoi(parts[1]); err == nil {
+			cidr6 = v
+		}
+	} else if strings.HasPrefix(suffix, "/") {
+		if v, err := strconv.Atoi(strings.TrimPrefix(suffix, "/")); err == nil {
+			cidr4, cidr6 = 32, v
+		}
+	}
+	return cidr4, cidr6
+}
+
+// normalizeCIDR validates an address or CIDR and returns it in canonical CIDR notation
+func normalizeCIDR(value string) (string, bool) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", false
+		}
+		if ip.To4() != nil {
+			return ip.String() + "/32", true
+		}
+		return ip.String() + "/128", true
+	}
+
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return "", false
+	}
+	return ipNet.String(), true
+}
+
+// lookupMXHosts returns the exchange hosts of a domain's MX records
+func lookupMXHosts(domain string, nameserver string) ([]string, error) {
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(domain), dns.TypeMX)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query failed: %v", err)
+	}
+
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+	}
+
+	var hosts []string
+	for _, a := range r.Answer {
+		if mx, ok := a.(*dns.MX); ok {
+			hosts = append(hosts, strings.TrimSuffix(mx.Mx, "."))
+		}
+	}
+	return hosts, nil
+}
+
+// lookupAddresses returns the IPv4 and IPv6 addresses of a host
+func lookupAddresses(host string, nameserver string) ([]net.IP, error) {
+	c := new(dns.Client)
+	var ips []net.IP
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(host), qtype)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			return nil, fmt.Errorf("DNS query failed: %v", err)
+		}
+
+		for _, a := range r.Answer {
+			switch record := a.(type) {
+			case *dns.A:
+				ips = append(ips, record.A)
+			case *dns.AAAA:
+				ips = append(ips, record.AAAA)
+			}
+		}
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,64 @@
+package spf
+
+import (
+	"strings"
+)
+
+// Provider maps a well-known SPF include target to the email provider behind it
+type Provider struct {
+	Include string // Include domain (or parent domain) published by the provider
+	Name    string // Human readable provider name
+}
+
+// KnownProviders is a list of well-known SPF include targets and their providers
+var KnownProviders = []Provider{
+	{"_spf.google.com", "Google Workspace"},
+	{"spf.protection.outlook.com", "Microsoft 365"},
+	{"sendgrid.net", "SendGrid"},
+	{"mailgun.org", "Mailgun"},
+	{"servers.mcsv.net", "Mailchimp"},
+	{"spf.mandrillapp.com", "Mandrill"},
+	{"amazonses.com", "Amazon SES"},
+	{"_spf.salesforce.com", "Salesforce"},
+	{"zoho.com", "Zoho Mail"},
+	{"zoho.eu", "Zoho Mail"},
+	{"spf.messagingengine.com", "Fastmail"},
+	{"_spf.protonmail.ch", "Proton Mail"},
+	{"mktomail.com", "Marketo"},
+	{"spf.mtasv.net", "Postmark"},
+	{"sparkpostmail.com", "SparkPost"},
+	{"spf.mailjet.com", "Mailjet"},
+	{"hubspotemail.net", "HubSpot"},
+	{"mail.zendesk.com", "Zendesk"},
+	{"email.freshdesk.com", "Freshdesk"},
+	{"_netblocks.mimecast.com", "Mimecast"},
+	{"pphosted.com", "Proofpoint"},
+	{"mx.ovh.com", "OVHcloud"},
+	{"spf.strato.com", "Strato"},
+	{"_spf.transip.email", "TransIP"},
+}
+
+// LookupProvider returns the provider name for an include target, if it is known
+func LookupProvider(include string) (string, bool) {
+	include = strings.ToLower(strings.TrimSuffix(include, "."))
+	for _, provider := range KnownProviders {
+		if include == provider.Include || strings.HasSuffix(include, "."+provider.Include) {
+			return provider.Name, true
+		}
+	}
+	return "", false
+}
+
+// Providers returns the deduplicated list of known providers authorized by the record's include mechanisms
+func (r *SPFRecord) Providers() []string {
+	seen := make(map[string]bool)
+	providers := []string{}
+	for _, term := range r.Mechanisms("include") {
+		name, ok := LookupProvider(term.Value)
+		if ok && !seen[name] {
+			seen[name] = true
+			providers = append(providers, name)
+		}
+	}
+	return providers
+}
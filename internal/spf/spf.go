@@ -10,9 +10,98 @@ import (
 
 // SPFRecord represents an SPF record with its parsed value
 type SPFRecord struct {
-	Raw     string   // The complete raw TXT record
-	Version string   // Should be "spf1"
-	Terms   []string // The individual mechanisms and modifiers
+	Raw      string // The complete raw TXT record
+	Version  string // Should be "spf1"
+	Terms    []Term // The individual mechanisms and modifiers
+	TTL      uint32 // TTL of the TXT record in seconds as configured at the authoritative nameservers
+	TTLKnown bool   // Whether the TTL could be read from an authoritative nameserver
+}
+
+// Term represents a single parsed SPF mechanism or modifier
+type Term struct {
+	Raw       string // The term as it appears in the record
+	Qualifier string // "+", "-", "~" or "?" (empty for modifiers)
+	Mechanism string // Mechanism or modifier name (all, include, a, mx, ptr, ip4, ip6, exists, redirect, exp, ...)
+	Value     string // Domain-spec or IP address, if any
+	CIDR      string // CIDR length suffix without the leading slash (e.g. "24" or "24//64"), if any
+	Modifier  bool   // Whether this term is a modifier (name=value) rather than a mechanism
+}
+
+// ParseSPF parses a raw SPF TXT record into an SPFRecord
+func ParseSPF(raw string) *SPFRecord {
+	fields := strings.Fields(raw)
+	record := &SPFRecord{
+		Raw:   raw,
+		Terms: []Term{},
+	}
+	if len(fields) == 0 {
+		return record
+	}
+
+	record.Version = strings.TrimPrefix(strings.ToLower(fields[0]), "v=")
+	for _, field := range fields[1:] {
+		record.Terms = append(record.Terms, ParseTerm(field))
+	}
+
+	return record
+}
+
+// ParseTerm parses a single SPF term into its qualifier, mechanism, value and CIDR parts
+func ParseTerm(raw string) Term {
+	term := Term{Raw: raw}
+
+	// Modifiers have the form name=value and carry no qualifier
+	if eq := strings.Index(raw, "="); eq > 0 && !strings.ContainsAny(raw[:eq], ":/") {
+		term.Modifier = true
+		term.Mechanism = strings.ToLower(raw[:eq])
+		term.Value = raw[eq+1:]
+		return term
+	}
+
+	rest := raw
+	term.Qualifier = "+"
+	if rest != "" && strings.ContainsRune("+-~?", rune(rest[0])) {
+		term.Qualifier = rest[:1]
+		rest = rest[1:]
+	}
+
+	// Split off the mechanism name
+	name := rest
+	if i := strings.IndexAny(rest, ":/"); i >= 0 {
+		name = rest[:i]
+		rest = rest[i:]
+	} else {
+		rest = ""
+	}
+	term.Mechanism = strings.ToLower(name)
+
+	if strings.HasPrefix(rest, ":") {
+		rest = rest[1:]
+	}
+
+	// ip6 values contain colons, so only a slash can start the CIDR suffix
+	if i := strings.Index(rest, "/"); i >= 0 {
+		term.Value = rest[:i]
+		term.CIDR = rest[i+1:]
+	} else {
+		term.Value = rest
+	}
+
+	return term
+}
+
+// BuildRecord assembles an SPF record string from a list of terms
+func BuildRecord(terms []Term) string {
+	parts := []string{"v=spf1"}
+	for _, term := range terms {
+		parts = append(parts, term.Raw)
+	}
+	return strings.Join(parts, " ")
+}
+
+// IsMechanism reports whether the term is the given mechanism (case-insensitive)
+func (t Term) IsMechanism(name string) bool {
+	return !t.Modifier && t.Mechanism == strings.ToLower(name)
 }
 
 // LookupSPF looks up SPF records for the specified domain using the given nameserver
@@ -44,13 +133,7 @@ func LookupSPF(domain string, nameserver string) (*SPFRecord, error) {
 
 			// Check if this is an SPF record
 			if strings.HasPrefix(strings.ToLower(txtValue), "v=spf1") {
-				// Parse the SPF record
-				terms := strings.Fields(txtValue)
-				return &SPFRecord{
-					Raw:     txtValue,
-					Version: strings.TrimPrefix(terms[0], "v="),
-					Terms:   terms[1:],
-				}, nil
+				return ParseSPF(txtValue), nil
 			}
 		}
 	}
@@ -76,12 +159,7 @@ func LookupSPFWithFallback(domain string, nameserver string) (*SPFRecord, error)
 	// Look for SPF record in TXT records
 	for _, txt := range txtRecords {
 		if strings.HasPrefix(strings.ToLower(txt), "v=spf1") {
-			terms := strings.Fields(txt)
-			return &SPFRecord{
-				Raw:     txt,
-				Version: strings.TrimPrefix(terms[0], "v="),
-				Terms:   terms[1:],
-			}, nil
+			return ParseSPF(txt), nil
 		}
 	}
 
@@ -90,9 +168,8 @@ func LookupSPFWithFallback(domain string, nameserver string) (*SPFRecord, error)
 
 // HasInclude checks if the SPF record includes the specified domain
 func (r *SPFRecord) HasInclude(domain string) bool {
-	includePrefix := "include:" + domain
 	for _, term := range r.Terms {
-		if term == includePrefix || strings.HasPrefix(term, includePrefix+"/") {
+		if term.IsMechanism("include") && strings.EqualFold(term.Value, domain) {
 			return true
 		}
 	}
@@ -101,11 +178,31 @@ func (r *SPFRecord) HasInclude(domain string) bool {
 
 // HasIP checks if the SPF record includes the specified IP
 func (r *SPFRecord) HasIP(ip string) bool {
-	ipPrefix := "ip4:" + ip
 	for _, term := range r.Terms {
-		if term == ipPrefix || strings.HasPrefix(term, ipPrefix+"/") {
+		if (term.IsMechanism("ip4") || term.IsMechanism("ip6")) && term.Value == ip {
 			return true
 		}
 	}
 	return false
 }
+
+// Mechanisms returns all terms matching the given mechanism name
+func (r *SPFRecord) Mechanisms(name string) []Term {
+	var terms []Term
+	for _, term := range r.Terms {
+		if term.IsMechanism(name) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Modifier returns the value of the given modifier, if present
+func (r *SPFRecord) Modifier(name string) (string, bool) {
+	for _, term := range r.Terms {
+		if term.Modifier && term.Mechanism == strings.ToLower(name) {
+			return term.Value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+package subdomain
+
+import (
+	"fmt"
+	"strings"
+
+	"check-maildomain/internal/dmarc"
+	"check-maildomain/internal/mx"
+	"check-maildomain/internal/spf"
+
+	"github.com/miekg/dns"
+)
+
+// SubdomainInfo contains the mail related records found for a single subdomain
+type SubdomainInfo struct {
+	Domain      string             // Fully qualified subdomain that was checked
+	Exists      bool               // Whether the subdomain exists in DNS
+	HasAddress  bool               // Whether the subdomain has A or AAAA records
+	MXRecords   []mx.MXRecord      // MX records of the subdomain
+	SPFRecord   *spf.SPFRecord     // SPF record of the subdomain, if any
+	DMARCRecord *dmarc.DMARCRecord // Explicit DMARC record of the subdomain, overriding the organizational policy
+	Error       string             // Any error encountered during the check
+}
+
+// DefaultSubdomains is a list of commonly used mail related subdomain labels to check
+var DefaultSubdomains = []string{
+	"mail", "smtp", "email", "mx", "bounce", "bounces", "newsletter", "newsletters",
+	"news", "marketing", "info", "noreply", "no-reply", "support", "notifications", "mg", "em",
+}
+
+// CanSendMail reports whether the subdomain has records that allow it to be used for mail
+func (s *SubdomainInfo) CanSendMail() bool {
+	return len(s.MXRecords) > 0 || s.HasAddress
+}
+
+// Scan checks each subdomain label under the domain for MX, address, SPF and DMARC records
+func Scan(domain string, labels []string, nameserver string) []SubdomainInfo {
+	results := []SubdomainInfo{}
+	for _, label := range labels {
+		label = strings.Trim(strings.TrimSpace(label), ".")
+		if label == "" {
+			continue
+		}
+		results = append(results, checkSubdomainIsolated(label+"."+domain, nameserver))
+	}
+	return results
+}
+
+// checkSubdomainIsolated checks a subdomain, converting a panic into an error result for that subdomain only
+func checkSubdomainIsolated(subdomain string, nameserver string) (info SubdomainInfo) {
+	defer func() {
+		if r := recover(); r != nil {
+			info = SubdomainInfo{
+				Domain: subdomain,
+				Error:  fmt.Sprintf("internal error: %v", r),
+			}
+		}
+	}()
+
+	return CheckSubdomain(subdomain, nameserver)
+}
+
+// CheckSubdomain collects the mail related records of a single subdomain
+func CheckSubdomain(subdomain string, nameserver string) SubdomainInfo {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	info := SubdomainInfo{
+		Domain:    subdomain,
+		MXRecords: []mx.MXRecord{},
+	}
+
+	c := new(dns.Client)
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(subdomain), qtype)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			info.Error = fmt.Sprintf("DNS query failed: %v", err)
+			return info
+		}
+
+		if r.Rcode == dns.RcodeNameError {
+			// The subdomain does not exist at all
+			return info
+		}
+
+		info.Exists = true
+		for _, a := range r.Answer {
+			if a.Header().Rrtype == qtype {
+				info.HasAddress = true
+			}
+		}
+	}
+
+	if records, err := mx.LookupMX(subdomain, nameserver); err == nil {
+		info.MXRecords = records
+	}
+
+	if record, err := spf.LookupSPF(subdomain, nameserver); err == nil {
+		info.SPFRecord = record
+	}
+
+	// Only an explicit record counts, the organizational record is not inherited here
+	if record, err := dmarc.LookupDMARC(subdomain, nameserver); err == nil {
+		info.DMARCRecord = record
+	}
+
+	return info
+}
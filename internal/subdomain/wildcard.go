@@ -0,0 +1,81 @@
+package subdomain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Wildcard describes the records a wildcard returns for names that don't exist under the domain
+type Wildcard struct {
+	Name  string   // Random name that was probed
+	Types []string // Record types answered for the name, e.g. "A", "MX" or "TXT"
+	DKIM  bool     // Whether a random name below _domainkey returns a TXT record, faking DKIM selectors
+}
+
+// DetectWildcard probes a random label under the domain for A, AAAA, MX and TXT records. It returns nil when
+// the domain has no wildcard.
+func DetectWildcard(domain string, nameserver string) (*Wildcard, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	label, err := randomLabel()
+	if err != nil {
+		return nil, err
+	}
+
+	wildcard := &Wildcard{Name: label + "." + domain}
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeMX, dns.TypeTXT} {
+		answered, err := hasAnswer(wildcard.Name, qtype, nameserver)
+		if err != nil {
+			return nil, err
+		}
+		if answered {
+			wildcard.Types = append(wildcard.Types, dns.TypeToString[qtype])
+		}
+	}
+
+	// Selector discovery queries names below _domainkey, which a wildcard matches when _domainkey doesn't exist
+	wildcard.DKIM, err = hasAnswer(label+"._domainkey."+domain, dns.TypeTXT, nameserver)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wildcard.Types) == 0 && !wildcard.DKIM {
+		return nil, nil
+	}
+	return wildcard, nil
+}
+
+// randomLabel returns a label that is very unlikely to exist in any zone
+func randomLabel() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating a random label failed: %v", err)
+	}
+	return "wildcard-probe-" + hex.EncodeToString(b), nil
+}
+
+// hasAnswer reports whether the name has records of the given type, including records synthesized by a wildcard
+func hasAnswer(name string, qtype uint16, nameserver string) (bool, error) {
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return false, fmt.Errorf("DNS query failed: %v", err)
+	}
+
+	for _, a := range r.Answer {
+		if a.Header().Rrtype == qtype {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,134 @@
+package txt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Categories of TXT records
+const (
+	CategorySPF          = "spf"               // SPF policy
+	CategoryVerification = "site-verification" // Domain ownership token of a web or SaaS service
+	CategoryProviderKey  = "provider-key"      // Verification key or code of an email provider
+	CategoryUnknown      = "unknown"           // Not recognized
+)
+
+// SafeUDPSize is the EDNS buffer size recommended since DNS Flag Day 2020; larger answers are truncated over
+// UDP by many resolvers and need a retry over TCP
+const SafeUDPSize = 1232
+
+// Record is a single TXT record with its category
+type Record struct {
+	Value    string // TXT strings joined together
+	Category string // One of the category constants
+	Service  string // Service the record belongs to, if recognized
+	Size     int    // Length of the record data in bytes
+}
+
+// Inventory contains all TXT records at a name
+type Inventory struct {
+	Records      []Record
+	Size         int  // Total size of the TXT data in bytes
+	ResponseSize int  // Size of the complete DNS response in bytes
+	Truncated    bool // Whether the answer was truncated over UDP with the safe EDNS buffer size
+}
+
+// pattern maps the prefix of a TXT record to its category and service
+type pattern struct {
+	Prefix   string
+	Category string
+	Service  string
+}
+
+// knownPatterns lists the prefixes of well-known TXT records
+var knownPatterns = []pattern{
+	{"v=spf1", CategorySPF, "SPF"},
+	{"google-site-verification=", CategoryVerification, "Google"},
+	{"facebook-domain-verification=", CategoryVerification, "Facebook"},
+	{"apple-domain-verification=", CategoryVerification, "Apple"},
+	{"atlassian-domain-verification=", CategoryVerification, "Atlassian"},
+	{"adobe-idp-site-verification=", CategoryVerification, "Adobe"},
+	{"docusign=", CategoryVerification, "DocuSign"},
+	{"globalsign-domain-verification=", CategoryVerification, "GlobalSign"},
+	{"stripe-verification=", CategoryVerification, "Stripe"},
+	{"zoom_verify_", CategoryVerification, "Zoom"},
+	{"slack-domain-verification=", CategoryVerification, "Slack"},
+	{"dropbox-domain-verification=", CategoryVerification, "Dropbox"},
+	{"openai-domain-verification=", CategoryVerification, "OpenAI"},
+	{"onetrust-domain-verification=", CategoryVerification, "OneTrust"},
+	{"cisco-ci-domain-verification=", CategoryVerification, "Cisco"},
+	{"have-i-been-pwned-verification=", CategoryVerification, "Have I Been Pwned"},
+	{"keybase-site-verification=", CategoryVerification, "Keybase"},
+	{"yandex-verification:", CategoryVerification, "Yandex"},
+	{"ms=", CategoryProviderKey, "Microsoft 365"},
+	{"zoho-verification=", CategoryProviderKey, "Zoho Mail"},
+	{"protonmail-verification=", CategoryProviderKey, "Proton Mail"},
+	{"amazonses:", CategoryProviderKey, "Amazon SES"},
+	{"mandrill_verify.", CategoryProviderKey, "Mandrill"},
+	{"brevo-code:", CategoryProviderKey, "Brevo"},
+	{"sendinblue-code:", CategoryProviderKey, "Brevo"},
+	{"mailru-verification:", CategoryProviderKey, "Mail.ru"},
+}
+
+// Categorize returns the category and service of a TXT record
+func Categorize(value string) (string, string) {
+	lower := strings.ToLower(strings.TrimSpace(value))
+	for _, p := range knownPatterns {
+		if strings.HasPrefix(lower, p.Prefix) {
+			return p.Category, p.Service
+		}
+	}
+	return CategoryUnknown, ""
+}
+
+// Lookup collects and categorizes all TXT records at the name. The records are queried over UDP with the
+// safe EDNS buffer size, and over TCP when the answer is truncated.
+func Lookup(name string, nameserver string) (*Inventory, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+	m.RecursionDesired = true
+	m.SetEdns0(SafeUDPSize, false)
+
+	c := new(dns.Client)
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query failed: %v", err)
+	}
+
+	inventory := &Inventory{Records: []Record{}}
+	if r.Truncated {
+		inventory.Truncated = true
+		c.Net = "tcp"
+		r, _, err = c.Exchange(m, nameserver)
+		if err != nil {
+			return nil, fmt.Errorf("DNS query over TCP failed: %v", err)
+		}
+	}
+
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+	}
+
+	inventory.ResponseSize = r.Len()
+	for _, a := range r.Answer {
+		if record, ok := a.(*dns.TXT); ok {
+			value := strings.Join(record.Txt, "")
+			category, service := Categorize(value)
+			inventory.Records = append(inventory.Records, Record{
+				Value:    value,
+				Category: category,
+				Service:  service,
+				Size:     len(value),
+			})
+			inventory.Size += len(value)
+		}
+	}
+
+	return inventory, nil
+}
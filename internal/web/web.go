@@ -0,0 +1,132 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Domain liveness classifications
+const (
+	StatusActive = "active" // A regular website answers
+	StatusParked = "parked" // A parking or for-sale page answers
+	StatusDead   = "dead"   // No website answers at all
+)
+
+// maxBodySize limits how much of a page is read for classification
+const maxBodySize = 256 * 1024
+
+// WebInfo contains the result of probing the website of a domain
+type WebInfo struct {
+	Domain           string // Domain that was probed
+	Status           string // active, parked or dead
+	URL              string // URL that answered
+	FinalURL         string // URL after following redirects
+	StatusCode       int    // HTTP status code of the answering URL
+	Title            string // Page title, if any
+	ParkingIndicator string // The indicator that classified the page as parked
+	Error            string // Any error encountered during the probe
+}
+
+// ParkingHosts are the hosts of parking and domain sale services that parked domains redirect to
+var ParkingHosts = []string{
+	"sedoparking.com", "sedo.com", "parkingcrew.net", "bodis.com", "dan.com", "afternic.com",
+	"hugedomains.com", "above.com", "parklogic.com", "domainmarket.com", "undeveloped.com",
+}
+
+// ParkingPhrases are phrases commonly found in the title or text of parking and for-sale pages
+var ParkingPhrases = []string{
+	"domain is for sale", "this domain may be for sale", "buy this domain", "domain for sale",
+	"parked free", "parked domain", "domain parking", "this domain is parked",
+}
+
+var (
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	// hiddenPattern matches the parts of a page that are not displayed: scripts, styles, comments and the head
+	hiddenPattern = regexp.MustCompile(`(?is)<script[^>]*>.*?</script>|<style[^>]*>.*?</style>|<!--.*?-->|<head[^>]*>.*?</head>`)
+	tagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// Probe requests the apex and www website of a domain over HTTPS and HTTP and classifies it
+func Probe(domain string) *WebInfo {
+	info := &WebInfo{
+		Domain: domain,
+		Status: StatusDead,
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	urls := []string{
+		"https://" + domain + "/",
+		"https://www." + domain + "/",
+		"http://" + domain + "/",
+		"http://www." + domain + "/",
+	}
+
+	var failures []string
+	for _, target := range urls {
+		resp, err := client.Get(target)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		resp.Body.Close()
+
+		if resp.StatusCode >= 500 {
+			failures = append(failures, fmt.Sprintf("%s returned HTTP %d", target, resp.StatusCode))
+			continue
+		}
+
+		info.URL = target
+		info.FinalURL = resp.Request.URL.String()
+		info.StatusCode = resp.StatusCode
+		if match := titlePattern.FindSubmatch(body); match != nil {
+			info.Title = strings.TrimSpace(string(match[1]))
+		}
+
+		info.Status = StatusActive
+		if indicator, ok := findParkingIndicator(info.FinalURL, info.Title, string(body)); ok {
+			info.Status = StatusParked
+			info.ParkingIndicator = indicator
+		}
+		return info
+	}
+
+	info.Error = strings.Join(failures, "; ")
+	return info
+}
+
+// findParkingIndicator looks for the host of a parking service in the final URL, and for parking phrases in
+// the title and visible text of the page. Hidden markup such as scripts and links isn't searched, since
+// regular sites mention parking services there too.
+func findParkingIndicator(finalURL string, title string, body string) (string, bool) {
+	if u, err := url.Parse(finalURL); err == nil {
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		for _, parkingHost := range ParkingHosts {
+			if host == parkingHost || strings.HasSuffix(host, "."+parkingHost) {
+				return parkingHost, true
+			}
+		}
+	}
+
+	text := strings.ToLower(title + " " + visibleText(body))
+	for _, phrase := range ParkingPhrases {
+		if strings.Contains(text, phrase) {
+			return phrase, true
+		}
+	}
+	return "", false
+}
+
+// visibleText returns the text of an HTML page as displayed, without markup and with collapsed whitespace
+func visibleText(body string) string {
+	text := hiddenPattern.ReplaceAllString(body, " ")
+	text = tagPattern.ReplaceAllString(text, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
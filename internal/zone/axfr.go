@@ -0,0 +1,67 @@
+package zone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// TransferResult contains the outcome of a zone transfer attempt against a single nameserver
+type TransferResult struct {
+	Nameserver string // Hostname of the nameserver
+	Address    string // Address that was queried
+	Allowed    bool   // Whether the server transferred the zone
+	Records    int    // Number of records received
+	Error      string // Why the transfer was refused or failed
+}
+
+// TryTransfers attempts a zone transfer (AXFR) against every nameserver of the delegation that has an address.
+// Servers should refuse transfers to arbitrary clients, a leaked zone exposes every host and DKIM selector.
+func TryTransfers(delegation *Delegation) []TransferResult {
+	results := []TransferResult{}
+	if delegation == nil {
+		return results
+	}
+
+	for _, server := range delegation.Servers {
+		if server.Address == "" {
+			continue
+		}
+		results = append(results, tryTransfer(delegation.Zone, server))
+	}
+	return results
+}
+
+// tryTransfer attempts a zone transfer of the zone from the server
+func tryTransfer(zone string, server ServerNS) TransferResult {
+	result := TransferResult{
+		Nameserver: server.Nameserver,
+		Address:    server.Address,
+	}
+
+	m := new(dns.Msg)
+	m.SetAxfr(dns.Fqdn(zone))
+
+	t := new(dns.Transfer)
+	envelopes, err := t.In(m, dnsAddress(server.Address))
+	if err != nil {
+		result.Error = fmt.Sprintf("zone transfer failed: %v", err)
+		return result
+	}
+
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			// Drain the channel so the transfer goroutine can finish
+			if result.Error == "" {
+				result.Error = strings.TrimPrefix(envelope.Error.Error(), "dns: ")
+			}
+			continue
+		}
+		result.Records += len(envelope.RR)
+	}
+
+	// Any records received count as a leaked zone, even when the transfer broke off
+	result.Allowed = result.Records > 0
+	return result
+}
@@ -0,0 +1,123 @@
+package zone
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// RecordQuery is a mail related record to compare between the authoritative nameservers
+type RecordQuery struct {
+	Label  string // Human readable name of the record, e.g. "SPF" or "DKIM (s1)"
+	Name   string // Owner name to query
+	Qtype  uint16
+	Prefix string // Only TXT records starting with this prefix (case-insensitive) are compared
+}
+
+// RecordComparison contains the answers of each authoritative nameserver for a single record
+type RecordComparison struct {
+	Label      string              // Human readable name of the record
+	Name       string              // Owner name that was queried
+	Type       string              // Record type, e.g. "MX" or "TXT"
+	Answers    map[string][]string // Sorted record data per nameserver that answered
+	Errors     map[string]string   // Why a nameserver gave no usable answer, left out of the comparison
+	Consistent bool                // Whether all nameservers that answered returned the same records
+}
+
+// MailRecordQueries returns the mail related records of a domain: MX, SPF, DMARC and the given DKIM selectors
+func MailRecordQueries(domain string, selectors []string) []RecordQuery {
+	queries := []RecordQuery{
+		{Label: "MX", Name: domain, Qtype: dns.TypeMX},
+		{Label: "SPF", Name: domain, Qtype: dns.TypeTXT, Prefix: "v=spf1"},
+		{Label: "DMARC", Name: "_dmarc." + domain, Qtype: dns.TypeTXT, Prefix: "v=dmarc1"},
+	}
+	for _, selector := range selectors {
+		queries = append(queries, RecordQuery{
+			Label: fmt.Sprintf("DKIM (%s)", selector),
+			Name:  selector + "._domainkey." + domain,
+			Qtype: dns.TypeTXT,
+		})
+	}
+	return queries
+}
+
+// CompareRecords queries every record directly at each authoritative nameserver of the delegation and
+// compares the answers. Stale secondaries show up as records that differ between the servers. Lame servers are
+// skipped, and servers that fail to answer are recorded in Errors instead of being compared.
+func CompareRecords(delegation *Delegation, queries []RecordQuery) []RecordComparison {
+	comparisons := []RecordComparison{}
+	if delegation == nil {
+		return comparisons
+	}
+
+	for _, query := range queries {
+		comparison := RecordComparison{
+			Label:      query.Label,
+			Name:       query.Name,
+			Type:       dns.TypeToString[query.Qtype],
+			Answers:    make(map[string][]string),
+			Errors:     make(map[string]string),
+			Consistent: true,
+		}
+
+		var first string
+		compared := 0
+		for _, server := range delegation.Servers {
+			if server.Address == "" || server.Lame {
+				// Lame servers are reported by their own rule
+				continue
+			}
+
+			r, err := queryDirect(query.Name, query.Qtype, server.Address)
+			switch {
+			case err != nil:
+				comparison.Errors[server.Nameserver] = err.Error()
+				continue
+			case r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError:
+				comparison.Errors[server.Nameserver] = fmt.Sprintf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+				continue
+			case !r.Authoritative:
+				comparison.Errors[server.Nameserver] = fmt.Sprintf("%s is not authoritative for %s", server.Nameserver, query.Name)
+				continue
+			}
+			answer := recordData(r.Answer, query)
+			comparison.Answers[server.Nameserver] = answer
+
+			joined := strings.Join(answer, "\n")
+			if compared == 0 {
+				first = joined
+			} else if joined != first {
+				comparison.Consistent = false
+			}
+			compared++
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons
+}
+
+// recordData returns the sorted data of the records matching the query, without names and TTLs
+func recordData(records []dns.RR, query RecordQuery) []string {
+	data := []string{}
+	for _, rr := range records {
+		if rr.Header().Rrtype != query.Qtype {
+			continue
+		}
+		switch record := rr.(type) {
+		case *dns.MX:
+			data = append(data, fmt.Sprintf("%d %s", record.Preference, strings.ToLower(record.Mx)))
+		case *dns.TXT:
+			value := strings.Join(record.Txt, "")
+			if query.Prefix != "" && !strings.HasPrefix(strings.ToLower(value), query.Prefix) {
+				continue
+			}
+			data = append(data, value)
+		default:
+			data = append(data, strings.TrimPrefix(rr.String(), rr.Header().String()))
+		}
+	}
+	sort.Strings(data)
+	return data
+}
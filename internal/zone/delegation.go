@@ -0,0 +1,202 @@
+package zone
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"check-maildomain/internal/host"
+
+	"github.com/miekg/dns"
+)
+
+// Delegation contains the NS records of a zone as seen by the parent zone and by each authoritative server
+type Delegation struct {
+	Zone    string     // Zone apex
+	Parent  []string   // NS records in the delegation from the parent zone
+	Servers []ServerNS // NS records as published by each authoritative nameserver
+	Error   string     // Why the delegation could not be retrieved from the parent
+}
+
+// ServerNS contains the NS records a single authoritative nameserver publishes for the zone
+type ServerNS struct {
+	Nameserver string   // Hostname of the nameserver
+	Address    string   // Address that was queried
+	Addresses  []string // All IPv4 and IPv6 addresses of the nameserver
+	ASNs       []string // Autonomous systems announcing the addresses
+	NS         []string // NS records in the server's answer
+	Reachable  bool     // Whether the server answered at all
+	Lame       bool     // Whether the server answered without authority (AA bit) for the zone
+	Error      string   // Any error encountered while querying the server
+}
+
+// CheckDelegation retrieves the NS records of the zone from a nameserver of the parent zone and from every
+// authoritative nameserver, queried directly without recursion
+func CheckDelegation(zone string, nameserver string) *Delegation {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	delegation := &Delegation{Zone: zone}
+
+	parent, err := parentDelegation(zone, nameserver)
+	if err != nil {
+		delegation.Error = err.Error()
+	}
+	delegation.Parent = parent
+
+	// Query the nameservers named by the parent as well as the ones only listed in the zone itself
+	nameservers := append([]string{}, parent...)
+	if child, err := LookupNS(zone, nameserver); err == nil {
+		for _, ns := range child {
+			if !slices.Contains(nameservers, ns) {
+				nameservers = append(nameservers, ns)
+			}
+		}
+	}
+
+	for _, ns := range nameservers {
+		server := ServerNS{Nameserver: ns}
+		addresses, err := resolveAddresses(ns, nameserver)
+		if err != nil {
+			server.Error = err.Error()
+			delegation.Servers = append(delegation.Servers, server)
+			continue
+		}
+		server.Address = addresses[0]
+		server.Addresses = addresses
+		for _, address := range addresses {
+			if asn, err := host.LookupASN(address, nameserver); err == nil && !slices.Contains(server.ASNs, asn) {
+				server.ASNs = append(server.ASNs, asn)
+			}
+		}
+		address := server.Address
+
+		r, err := queryDirect(zone, dns.TypeNS, address)
+		if err != nil {
+			server.Error = err.Error()
+		} else {
+			server.Reachable = true
+			// A server that refuses, fails or refers elsewhere doesn't serve the zone it is delegated
+			server.Lame = !r.Authoritative || r.Rcode != dns.RcodeSuccess
+			server.NS = nsNames(zone, r.Answer)
+			if server.Lame {
+				server.Error = fmt.Sprintf("not authoritative for %s (%s)", zone, dns.RcodeToString[r.Rcode])
+			} else if len(server.NS) == 0 {
+				server.Error = fmt.Sprintf("no NS records in the answer (%s)", dns.RcodeToString[r.Rcode])
+			}
+		}
+		delegation.Servers = append(delegation.Servers, server)
+	}
+	return delegation
+}
+
+// parentDelegation asks the nameservers of the parent zone for the delegation of the zone
+func parentDelegation(zone string, nameserver string) ([]string, error) {
+	labels := dns.SplitDomainName(zone)
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("%s has no parent zone to check", zone)
+	}
+	parent := strings.Join(labels[1:], ".")
+
+	parentServers, err := LookupNS(parent, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("looking up the nameservers of %s failed: %v", parent, err)
+	}
+
+	var lastErr error
+	for _, ns := range parentServers {
+		address, err := resolveAddress(ns, nameserver)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		r, err := queryDirect(zone, dns.TypeNS, address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		// The delegation is a referral in the authority section, unless the parent also serves the zone
+		if names := nsNames(zone, append(r.Answer, r.Ns...)); len(names) > 0 {
+			return names, nil
+		}
+		lastErr = fmt.Errorf("%s returned no delegation for %s", ns, zone)
+	}
+	return nil, lastErr
+}
+
+// queryDirect sends a non-recursive query to an authoritative nameserver
+func queryDirect(name string, qtype uint16, address string) (*dns.Msg, error) {
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = false
+
+	r, _, err := c.Exchange(m, dnsAddress(address))
+	if err != nil {
+		return nil, fmt.Errorf("DNS query to %s failed: %v", address, err)
+	}
+	return r, nil
+}
+
+// resolveAddress returns the first IPv4 or, without IPv4, IPv6 address of a nameserver
+func resolveAddress(host string, nameserver string) (string, error) {
+	addresses, err := resolveAddresses(host, nameserver)
+	if err != nil {
+		return "", err
+	}
+	return addresses[0], nil
+}
+
+// resolveAddresses returns the IPv4 addresses followed by the IPv6 addresses of a host
+func resolveAddresses(host string, nameserver string) ([]string, error) {
+	c := new(dns.Client)
+	var addresses []string
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(host), qtype)
+		m.RecursionDesired = true
+
+		r, _, err := c.Exchange(m, nameserver)
+		if err != nil {
+			return nil, fmt.Errorf("DNS query failed: %v", err)
+		}
+		for _, a := range r.Answer {
+			switch record := a.(type) {
+			case *dns.A:
+				addresses = append(addresses, record.A.String())
+			case *dns.AAAA:
+				addresses = append(addresses, record.AAAA.String())
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no A or AAAA records found for nameserver: %s", host)
+	}
+	return addresses, nil
+}
+
+// dnsAddress returns the address with port 53, bracketing IPv6 addresses
+func dnsAddress(address string) string {
+	if strings.Contains(address, ":") {
+		return "[" + address + "]:53"
+	}
+	return address + ":53"
+}
+
+// nsNames returns the sorted NS targets for the zone in the records
+func nsNames(zone string, records []dns.RR) []string {
+	var names []string
+	for _, rr := range records {
+		if ns, ok := rr.(*dns.NS); ok && strings.EqualFold(strings.TrimSuffix(ns.Hdr.Name, "."), zone) {
+			name := strings.ToLower(strings.TrimSuffix(ns.Ns, "."))
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,125 @@
+package zone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// SOA contains the SOA record of the zone the domain belongs to
+type SOA struct {
+	Zone    string // Zone apex the SOA belongs to
+	MName   string // Primary nameserver
+	RName   string // Mailbox of the person responsible for the zone, in DNS notation
+	Serial  uint32
+	Refresh uint32 // Seconds between secondary refresh attempts
+	Retry   uint32 // Seconds between retries after a failed refresh
+	Expire  uint32 // Seconds after which secondaries stop answering without a successful refresh
+	Minimum uint32 // Negative caching TTL (RFC 2308)
+	TTL     uint32 // TTL of the SOA record itself, the remaining cache time unless Authoritative
+
+	Authoritative bool // Whether the record was read directly from an authoritative nameserver
+}
+
+// LookupSOA looks up the SOA record of the zone the domain belongs to. Below the zone apex the SOA is
+// returned in the authority section.
+func LookupSOA(domain string, nameserver string) (*SOA, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(domain), dns.TypeSOA)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query failed: %v", err)
+	}
+
+	for _, section := range [][]dns.RR{r.Answer, r.Ns} {
+		for _, rr := range section {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return newSOA(soa), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no SOA record found for domain: %s", domain)
+}
+
+// AuthoritativeSOA queries the SOA record of the zone directly at its authoritative nameservers, whose TTL
+// is the configured one rather than the time a resolver keeps it cached
+func AuthoritativeSOA(delegation *Delegation) (*SOA, error) {
+	if delegation == nil {
+		return nil, fmt.Errorf("no authoritative nameservers known")
+	}
+
+	lastErr := fmt.Errorf("no authoritative nameserver answered for %s", delegation.Zone)
+	for _, server := range delegation.Servers {
+		if server.Address == "" || server.Lame {
+			continue
+		}
+		r, err := queryDirect(delegation.Zone, dns.TypeSOA, server.Address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, rr := range r.Answer {
+			if soa, ok := rr.(*dns.SOA); ok && r.Authoritative {
+				record := newSOA(soa)
+				record.Authoritative = true
+				return record, nil
+			}
+		}
+		lastErr = fmt.Errorf("%s returned no SOA record for %s", server.Nameserver, delegation.Zone)
+	}
+	return nil, lastErr
+}
+
+// newSOA converts a SOA resource record
+func newSOA(soa *dns.SOA) *SOA {
+	return &SOA{
+		Zone:    strings.ToLower(strings.TrimSuffix(soa.Hdr.Name, ".")),
+		MName:   strings.ToLower(strings.TrimSuffix(soa.Ns, ".")),
+		RName:   strings.TrimSuffix(soa.Mbox, "."),
+		Serial:  soa.Serial,
+		Refresh: soa.Refresh,
+		Retry:   soa.Retry,
+		Expire:  soa.Expire,
+		Minimum: soa.Minttl,
+		TTL:     soa.Hdr.Ttl,
+	}
+}
+
+// LookupNS returns the nameservers of the zone as published in the zone itself
+func LookupNS(zone string, nameserver string) ([]string, error) {
+	if !strings.HasSuffix(nameserver, ":53") {
+		nameserver = nameserver + ":53"
+	}
+
+	c := new(dns.Client)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(zone), dns.TypeNS)
+	m.RecursionDesired = true
+
+	r, _, err := c.Exchange(m, nameserver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query failed: %v", err)
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("DNS query returned non-success code: %v", dns.RcodeToString[r.Rcode])
+	}
+
+	var nameservers []string
+	for _, a := range r.Answer {
+		if ns, ok := a.(*dns.NS); ok {
+			nameservers = append(nameservers, strings.ToLower(strings.TrimSuffix(ns.Ns, ".")))
+		}
+	}
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("no NS records found for zone: %s", zone)
+	}
+	return nameservers, nil
+}
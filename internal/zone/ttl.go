@@ -0,0 +1,49 @@
+package zone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// LookupTTL queries a record directly at the authoritative nameservers of the delegation and returns its TTL
+// as configured in the zone. Resolvers answer with the time the record remains in their cache instead. qtype
+// is the record type, e.g. "TXT"; only TXT records starting with prefix (case-insensitive) are considered.
+func LookupTTL(delegation *Delegation, name string, qtype string, prefix string) (uint32, error) {
+	if delegation == nil {
+		return 0, fmt.Errorf("no authoritative nameservers known")
+	}
+	rrtype, ok := dns.StringToType[qtype]
+	if !ok {
+		return 0, fmt.Errorf("unknown record type %s", qtype)
+	}
+
+	lastErr := fmt.Errorf("no authoritative nameserver answered for %s", name)
+	for _, server := range delegation.Servers {
+		if server.Address == "" || server.Lame {
+			continue
+		}
+		r, err := queryDirect(name, rrtype, server.Address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !r.Authoritative {
+			lastErr = fmt.Errorf("%s is not authoritative for %s", server.Nameserver, name)
+			continue
+		}
+
+		for _, rr := range r.Answer {
+			if rr.Header().Rrtype != rrtype || !strings.EqualFold(rr.Header().Name, dns.Fqdn(name)) {
+				continue
+			}
+			if txt, ok := rr.(*dns.TXT); ok && !strings.HasPrefix(strings.ToLower(strings.Join(txt.Txt, "")), prefix) {
+				continue
+			}
+			return rr.Header().Ttl, nil
+		}
+		return 0, fmt.Errorf("%s publishes no %s record at %s", server.Nameserver, qtype, name)
+	}
+	return 0, lastErr
+}
@@ -1,38 +1,137 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"check-maildomain/internal/benchmark"
+	"check-maildomain/internal/check"
+	"check-maildomain/internal/dkim"
+	"check-maildomain/internal/dmarc"
 	"check-maildomain/internal/dns"
+	"check-maildomain/internal/dnssec"
+	"check-maildomain/internal/generate"
+	"check-maildomain/internal/message"
+	"check-maildomain/internal/rua"
+	"check-maildomain/internal/ruf"
 	"check-maildomain/internal/rules"
+	"check-maildomain/internal/spf"
+	"check-maildomain/internal/subdomain"
 )
 
 func main() {
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "benchmark":
+			runBenchmark(os.Args[2:])
+			return
+		case "generate":
+			runGenerate(os.Args[2:])
+			return
+		case "rua":
+			runRUA(os.Args[2:])
+			return
+		case "ruf":
+			runRUF(os.Args[2:])
+			return
+		case "verify-message":
+			runVerifyMessage(os.Args[2:])
+			return
+		}
+	}
+
 	// Define flags
 	domain := flag.String("domain", "suspiciousbytes.com", "what domain to use")
 	nameserver := flag.String("nameserver", "8.8.8.8", "what nameserver to use")
 	jsonOutput := flag.Bool("json", false, "output as JSON")
 	outputFolder := flag.String("output", "", "folder to save JSON output files")
+	scanSubdomains := flag.Bool("scan-subdomains", false, "scan common mail subdomains for SPF, MX and DMARC records")
+	subdomains := flag.String("subdomains", "", "comma-separated list of subdomain labels to scan (implies -scan-subdomains)")
+	probeWeb := flag.Bool("probe-web", false, "probe the apex and www website to detect parked or dead domains")
+	inputType := flag.String("input-type", "auto", "type of input: auto, domain, host or ip")
+	smtpProbe := flag.Bool("smtp-probe", false, "actively probe port 25 for SMTP and STARTTLS support")
+	lintDMARC := flag.String("lint-dmarc", "", "validate a DMARC record offline without any DNS queries")
+	dkimSelectors := flag.String("dkim-selectors", "", "comma-separated list of DKIM selectors to check instead of the common selectors")
+	dkimSelectorFile := flag.String("dkim-selector-file", "", "file with DKIM selectors to try for discovery, one per line")
+	ruaReports := flag.String("rua-reports", "", "comma-separated list of DMARC aggregate report files whose DKIM selectors are checked")
+	strictDMARC := flag.Bool("strict-dmarc", false, "reject DMARC records with duplicate tags, v not first or stray data")
+	rrsigWarningDays := flag.Int("rrsig-warning-days", rules.DefaultSettings().SignatureWarningDays, "warn when DNSSEC signatures expire within this many days, or within the last fifth of their validity when shorter")
+	ednsBufferSize := uint16(dnssec.DefaultEDNSBufferSize)
+	flag.Func("edns-buffer-size", fmt.Sprintf("EDNS0 UDP buffer size for the DNSSEC queries, %d to 65535, truncated answers are retried over TCP (default %d)", dnssec.MinEDNSBufferSize, dnssec.DefaultEDNSBufferSize), func(value string) error {
+		size, err := strconv.ParseUint(value, 10, 16)
+		if err != nil || size < dnssec.MinEDNSBufferSize {
+			return fmt.Errorf("must be a number from %d to 65535", dnssec.MinEDNSBufferSize)
+		}
+		ednsBufferSize = uint16(size)
+		return nil
+	})
+	nsecWalk := flag.Bool("nsec-walk", false, "enumerate DKIM selectors by walking the NSEC chain of a DNSSEC-signed _domainkey zone")
+	compareNameservers := flag.Bool("compare-nameservers", false, "query the mail records at every authoritative nameserver and report differences")
+	tryAXFR := flag.Bool("axfr", false, "attempt a zone transfer (AXFR) against every authoritative nameserver")
+	dkimRotationMonths := flag.Int("dkim-rotation-months", rules.DefaultSettings().DKIMRotationMonths, "age in months after which date-stamped DKIM selectors should be rotated")
 
 	// Parse the flags
 	flag.Parse()
 
-	// Collect all DNS information
-	info, err := dns.CollectDNSInfo(*domain, *nameserver)
+	// Lint a pasted DMARC record instead of checking a domain
+	if *lintDMARC != "" {
+		lintDMARCRecord(*domain, *lintDMARC, dmarc.ParseOptions{Strict: *strictDMARC}, *jsonOutput)
+		return
+	}
+
+	// Determine optional checks
+	opts := dns.Options{
+		ProbeWeb:  *probeWeb,
+		SMTPProbe: *smtpProbe,
+		DMARC:     dmarc.ParseOptions{Strict: *strictDMARC},
+		DNSSEC:    dnssec.Options{EDNSBufferSize: ednsBufferSize},
+	}
+	if *dkimSelectors != "" {
+		opts.DKIMSelectors.Supplied = strings.Split(*dkimSelectors, ",")
+	}
+	if *ruaReports != "" {
+		selectors, err := ruaSelectors(*domain, strings.Split(*ruaReports, ","))
+		if err != nil {
+			log.Fatalf("Error reading aggregate reports: %v", err)
+		}
+		opts.DKIMSelectors.Supplied = append(opts.DKIMSelectors.Supplied, selectors...)
+	}
+	if *dkimSelectorFile != "" {
+		selectors, err := dkim.LoadSelectors(*dkimSelectorFile)
+		if err != nil {
+			log.Fatalf("Error loading DKIM selectors: %v", err)
+		}
+		opts.DKIMSelectors.Discovery = selectors
+	}
+	opts.DKIMSelectors.Walk = *nsecWalk
+	opts.CompareNameservers = *compareNameservers
+	opts.TryZoneTransfer = *tryAXFR
+	if *subdomains != "" {
+		opts.Subdomains = strings.Split(*subdomains, ",")
+	} else if *scanSubdomains {
+		opts.Subdomains = subdomain.DefaultSubdomains
+	}
+
+	// Configure the rules
+	settings := rules.DefaultSettings()
+	settings.DKIMRotationMonths = *dkimRotationMonths
+	settings.SignatureWarningDays = *rrsigWarningDays
+
+	// Run the check pipeline
+	enhanced, err := check.RunWithSettings(*domain, *nameserver, *inputType, opts, settings)
 	if err != nil {
 		log.Fatalf("Error collecting DNS info: %v", err)
 	}
 
-	// Create enhanced domain info and apply rules
-	enhanced := rules.NewEnhancedDomainInfo(info)
-	rules.ApplyAllRules(enhanced)
-
 	// Output results
 	if *jsonOutput {
 		// Output as JSON
@@ -67,31 +166,480 @@ func main() {
 	}
 }
 
+// ruaSelectors returns the DKIM selectors of the domain that receivers observed in the aggregate reports
+func ruaSelectors(domain string, paths []string) ([]string, error) {
+	var reports []*rua.Feedback
+	for _, path := range paths {
+		parsed, err := rua.ParseFile(strings.TrimSpace(path))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		reports = append(reports, parsed...)
+	}
+	return rua.Summarize(reports, nil).Selectors(domain), nil
+}
+
+// lintDMARCRecord runs the DMARC rules against a record without publishing it, exiting non-zero when a rule fails
+func lintDMARCRecord(domain string, record string, parseOpts dmarc.ParseOptions, jsonOutput bool) {
+	enhanced := generate.ValidateDMARCRecordWithOptions(domain, record, parseOpts)
+
+	if jsonOutput {
+		jsonData, err := json.MarshalIndent(enhanced.RuleCategories, "", "  ")
+		if err != nil {
+			log.Fatalf("Error marshaling to JSON: %v", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		fmt.Printf("DMARC record: %s\n", record)
+		printRuleResults(enhanced)
+	}
+
+	for _, result := range enhanced.RuleResults {
+		if result.Status == "fail" {
+			os.Exit(1)
+		}
+	}
+}
+
+func runBenchmark(args []string) {
+	flags := flag.NewFlagSet("benchmark", flag.ExitOnError)
+	nameserver := flags.String("nameserver", "8.8.8.8", "what nameserver to use")
+	runs := flags.Int("runs", 1, "how many times to check every reference domain")
+	jsonOutput := flags.Bool("json", false, "output as JSON")
+	flags.Parse(args)
+
+	summary := benchmark.Run(benchmark.References, *nameserver, *runs)
+
+	if *jsonOutput {
+		jsonData, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			log.Fatalf("Error marshaling to JSON: %v", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		summary.Print()
+	}
+}
+
+func runGenerate(args []string) {
+	if len(args) == 0 {
+		log.Fatalf("Usage: %s generate dmarc|dkim [-domain example.com]", os.Args[0])
+	}
+
+	switch args[0] {
+	case "dmarc":
+		flags := flag.NewFlagSet("generate dmarc", flag.ExitOnError)
+		domain := flags.String("domain", "", "domain to generate the record for")
+		flags.Parse(args[1:])
+
+		generate.RunDMARCWizard(os.Stdin, os.Stdout, *domain)
+	case "dkim":
+		flags := flag.NewFlagSet("generate dkim", flag.ExitOnError)
+		domain := flags.String("domain", "", "domain the key signs for")
+		selector := flags.String("selector", "", "selector to publish the key under")
+		keyType := flags.String("type", "rsa", "key type: rsa or ed25519")
+		bits := flags.Int("bits", 2048, "RSA key size in bits")
+		verify := flags.Bool("verify", false, "wait until the record is published and verify it")
+		nameserver := flags.String("nameserver", "8.8.8.8", "what nameserver to use for verification")
+		flags.Parse(args[1:])
+
+		if *domain == "" || *selector == "" {
+			log.Fatalf("Usage: %s generate dkim -domain example.com -selector s1 [-type rsa|ed25519] [-bits 2048] [-verify]", os.Args[0])
+		}
+
+		pair, err := generate.GenerateDKIMKey(*domain, *selector, *keyType, *bits)
+		if err != nil {
+			log.Fatalf("Error generating DKIM key: %v", err)
+		}
+
+		fmt.Println("Private key (configure this in your mail server, keep it secret):")
+		fmt.Print(pair.PrivateKeyPEM)
+		fmt.Printf("\nPublish this TXT record at %s:\n", pair.Name())
+		fmt.Println(pair.ZoneRecord())
+
+		if *verify {
+			fmt.Print("\nPress Enter once the record is published to verify it...")
+			bufio.NewReader(os.Stdin).ReadString('\n')
+			if err := generate.VerifyDKIMPublication(pair, *nameserver); err != nil {
+				log.Fatalf("Verification failed: %v", err)
+			}
+			fmt.Printf("✅ %s publishes the generated key\n", pair.Name())
+		}
+	default:
+		log.Fatalf("Unknown record type to generate: %s", args[0])
+	}
+}
+
+func runRUA(args []string) {
+	flags := flag.NewFlagSet("rua", flag.ExitOnError)
+	domain := flags.String("domain", "", "domain to cross-reference against (defaults to the domain in the reports)")
+	nameserver := flags.String("nameserver", "8.8.8.8", "what nameserver to use")
+	jsonOutput := flags.Bool("json", false, "output as JSON")
+	noSPF := flags.Bool("no-spf", false, "do not cross-reference sources against the current SPF record")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		log.Fatalf("Usage: %s rua [-domain example.com] report.xml[.gz|.zip] ...", os.Args[0])
+	}
+
+	var reports []*rua.Feedback
+	for _, path := range flags.Args() {
+		parsed, err := rua.ParseFile(path)
+		if err != nil {
+			log.Fatalf("Error parsing %s: %v", path, err)
+		}
+		reports = append(reports, parsed...)
+	}
+
+	// Cross-reference the sources against the current SPF record
+	var expansion *spf.Expansion
+	if !*noSPF {
+		spfDomain := *domain
+		if spfDomain == "" && len(reports) > 0 {
+			spfDomain = reports[0].PolicyPublished.Domain
+		}
+		if spfDomain != "" {
+			record, err := spf.LookupSPFWithFallback(spfDomain, *nameserver)
+			if err != nil {
+				log.Printf("Error looking up SPF record for %s: %v", spfDomain, err)
+			} else if record != nil {
+				expansion = spf.Expand(record, spfDomain, *nameserver)
+			}
+		}
+	}
+
+	summary := rua.Summarize(reports, expansion)
+	if *domain != "" {
+		summary.Domain = *domain
+	}
+
+	if *jsonOutput {
+		jsonData, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			log.Fatalf("Error marshaling to JSON: %v", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		summary.Print()
+	}
+}
+
+func runRUF(args []string) {
+	flags := flag.NewFlagSet("ruf", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "output as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		log.Fatalf("Usage: %s ruf report.eml|maildir ...", os.Args[0])
+	}
+
+	var reports []*ruf.FailureReport
+	for _, path := range flags.Args() {
+		parsed, errs := ruf.ParsePath(path)
+		for _, err := range errs {
+			log.Printf("Skipping: %v", err)
+		}
+		reports = append(reports, parsed...)
+	}
+
+	summary := ruf.Summarize(reports)
+
+	if *jsonOutput {
+		jsonData, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			log.Fatalf("Error marshaling to JSON: %v", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		summary.Print()
+	}
+}
+
+func runVerifyMessage(args []string) {
+	flags := flag.NewFlagSet("verify-message", flag.ExitOnError)
+	nameserver := flags.String("nameserver", "8.8.8.8", "what nameserver to use")
+	jsonOutput := flags.Bool("json", false, "output as JSON")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatalf("Usage: %s verify-message [-nameserver 8.8.8.8] message.eml", os.Args[0])
+	}
+
+	msg, err := message.ParseFile(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Error reading message: %v", err)
+	}
+
+	verification := message.Verify(msg, *nameserver)
+
+	if *jsonOutput {
+		jsonData, err := json.MarshalIndent(verification, "", "  ")
+		if err != nil {
+			log.Fatalf("Error marshaling to JSON: %v", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		verification.Print()
+	}
+}
+
 func printEnhancedDomainInfo(enhanced *rules.EnhancedDomainInfo) {
+	if enhanced.DomainInfo.HostInfo != nil {
+		printHostInfo(enhanced)
+		return
+	}
+
 	fmt.Println("Domain Info:")
 	fmt.Printf("Domain: %s\n", enhanced.DomainInfo.Domain)
+	if enhanced.DomainInfo.UnicodeDomain != "" {
+		fmt.Printf("Unicode: %s\n", enhanced.DomainInfo.UnicodeDomain)
+	}
 	fmt.Printf("Checked at: %v\n", enhanced.DomainInfo.QueryTime)
 
 	fmt.Println("\nDNSSEC Info:")
 	if enhanced.DomainInfo.DNSSECInfo != nil {
 		fmt.Printf("DNSSEC Enabled: %v\n", enhanced.DomainInfo.DNSSECInfo.Enabled)
+		if enhanced.DomainInfo.DNSSECInfo.Validation != "" {
+			fmt.Printf("Validation: %s\n", enhanced.DomainInfo.DNSSECInfo.Validation)
+		}
+		for _, key := range enhanced.DomainInfo.DNSSECInfo.Keys {
+			fmt.Printf("Key: %d, Role: %s, Algorithm: %s, Key size: %d bits, Flags: %d\n", key.KeyTag, key.Role, key.AlgorithmName, key.KeySize, key.Flags)
+		}
+		if enhanced.DomainInfo.DNSSECInfo.TCPRequired {
+			fmt.Println("Answers were truncated over UDP and retrieved over TCP")
+		}
 	} else {
 		fmt.Println("DNSSEC Info: Not available")
 	}
 
+	if enhanced.DomainInfo.WebInfo != nil {
+		fmt.Printf("Website: %s\n", enhanced.DomainInfo.WebInfo.Status)
+	}
+
+	if soa := enhanced.DomainInfo.SOA; soa != nil {
+		fmt.Println("\nZone Info:")
+		fmt.Printf("Zone: %s\n", soa.Zone)
+		fmt.Printf("SOA: %s %s serial %d, refresh %d, retry %d, expire %d, minimum %d\n", soa.MName, soa.RName, soa.Serial, soa.Refresh, soa.Retry, soa.Expire, soa.Minimum)
+		if len(enhanced.DomainInfo.Nameservers) > 0 {
+			fmt.Printf("Nameservers: %s\n", strings.Join(enhanced.DomainInfo.Nameservers, ", "))
+		}
+		if delegation := enhanced.DomainInfo.Delegation; delegation != nil {
+			if len(delegation.Parent) > 0 {
+				fmt.Printf("Delegation: %s\n", strings.Join(delegation.Parent, ", "))
+			} else if delegation.Error != "" {
+				fmt.Printf("Delegation: %s\n", delegation.Error)
+			}
+			for _, server := range delegation.Servers {
+				if server.Error != "" {
+					fmt.Printf("  %s: %s\n", server.Nameserver, server.Error)
+				} else {
+					fmt.Printf("  %s (%s): %s\n", server.Nameserver, server.Address, strings.Join(server.NS, ", "))
+				}
+			}
+		}
+	}
+
 	fmt.Println("\nMX Records:")
 	if len(enhanced.DomainInfo.MXRecords) > 0 {
 		for _, mx := range enhanced.DomainInfo.MXRecords {
-			fmt.Printf("Host: %s, Priority: %d\n", mx.Host, mx.Priority)
+			if mx.TTLKnown {
+				fmt.Printf("Host: %s, Priority: %d, TTL: %ds\n", mx.Host, mx.Priority, mx.TTL)
+			} else {
+				fmt.Printf("Host: %s, Priority: %d\n", mx.Host, mx.Priority)
+			}
+		}
+		if len(enhanced.DomainInfo.InboundProviders) > 0 {
+			fmt.Printf("Inbound provider: %s\n", strings.Join(enhanced.DomainInfo.InboundProviders, ", "))
 		}
 	} else {
 		fmt.Println("No MX records found")
+		if len(enhanced.DomainInfo.ApexAddresses) > 0 {
+			fmt.Printf("Implicit MX: %s\n", strings.Join(enhanced.DomainInfo.ApexAddresses, ", "))
+		}
+	}
+
+	if enhanced.DomainInfo.TXTRecords != nil && len(enhanced.DomainInfo.TXTRecords.Records) > 0 {
+		fmt.Println("\nTXT Records:")
+		for _, record := range enhanced.DomainInfo.TXTRecords.Records {
+			if record.Service != "" {
+				fmt.Printf("[%s, %s] %s\n", record.Category, record.Service, record.Value)
+			} else {
+				fmt.Printf("[%s] %s\n", record.Category, record.Value)
+			}
+		}
+		fmt.Printf("Total: %d bytes, response size: %d bytes\n", enhanced.DomainInfo.TXTRecords.Size, enhanced.DomainInfo.TXTRecords.ResponseSize)
+	}
+
+	if mtasts := enhanced.DomainInfo.MTASTS; mtasts != nil && len(mtasts.Records) > 0 {
+		fmt.Println("\nMTA-STS:")
+		for _, record := range mtasts.Records {
+			fmt.Printf("Record: %s\n", record)
+		}
+		if mtasts.Policy != nil {
+			fmt.Printf("Policy: %s, mode: %s, max_age: %d\n", mtasts.PolicyURL, mtasts.Policy.Mode, mtasts.Policy.MaxAge)
+			fmt.Printf("MX: %s\n", strings.Join(mtasts.Policy.MX, ", "))
+		} else {
+			fmt.Printf("Policy: %s\n", mtasts.Error)
+		}
+	}
+
+	if bimi := enhanced.DomainInfo.BIMI; bimi != nil && len(bimi.Records) > 0 {
+		fmt.Println("\nBIMI:")
+		for _, record := range bimi.Records {
+			fmt.Printf("Record: %s (%s)\n", record, bimi.Name)
+		}
+		if logo := bimi.Logo; logo != nil {
+			if logo.Error != "" {
+				fmt.Printf("Logo: %s (%s)\n", logo.URL, logo.Error)
+			} else {
+				fmt.Printf("Logo: %s (%d bytes, %d violations)\n", logo.URL, logo.Size, len(logo.Violations))
+			}
+		} else if bimi.Record != nil && bimi.Record.Logo != "" {
+			fmt.Printf("Logo: %s\n", bimi.Record.Logo)
+		}
+		if evidence := bimi.Evidence; evidence != nil {
+			if evidence.Error != "" {
+				fmt.Printf("Evidence: %s (%s)\n", evidence.URL, evidence.Error)
+			} else {
+				fmt.Printf("Evidence: %s (%s, %s, valid until %s)\n", evidence.URL, evidence.Type, evidence.Issuer, evidence.NotAfter.Format("2006-01-02"))
+			}
+		} else if bimi.Record != nil && bimi.Record.Authority != "" {
+			fmt.Printf("Evidence: %s\n", bimi.Record.Authority)
+		}
+	}
+
+	if len(enhanced.DomainInfo.MXTLSA) > 0 {
+		fmt.Println("\nDANE TLSA:")
+		for _, tlsa := range enhanced.DomainInfo.MXTLSA {
+			switch {
+			case tlsa.Error != "":
+				fmt.Printf("%s: %s\n", tlsa.Name, tlsa.Error)
+			case len(tlsa.Records) == 0:
+				fmt.Printf("%s: no TLSA records\n", tlsa.Name)
+			default:
+				fmt.Printf("%s (authenticated: %v):\n", tlsa.Name, tlsa.Authenticated)
+				for _, record := range tlsa.Records {
+					fmt.Printf("  %d %d %d %s\n", record.Usage, record.Selector, record.MatchingType, record.Data)
+				}
+				for _, verification := range tlsa.Verifications {
+					if verification.Matched != "" {
+						fmt.Printf("  %s: matches %s\n", verification.IP, verification.Matched)
+					} else {
+						fmt.Printf("  %s: %s\n", verification.IP, verification.Error)
+					}
+				}
+			}
+		}
 	}
 
+	fmt.Println("\nAddresses:")
+	if len(enhanced.DomainInfo.ApexAddresses) > 0 {
+		fmt.Printf("%s: %s\n", enhanced.DomainInfo.Domain, strings.Join(enhanced.DomainInfo.ApexAddresses, ", "))
+	} else {
+		fmt.Printf("%s: no address records\n", enhanced.DomainInfo.Domain)
+	}
+	if len(enhanced.DomainInfo.WWWAddresses) > 0 {
+		fmt.Printf("www.%s: %s\n", enhanced.DomainInfo.Domain, strings.Join(enhanced.DomainInfo.WWWAddresses, ", "))
+	} else {
+		fmt.Printf("www.%s: no address records\n", enhanced.DomainInfo.Domain)
+	}
+
+	if enhanced.DomainInfo.DKIMInfo != nil && enhanced.DomainInfo.DKIMInfo.CustomSelectors {
+		fmt.Println("\nDKIM Selectors:")
+		for _, result := range enhanced.DomainInfo.DKIMInfo.SelectorResults {
+			if !result.Supplied {
+				continue
+			}
+			switch {
+			case result.Error != "":
+				fmt.Printf("%s: %s\n", result.Selector, result.Error)
+			case result.Found:
+				fmt.Printf("%s: found\n", result.Selector)
+			default:
+				fmt.Printf("%s: not found (%s)\n", result.Selector, result.ResponseCode)
+			}
+		}
+	}
+
+	if enhanced.DomainInfo.DKIMInfo != nil && (enhanced.DomainInfo.DKIMInfo.Walked || enhanced.DomainInfo.DKIMInfo.WalkError != "") {
+		fmt.Println("\nDKIM NSEC Walk:")
+		if enhanced.DomainInfo.DKIMInfo.Walked {
+			fmt.Printf("Selectors in the NSEC chain: %s\n", strings.Join(enhanced.DomainInfo.DKIMInfo.WalkedSelectors, ", "))
+		} else {
+			fmt.Printf("Walking failed, selectors were guessed: %s\n", enhanced.DomainInfo.DKIMInfo.WalkError)
+		}
+	}
+
+	if enhanced.DomainInfo.DKIMInfo != nil && len(enhanced.DomainInfo.DKIMInfo.Keys) > 0 {
+		fmt.Println("\nDKIM Keys:")
+		for _, key := range enhanced.DomainInfo.DKIMInfo.Keys {
+			if key.KeyBits > 0 {
+				fmt.Printf("Selector: %s, Key type: %s, Key size: %d bits\n", key.Selector, key.KeyType, key.KeyBits)
+			} else {
+				fmt.Printf("Selector: %s, Key type: %s\n", key.Selector, key.KeyType)
+			}
+			for _, result := range enhanced.DomainInfo.DKIMInfo.SelectorResults {
+				if result.Selector != key.Selector {
+					continue
+				}
+				if len(result.CNAMEChain) > 0 {
+					fmt.Printf("    CNAME: %s\n", strings.Join(result.CNAMEChain, " -> "))
+				}
+				quoted := make([]string, len(result.TXT))
+				for i, txt := range result.TXT {
+					quoted[i] = strconv.Quote(txt)
+				}
+				fmt.Printf("    Record: %s\n", strings.Join(quoted, " "))
+			}
+		}
+	}
+
+	if enhanced.DomainInfo.Subdomains != nil {
+		fmt.Println("\nSubdomains:")
+		for _, sub := range enhanced.DomainInfo.Subdomains {
+			if !sub.Exists {
+				continue
+			}
+			fmt.Printf("%s: MX records: %d, SPF: %v, DMARC: %v\n", sub.Domain, len(sub.MXRecords), sub.SPFRecord != nil, sub.DMARCRecord != nil)
+		}
+	}
+
+	printRuleResults(enhanced)
+}
+
+func printHostInfo(enhanced *rules.EnhancedDomainInfo) {
+	hostInfo := enhanced.DomainInfo.HostInfo
+	fmt.Println("Host Info:")
+	fmt.Printf("Host: %s (%s)\n", hostInfo.Input, hostInfo.Type)
+	fmt.Printf("Checked at: %v\n", enhanced.DomainInfo.QueryTime)
+
+	fmt.Println("\nReverse DNS:")
+	for _, ptr := range hostInfo.PTR {
+		if ptr.Error != "" {
+			fmt.Printf("%s: lookup failed: %s\n", ptr.IP, ptr.Error)
+			continue
+		}
+		fmt.Printf("%s: %s (FCrDNS: %v)\n", ptr.IP, strings.Join(ptr.Names, ", "), ptr.ForwardConfirmed)
+	}
+
+	printRuleResults(enhanced)
+}
+
+func printRuleResults(enhanced *rules.EnhancedDomainInfo) {
 	fmt.Println("\nRule Check Results:")
-	for _, result := range enhanced.RuleResults {
-		icon := getRuleStatusIcon(result.Status)
-		fmt.Printf("%s - %s: %s\n", icon, result.Description, result.Message)
+	for _, group := range enhanced.RuleCategories {
+		fmt.Printf("\n[%s]\n", group.Category)
+		for _, result := range group.Results {
+			icon := getRuleStatusIcon(result.Status)
+			confidence := ""
+			if result.Confidence != rules.ConfidenceHigh {
+				confidence = fmt.Sprintf(" (confidence: %s)", result.Confidence)
+			}
+			fmt.Printf("%s - %s: %s%s\n", icon, result.Description, result.Message, confidence)
+			if result.Remediation != "" {
+				fmt.Printf("    Suggested record: %s\n", result.Remediation)
+			}
+		}
 	}
 }
 